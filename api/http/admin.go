@@ -0,0 +1,46 @@
+package apihttp
+
+import (
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/user"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type adminHandler struct{ users *user.Usecase }
+
+// RegisterAdminRoutes registers user administration on rg, which must be
+// restricted to admins.
+func RegisterAdminRoutes(rg *gin.RouterGroup, users *user.Usecase) {
+	h := &adminHandler{users: users}
+	rg.PUT("/users/:id/role", h.setRole)
+}
+
+func (h *adminHandler) setRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	var req struct {
+		Role user.Role `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.users.SetRole(id, req.Role); err != nil {
+		switch err {
+		case user.ErrUnknownRole:
+			httpx.RespondWithError(c, http.StatusBadRequest, "Role must be user, moderator or admin")
+		case user.ErrNotFound:
+			httpx.RespondWithError(c, http.StatusNotFound, "User not found")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to update role")
+		}
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, gin.H{"id": id, "role": req.Role})
+}
@@ -0,0 +1,77 @@
+package apihttp
+
+import (
+	"majoo-case1-rest-api/internal/block"
+	httpx "majoo-case1-rest-api/internal/http"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type blockHandler struct{ uc *block.Usecase }
+
+// RegisterBlockRoutes registers blocking and muting on rg, which must
+// require authentication.
+func RegisterBlockRoutes(rg *gin.RouterGroup, uc *block.Usecase) {
+	h := &blockHandler{uc: uc}
+	rg.POST("/users/:id/block", h.block)
+	rg.DELETE("/users/:id/block", h.unblock)
+	rg.POST("/users/:id/mute", h.mute)
+	rg.DELETE("/users/:id/mute", h.unmute)
+	rg.GET("/me/blocks", h.blocks)
+	rg.GET("/me/mutes", h.mutes)
+}
+
+func (h *blockHandler) block(c *gin.Context)   { h.add(c, block.Block, "blocked") }
+func (h *blockHandler) unblock(c *gin.Context) { h.remove(c, block.Block, "unblocked") }
+func (h *blockHandler) mute(c *gin.Context)    { h.add(c, block.Mute, "muted") }
+func (h *blockHandler) unmute(c *gin.Context)  { h.remove(c, block.Mute, "unmuted") }
+func (h *blockHandler) blocks(c *gin.Context)  { h.list(c, block.Block, "blocks") }
+func (h *blockHandler) mutes(c *gin.Context)   { h.list(c, block.Mute, "mutes") }
+
+func (h *blockHandler) add(c *gin.Context, k block.Kind, done string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	userID := c.MustGet("userID").(int)
+	if err := h.uc.Add(k, userID, id); err != nil {
+		switch err {
+		case block.ErrSelf:
+			httpx.RespondWithError(c, http.StatusBadRequest, "You cannot "+string(k)+" yourself")
+		case block.ErrNotFound:
+			httpx.RespondWithError(c, http.StatusNotFound, "User not found")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to "+string(k)+" user")
+		}
+		return
+	}
+	httpx.RespondWithMessage(c, http.StatusOK, "User "+done)
+}
+
+func (h *blockHandler) remove(c *gin.Context, k block.Kind, done string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	userID := c.MustGet("userID").(int)
+	if err := h.uc.Remove(k, userID, id); err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to un"+string(k)+" user")
+		return
+	}
+	httpx.RespondWithMessage(c, http.StatusOK, "User "+done)
+}
+
+func (h *blockHandler) list(c *gin.Context, k block.Kind, key string) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	users, err := h.uc.List(k, c.MustGet("userID").(int), page, limit)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, gin.H{key: users, "page": page, "limit": limit})
+}
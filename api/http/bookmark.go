@@ -0,0 +1,66 @@
+package apihttp
+
+import (
+	"majoo-case1-rest-api/internal/bookmark"
+	httpx "majoo-case1-rest-api/internal/http"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type bookmarkHandler struct{ uc *bookmark.Usecase }
+
+func RegisterBookmarkRoutes(rg *gin.RouterGroup, uc *bookmark.Usecase) {
+	h := &bookmarkHandler{uc: uc}
+	rg.POST("/posts/:id/bookmark", h.add)
+	rg.DELETE("/posts/:id/bookmark", h.remove)
+	rg.GET("/me/bookmarks", h.list)
+}
+
+func (h *bookmarkHandler) add(c *gin.Context) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	userID := c.MustGet("userID").(int)
+	if err := h.uc.Add(userID, postID); err != nil {
+		if err == bookmark.ErrNotFound {
+			httpx.RespondWithError(c, http.StatusNotFound, "Post not found")
+			return
+		}
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to bookmark post")
+		return
+	}
+	httpx.RespondWithMessage(c, http.StatusOK, "Post bookmarked")
+}
+
+func (h *bookmarkHandler) remove(c *gin.Context) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	userID := c.MustGet("userID").(int)
+	if err := h.uc.Remove(userID, postID); err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to remove bookmark")
+		return
+	}
+	httpx.RespondWithMessage(c, http.StatusOK, "Bookmark removed")
+}
+
+func (h *bookmarkHandler) list(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	userID := c.MustGet("userID").(int)
+	page, err := h.uc.List(userID, c.Query("cursor"), limit)
+	if err != nil {
+		if err == bookmark.ErrInvalidCursor {
+			httpx.RespondWithError(c, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch bookmarks")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, page)
+}
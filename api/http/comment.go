@@ -1,6 +1,7 @@
 package apihttp
 
 import (
+	"majoo-case1-rest-api/config"
 	"majoo-case1-rest-api/internal/comment"
 	httpx "majoo-case1-rest-api/internal/http"
 	"net/http"
@@ -9,15 +10,21 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-type commentHandler struct{ uc *comment.Usecase }
+type commentHandler struct {
+	uc             *comment.Usecase
+	requireIfMatch bool
+}
 
-func RegisterCommentRoutes(rg *gin.RouterGroup, uc *comment.Usecase) {
-	h := &commentHandler{uc: uc}
-	rg.GET("/posts/:id/comments", h.listByPost)
-	rg.GET("/comments/:id", h.get)
-	rg.POST("/posts/:id/comments", h.create)
-	rg.PUT("/comments/:id", h.update)
-	rg.DELETE("/comments/:id", h.delete)
+// RegisterCommentRoutes registers reads on read and writes on write, with
+// comment creation behind idempotent, as RegisterPostRoutes does.
+func RegisterCommentRoutes(read, write *gin.RouterGroup, uc *comment.Usecase, cfg config.Config, idempotent gin.HandlerFunc) {
+	h := &commentHandler{uc: uc, requireIfMatch: cfg.RequireIfMatch}
+	read.GET("/posts/:id/comments", h.listByPost)
+	read.GET("/comments/:id", h.get)
+	write.POST("/posts/:id/comments", idempotent, h.create)
+	write.PUT("/comments/:id", h.update)
+	write.DELETE("/comments/:id", h.delete)
+	write.POST("/comments/:id/restore", h.restore)
 }
 
 func (h *commentHandler) listByPost(c *gin.Context) {
@@ -26,7 +33,7 @@ func (h *commentHandler) listByPost(c *gin.Context) {
 		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
-	items, err := h.uc.ListByPost(postID)
+	items, err := h.uc.ListByPost(viewerID(c), postID)
 	if err != nil {
 		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch comments")
 		return
@@ -40,12 +47,12 @@ func (h *commentHandler) get(c *gin.Context) {
 		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid comment ID")
 		return
 	}
-	cm, err := h.uc.Get(id)
+	cm, err := h.uc.Get(viewerID(c), id)
 	if err != nil {
 		httpx.RespondWithError(c, http.StatusNotFound, "Comment not found")
 		return
 	}
-	httpx.RespondWithSuccess(c, http.StatusOK, cm)
+	respondVersioned(c, cm.Version, cm)
 }
 
 func (h *commentHandler) create(c *gin.Context) {
@@ -60,12 +67,25 @@ func (h *commentHandler) create(c *gin.Context) {
 		return
 	}
 	userID := c.MustGet("userID").(int)
-	cm, err := h.uc.Create(postID, userID, req.Content)
+	cm, err := h.uc.Create(postID, userID, req)
 	if err != nil {
 		switch err {
 		case comment.ErrNotFound:
 			httpx.RespondWithError(c, http.StatusNotFound, "Post not found")
+		case comment.ErrInvalidParent:
+			httpx.RespondWithError(c, http.StatusBadRequest, "parent_id must be a comment on the same post")
+		case comment.ErrCommentsDisabled:
+			httpx.RespondWithErrorCode(c, http.StatusForbidden, err.Error(), "Comments are disabled on this post")
+		case comment.ErrCommentsLocked:
+			httpx.RespondWithErrorCode(c, http.StatusForbidden, err.Error(), "Comments on this post are locked")
+		case comment.ErrMembersOnly:
+			httpx.RespondWithErrorCode(c, http.StatusForbidden, err.Error(), "Only followers of the author may comment on this post")
+		case comment.ErrBlocked:
+			httpx.RespondWithErrorCode(c, http.StatusForbidden, err.Error(), "You have been blocked by the author")
 		default:
+			if respondRejected(c, err) {
+				return
+			}
 			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to create comment")
 		}
 		return
@@ -84,13 +104,24 @@ func (h *commentHandler) update(c *gin.Context) {
 		httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	version, ok := ifMatchVersion(c, h.requireIfMatch)
+	if !ok {
+		return
+	}
 	userID := c.MustGet("userID").(int)
-	cm, err := h.uc.Update(userID, id, req.Content)
+	cm, err := h.uc.Update(userID, id, req, version)
 	if err != nil {
 		if err == comment.ErrForbidden {
 			httpx.RespondWithError(c, http.StatusForbidden, "Forbidden")
 			return
 		}
+		if err == comment.ErrVersionMismatch {
+			httpx.RespondWithError(c, http.StatusPreconditionFailed, "Comment was changed since it was read")
+			return
+		}
+		if respondRejected(c, err) {
+			return
+		}
 		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to update comment")
 		return
 	}
@@ -103,14 +134,48 @@ func (h *commentHandler) delete(c *gin.Context) {
 		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid comment ID")
 		return
 	}
+	version, ok := ifMatchVersion(c, h.requireIfMatch)
+	if !ok {
+		return
+	}
 	userID := c.MustGet("userID").(int)
-	if err := h.uc.Delete(userID, id); err != nil {
+	if err := h.uc.Delete(userID, id, version); err != nil {
 		if err == comment.ErrForbidden {
 			httpx.RespondWithError(c, http.StatusForbidden, "Forbidden")
 			return
 		}
+		if err == comment.ErrVersionMismatch {
+			httpx.RespondWithError(c, http.StatusPreconditionFailed, "Comment was changed since it was read")
+			return
+		}
 		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to delete comment")
 		return
 	}
 	httpx.RespondWithMessage(c, http.StatusOK, "Comment deleted successfully")
 }
+
+func (h *commentHandler) restore(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+	if err := h.uc.Restore(c.MustGet("userID").(int), id); err != nil {
+		switch err {
+		case comment.ErrNotFound:
+			httpx.RespondWithError(c, http.StatusNotFound, "Comment not found in trash")
+		case comment.ErrForbidden:
+			httpx.RespondWithError(c, http.StatusForbidden, "Forbidden")
+		case comment.ErrExpired:
+			httpx.RespondWithError(c, http.StatusGone, "Comment can no longer be restored")
+		case comment.ErrPostDeleted:
+			httpx.RespondWithErrorCode(c, http.StatusConflict, err.Error(), "The comment's post is deleted; restore the post instead")
+		case comment.ErrParentDeleted:
+			httpx.RespondWithErrorCode(c, http.StatusConflict, err.Error(), "The comment replied to is deleted; restore it first")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to restore comment")
+		}
+		return
+	}
+	httpx.RespondWithMessage(c, http.StatusOK, "Comment restored successfully")
+}
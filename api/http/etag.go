@@ -0,0 +1,73 @@
+package apihttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	httpx "majoo-case1-rest-api/internal/http"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondVersioned writes a post or comment at version as JSON, answering
+// If-None-Match with 304. Its strong ETag is "<version>.<hash of the body>":
+// the hash covers what an edit does not change, such as reaction counts and
+// the viewer's own reactions, and the version is what If-Match checks.
+func respondVersioned(c *gin.Context, version int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + strconv.Itoa(version) + "." + hex.EncodeToString(sum[:16]) + `"`
+	// the body differs per viewer, who is identified by the auth cookie
+	c.Header("Vary", "Authorization, Cookie")
+	if httpx.NotModified(c, etag, time.Time{}) {
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// ifMatchVersion returns the version a PUT or DELETE is conditional on, read
+// from an ETag given by respondVersioned; only the version part is compared,
+// so a reaction since the read does not fail the precondition. It is 0,
+// meaning unconditional, for "If-Match: *" and, unless required, for a
+// missing header. A missing header that is required gets 428 and a tag that
+// cannot be one of our ETags gets 412; ok is false once it has responded.
+func ifMatchVersion(c *gin.Context, required bool) (version int, ok bool) {
+	h := strings.TrimSpace(c.GetHeader("If-Match"))
+	if h == "" {
+		if required {
+			httpx.RespondWithError(c, http.StatusPreconditionRequired, "If-Match header is required")
+			return 0, false
+		}
+		return 0, true
+	}
+	if h == "*" {
+		return 0, true
+	}
+	// weak tags never pass If-Match's strong comparison
+	tag := strings.TrimPrefix(h, `"`)
+	if len(tag) == len(h) || !strings.HasSuffix(tag, `"`) {
+		return 0, failPrecondition(c)
+	}
+	tag = strings.TrimSuffix(tag, `"`)
+	if i := strings.IndexByte(tag, '.'); i >= 0 {
+		tag = tag[:i]
+	}
+	v, err := strconv.Atoi(tag)
+	if err != nil || v < 1 {
+		return 0, failPrecondition(c)
+	}
+	return v, true
+}
+
+func failPrecondition(c *gin.Context) bool {
+	httpx.RespondWithError(c, http.StatusPreconditionFailed, "If-Match does not match the current version")
+	return false
+}
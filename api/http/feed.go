@@ -0,0 +1,103 @@
+package apihttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/feed"
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/tag"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type feedHandler struct {
+	uc      *feed.Usecase
+	baseURL string
+}
+
+// RegisterFeedRoutes serves RSS and Atom feeds. They are meant for feed
+// readers, so register them on a group without authentication.
+func RegisterFeedRoutes(rg *gin.RouterGroup, uc *feed.Usecase, cfg config.Config) {
+	h := &feedHandler{uc: uc, baseURL: cfg.PublicBaseURL}
+	rg.GET("/feed.rss", h.site(feed.RSS, feed.RSSContentType))
+	rg.GET("/feed.atom", h.site(feed.Atom, feed.AtomContentType))
+	rg.GET("/users/:id/feed.rss", h.author(feed.RSS, feed.RSSContentType))
+	rg.GET("/users/:id/feed.atom", h.author(feed.Atom, feed.AtomContentType))
+	rg.GET("/tags/:slug/feed.rss", h.tag(feed.RSS, feed.RSSContentType))
+	rg.GET("/tags/:slug/feed.atom", h.tag(feed.Atom, feed.AtomContentType))
+}
+
+type feedRenderer func(feed.Feed) ([]byte, error)
+
+func (h *feedHandler) site(render feedRenderer, contentType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, err := h.uc.Site()
+		h.write(c, f, err, render, contentType)
+	}
+}
+
+func (h *feedHandler) author(render feedRenderer, contentType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			httpx.RespondWithError(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		f, err := h.uc.Author(id)
+		h.write(c, f, err, render, contentType)
+	}
+}
+
+// tag serves the feed of a tag, redirecting spellings such as "Go" to the
+// tag's slug the way the posts list filter accepts them.
+func (h *feedHandler) tag(render feedRenderer, contentType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s := c.Param("slug")
+		slugs, err := tag.Slugs([]string{s})
+		if err != nil {
+			httpx.RespondWithError(c, http.StatusNotFound, "Feed not found")
+			return
+		}
+		if slugs[0] != s {
+			target := strings.Replace(c.Request.URL.Path, "/tags/"+s+"/", "/tags/"+url.PathEscape(slugs[0])+"/", 1)
+			if c.Request.URL.RawQuery != "" {
+				target += "?" + c.Request.URL.RawQuery
+			}
+			c.Redirect(http.StatusMovedPermanently, target)
+			return
+		}
+		f, err := h.uc.Tag(s)
+		h.write(c, f, err, render, contentType)
+	}
+}
+
+// write renders the feed and answers conditional requests. The ETag hashes the
+// rendered document, so it also changes when a post drops out of the feed,
+// which Last-Modified (the newest post's update time) cannot express.
+func (h *feedHandler) write(c *gin.Context, f feed.Feed, err error, render feedRenderer, contentType string) {
+	if err != nil {
+		if err == feed.ErrNotFound {
+			httpx.RespondWithError(c, http.StatusNotFound, "Feed not found")
+			return
+		}
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to build feed")
+		return
+	}
+	f.SelfURL = h.baseURL + c.Request.URL.Path
+	body, err := render(f)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to build feed")
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if httpx.NotModified(c, etag, f.Updated) {
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
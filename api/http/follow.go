@@ -0,0 +1,83 @@
+package apihttp
+
+import (
+	"majoo-case1-rest-api/internal/follow"
+	httpx "majoo-case1-rest-api/internal/http"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type followHandler struct{ uc *follow.Usecase }
+
+func RegisterFollowRoutes(read, write *gin.RouterGroup, uc *follow.Usecase) {
+	h := &followHandler{uc: uc}
+	write.POST("/users/:id/follow", h.follow)
+	write.DELETE("/users/:id/follow", h.unfollow)
+	read.GET("/users/:id/followers", h.followers)
+	read.GET("/users/:id/following", h.following)
+}
+
+func (h *followHandler) follow(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	userID := c.MustGet("userID").(int)
+	if err := h.uc.Follow(userID, id); err != nil {
+		switch err {
+		case follow.ErrSelf:
+			httpx.RespondWithError(c, http.StatusBadRequest, "You cannot follow yourself")
+		case follow.ErrNotFound:
+			httpx.RespondWithError(c, http.StatusNotFound, "User not found")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to follow user")
+		}
+		return
+	}
+	httpx.RespondWithMessage(c, http.StatusOK, "User followed")
+}
+
+func (h *followHandler) unfollow(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	userID := c.MustGet("userID").(int)
+	if err := h.uc.Unfollow(userID, id); err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to unfollow user")
+		return
+	}
+	httpx.RespondWithMessage(c, http.StatusOK, "User unfollowed")
+}
+
+func (h *followHandler) followers(c *gin.Context) {
+	h.list(c, "followers", h.uc.Followers)
+}
+
+func (h *followHandler) following(c *gin.Context) {
+	h.list(c, "following", h.uc.Following)
+}
+
+func (h *followHandler) list(c *gin.Context, key string, fetch func(userID, page, limit int) ([]follow.User, error)) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	users, err := fetch(id, page, limit)
+	if err != nil {
+		if err == follow.ErrNotFound {
+			httpx.RespondWithError(c, http.StatusNotFound, "User not found")
+			return
+		}
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, gin.H{"user_id": id, key: users, "page": page, "limit": limit})
+}
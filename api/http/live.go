@@ -0,0 +1,125 @@
+package apihttp
+
+import (
+	"encoding/json"
+	"log"
+	"majoo-case1-rest-api/config"
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/live"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// maxLiveMessage caps the size of one client message; comments are the
+// largest.
+const maxLiveMessage = 64 << 10
+
+type liveHandler struct {
+	uc        *live.Usecase
+	upgrader  websocket.Upgrader
+	heartbeat time.Duration
+}
+
+func RegisterLiveRoutes(rg *gin.RouterGroup, uc *live.Usecase, cfg config.Config) {
+	h := &liveHandler{uc: uc, heartbeat: cfg.StreamHeartbeat}
+	if len(cfg.LiveAllowedOrigins) > 0 {
+		// The auth cookie rides along on cross-site sockets too, so only
+		// listed origins may connect.
+		allowed := cfg.LiveAllowedOrigins
+		h.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			for _, o := range allowed {
+				if strings.EqualFold(o, origin) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	rg.GET("/live", h.serve)
+}
+
+// serve upgrades to a WebSocket and runs the session: a reader goroutine
+// handles commands, and this goroutine is the connection's only writer.
+func (h *liveHandler) serve(c *gin.Context) {
+	userID := c.MustGet("userID").(int)
+	s, err := h.uc.Open(userID)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to open live session")
+		return
+	}
+	defer s.Close()
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // the upgrader has already replied
+	}
+	defer conn.Close()
+
+	conn.SetReadLimit(maxLiveMessage)
+	deadline := func() { conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat)) }
+	deadline()
+	conn.SetPongHandler(func(string) error { deadline(); return nil })
+
+	replies := make(chan live.Frame, 16)
+	done := make(chan struct{}) // reader finished
+	quit := make(chan struct{}) // writer finished
+	defer close(quit)
+	go func() {
+		defer close(done)
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var cmd live.Command
+			f, ok := live.Frame{Type: "error", Error: "invalid_request"}, true
+			if json.Unmarshal(msg, &cmd) == nil {
+				f, ok = s.Handle(cmd)
+			}
+			if !ok {
+				continue
+			}
+			select {
+			case replies <- f:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(h.heartbeat)
+	defer ping.Stop()
+	for {
+		var f live.Frame
+		select {
+		case <-done:
+			return
+		case f = <-replies:
+		case m, ok := <-s.Events():
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too far behind"), time.Now().Add(time.Second))
+				return
+			}
+			if f, ok = s.Frame(m); !ok {
+				continue
+			}
+		case <-ping.C:
+			if err := s.Touch(); err != nil {
+				log.Printf("live: refreshing presence: %v", err)
+			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.heartbeat)); err != nil {
+				return
+			}
+			continue
+		}
+		conn.SetWriteDeadline(time.Now().Add(h.heartbeat))
+		if err := conn.WriteJSON(f); err != nil {
+			return
+		}
+	}
+}
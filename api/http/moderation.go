@@ -0,0 +1,112 @@
+package apihttp
+
+import (
+	"majoo-case1-rest-api/internal/comment"
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/moderation"
+	"majoo-case1-rest-api/internal/post"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type moderationHandler struct {
+	comments *comment.Usecase
+	posts    *post.Usecase
+}
+
+// RegisterModerationRoutes registers the comment moderation queue on rg,
+// which must be restricted to moderators.
+func RegisterModerationRoutes(rg *gin.RouterGroup, comments *comment.Usecase, posts *post.Usecase) {
+	h := &moderationHandler{comments: comments, posts: posts}
+	rg.GET("/comments", h.queue)
+	rg.POST("/comments/:id/approve", h.approve)
+	rg.POST("/comments/:id/reject", h.reject)
+	rg.PUT("/posts/:id/mode", h.setMode)
+}
+
+func (h *moderationHandler) queue(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	status := moderation.Status(c.Query("status"))
+	page, err := h.comments.Queue(status, c.Query("cursor"), limit)
+	if err != nil {
+		switch err {
+		case comment.ErrInvalidStatus:
+			httpx.RespondWithError(c, http.StatusBadRequest, "status must be pending or rejected")
+		case comment.ErrInvalidCursor:
+			httpx.RespondWithError(c, http.StatusBadRequest, "Invalid cursor")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch moderation queue")
+		}
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, page)
+}
+
+func (h *moderationHandler) approve(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+	cm, err := h.comments.Approve(c.MustGet("userID").(int), id)
+	h.respond(c, cm, err)
+}
+
+func (h *moderationHandler) reject(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+	var req comment.RejectCommentRequest
+	// the note is optional, and so is the body
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	cm, err := h.comments.Reject(c.MustGet("userID").(int), id, req.Note)
+	h.respond(c, cm, err)
+}
+
+func (h *moderationHandler) respond(c *gin.Context, cm comment.Comment, err error) {
+	if err != nil {
+		if err == comment.ErrNotFound {
+			httpx.RespondWithError(c, http.StatusNotFound, "Comment not found")
+			return
+		}
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to moderate comment")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, cm)
+}
+
+func (h *moderationHandler) setMode(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	var req struct {
+		Mode *moderation.Mode `json:"mode"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.posts.SetCommentModeration(id, req.Mode); err != nil {
+		switch err {
+		case moderation.ErrUnknownMode:
+			httpx.RespondWithError(c, http.StatusBadRequest, "mode must be open, first_time, all or null")
+		case post.ErrNotFound:
+			httpx.RespondWithError(c, http.StatusNotFound, "Post not found")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to update moderation mode")
+		}
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, gin.H{"post_id": id, "mode": req.Mode})
+}
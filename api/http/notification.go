@@ -0,0 +1,104 @@
+package apihttp
+
+import (
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/notification"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type notificationHandler struct{ uc *notification.Usecase }
+
+func RegisterNotificationRoutes(rg *gin.RouterGroup, uc *notification.Usecase) {
+	h := &notificationHandler{uc: uc}
+	rg.GET("/notifications", h.list)
+	rg.GET("/notifications/unread-count", h.unreadCount)
+	rg.POST("/notifications/:id/read", h.markRead)
+	rg.POST("/notifications/read-all", h.markAllRead)
+	rg.GET("/notifications/preferences", h.preferences)
+	rg.PUT("/notifications/preferences", h.setPreferences)
+}
+
+func (h *notificationHandler) list(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	unreadOnly, _ := strconv.ParseBool(c.DefaultQuery("unread", "false"))
+	userID := c.MustGet("userID").(int)
+	page, err := h.uc.List(userID, c.Query("cursor"), limit, unreadOnly)
+	if err != nil {
+		if err == notification.ErrInvalidCursor {
+			httpx.RespondWithError(c, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch notifications")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, page)
+}
+
+func (h *notificationHandler) unreadCount(c *gin.Context) {
+	userID := c.MustGet("userID").(int)
+	n, err := h.uc.UnreadCount(userID)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to count notifications")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, gin.H{"unread_count": n})
+}
+
+func (h *notificationHandler) markRead(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+	userID := c.MustGet("userID").(int)
+	if err := h.uc.MarkRead(userID, id); err != nil {
+		if err == notification.ErrNotFound {
+			httpx.RespondWithError(c, http.StatusNotFound, "Notification not found")
+			return
+		}
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to mark notification read")
+		return
+	}
+	httpx.RespondWithMessage(c, http.StatusOK, "Notification marked as read")
+}
+
+func (h *notificationHandler) markAllRead(c *gin.Context) {
+	userID := c.MustGet("userID").(int)
+	if err := h.uc.MarkAllRead(userID); err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to mark notifications read")
+		return
+	}
+	httpx.RespondWithMessage(c, http.StatusOK, "All notifications marked as read")
+}
+
+func (h *notificationHandler) preferences(c *gin.Context) {
+	userID := c.MustGet("userID").(int)
+	prefs, err := h.uc.Preferences(userID)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch preferences")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, prefs)
+}
+
+func (h *notificationHandler) setPreferences(c *gin.Context) {
+	var req map[notification.Type]bool
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID := c.MustGet("userID").(int)
+	prefs, err := h.uc.SetPreferences(userID, req)
+	if err != nil {
+		if err == notification.ErrUnknownType {
+			httpx.RespondWithError(c, http.StatusBadRequest, "Unknown notification type")
+			return
+		}
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to update preferences")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, prefs)
+}
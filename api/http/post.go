@@ -1,39 +1,86 @@
 package apihttp
 
 import (
+    "database/sql"
+    "majoo-case1-rest-api/config"
     httpx "majoo-case1-rest-api/internal/http"
     "majoo-case1-rest-api/internal/post"
+    "majoo-case1-rest-api/internal/tag"
     "net/http"
     "strconv"
+    "strings"
 
     "github.com/gin-gonic/gin"
 )
 
-type postHandler struct{ uc *post.Usecase }
+type postHandler struct {
+    uc             *post.Usecase
+    requireIfMatch bool
+}
 
-func RegisterPostRoutes(rg *gin.RouterGroup, uc *post.Usecase) {
-    h := &postHandler{uc: uc}
-    rg.GET("/posts", h.list)
-    rg.GET("/posts/:id", h.get)
-    rg.POST("/posts", h.create)
-    rg.PUT("/posts/:id", h.update)
-    rg.DELETE("/posts/:id", h.delete)
+// RegisterPostRoutes registers reads on read, which may allow anonymous
+// visitors, and writes on write, which must require authentication. Creating
+// a post goes through idempotent so that clients can retry it safely.
+func RegisterPostRoutes(read, write *gin.RouterGroup, uc *post.Usecase, cfg config.Config, idempotent gin.HandlerFunc) {
+    h := &postHandler{uc: uc, requireIfMatch: cfg.RequireIfMatch}
+    read.GET("/posts", h.list)
+    read.GET("/posts/:id", h.get)
+    read.GET("/posts/by-slug/:slug", h.getBySlug)
+    read.GET("/posts/:id/settings", h.settings)
+    write.GET("/feed", h.home)
+    write.POST("/posts", idempotent, h.create)
+    write.PUT("/posts/:id", h.update)
+    write.DELETE("/posts/:id", h.delete)
+    write.POST("/posts/:id/restore", h.restore)
+    write.PATCH("/posts/:id/settings", h.updateSettings)
 }
 
 func (h *postHandler) list(c *gin.Context) {
     page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
     limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-    posts, err := h.uc.List(page, limit)
+    tags, err := tag.Slugs(c.QueryArray("tag"))
+    if err != nil { httpx.RespondWithError(c, http.StatusBadRequest, "Invalid tag"); return }
+    match := c.DefaultQuery("match", "any")
+    if match != "any" && match != "all" { httpx.RespondWithError(c, http.StatusBadRequest, "match must be any or all"); return }
+    posts, err := h.uc.List(viewerID(c), page, limit, post.ListFilter{Tags: tags, MatchAll: match == "all"})
     if err != nil { httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch posts"); return }
     httpx.RespondWithSuccess(c, http.StatusOK, gin.H{"posts": posts, "page": page, "limit": limit})
 }
 
+// home is the caller's personalized feed of posts by the authors they follow.
+func (h *postHandler) home(c *gin.Context) {
+    limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+    userID := c.MustGet("userID").(int)
+    page, err := h.uc.HomeFeed(userID, c.Query("cursor"), limit)
+    if err != nil {
+        if err == post.ErrInvalidCursor { httpx.RespondWithError(c, http.StatusBadRequest, "Invalid cursor"); return }
+        httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch feed"); return
+    }
+    httpx.RespondWithSuccess(c, http.StatusOK, page)
+}
+
 func (h *postHandler) get(c *gin.Context) {
     id, err := strconv.Atoi(c.Param("id"))
     if err != nil { httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID"); return }
-    p, err := h.uc.Get(id)
+    p, err := h.uc.Get(viewerID(c), id)
     if err != nil { httpx.RespondWithError(c, http.StatusNotFound, "Post not found"); return }
-    httpx.RespondWithSuccess(c, http.StatusOK, p)
+    respondVersioned(c, p.Version, p)
+}
+
+func (h *postHandler) getBySlug(c *gin.Context) {
+    s := c.Param("slug")
+    p, current, err := h.uc.GetBySlug(viewerID(c), s)
+    if err != nil {
+        if err == sql.ErrNoRows { httpx.RespondWithError(c, http.StatusNotFound, "Post not found"); return }
+        httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch post"); return
+    }
+    if current != s {
+        target := strings.TrimSuffix(c.Request.URL.Path, s) + current
+        if c.Request.URL.RawQuery != "" { target += "?" + c.Request.URL.RawQuery }
+        c.Redirect(http.StatusMovedPermanently, target)
+        return
+    }
+    respondVersioned(c, p.Version, p)
 }
 
 func (h *postHandler) create(c *gin.Context) {
@@ -41,7 +88,11 @@ func (h *postHandler) create(c *gin.Context) {
     if err := c.ShouldBindJSON(&req); err != nil { httpx.RespondWithError(c, http.StatusBadRequest, err.Error()); return }
     userID := c.MustGet("userID").(int)
     p, err := h.uc.Create(userID, req)
-    if err != nil { httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to create post"); return }
+    if err != nil {
+        if err == tag.ErrInvalid { httpx.RespondWithError(c, http.StatusBadRequest, "Invalid tag"); return }
+        if respondRejected(c, err) { return }
+        httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to create post"); return
+    }
     httpx.RespondWithSuccess(c, http.StatusCreated, p)
 }
 
@@ -50,10 +101,15 @@ func (h *postHandler) update(c *gin.Context) {
     if err != nil { httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID"); return }
     var req post.UpdatePostRequest
     if err := c.ShouldBindJSON(&req); err != nil { httpx.RespondWithError(c, http.StatusBadRequest, err.Error()); return }
+    version, ok := ifMatchVersion(c, h.requireIfMatch)
+    if !ok { return }
     userID := c.MustGet("userID").(int)
-    p, err := h.uc.Update(userID, id, req)
+    p, err := h.uc.Update(userID, id, req, version)
     if err != nil {
         if err == post.ErrForbidden { httpx.RespondWithError(c, http.StatusForbidden, "Forbidden"); return }
+        if err == post.ErrVersionMismatch { httpx.RespondWithError(c, http.StatusPreconditionFailed, "Post was changed since it was read"); return }
+        if err == tag.ErrInvalid { httpx.RespondWithError(c, http.StatusBadRequest, "Invalid tag"); return }
+        if respondRejected(c, err) { return }
         httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to update post"); return
     }
     httpx.RespondWithSuccess(c, http.StatusOK, p)
@@ -62,12 +118,50 @@ func (h *postHandler) update(c *gin.Context) {
 func (h *postHandler) delete(c *gin.Context) {
     id, err := strconv.Atoi(c.Param("id"))
     if err != nil { httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID"); return }
+    version, ok := ifMatchVersion(c, h.requireIfMatch)
+    if !ok { return }
     userID := c.MustGet("userID").(int)
-    if err := h.uc.Delete(userID, id); err != nil {
+    if err := h.uc.Delete(userID, id, version); err != nil {
         if err == post.ErrForbidden { httpx.RespondWithError(c, http.StatusForbidden, "Forbidden"); return }
+        if err == post.ErrVersionMismatch { httpx.RespondWithError(c, http.StatusPreconditionFailed, "Post was changed since it was read"); return }
         httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to delete post"); return
     }
     httpx.RespondWithMessage(c, http.StatusOK, "Post deleted successfully")
 }
 
+func (h *postHandler) restore(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil { httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID"); return }
+    if err := h.uc.Restore(c.MustGet("userID").(int), id); err != nil {
+        if err == post.ErrNotFound { httpx.RespondWithError(c, http.StatusNotFound, "Post not found in trash"); return }
+        if err == post.ErrForbidden { httpx.RespondWithError(c, http.StatusForbidden, "Forbidden"); return }
+        if err == post.ErrExpired { httpx.RespondWithError(c, http.StatusGone, "Post can no longer be restored"); return }
+        httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to restore post"); return
+    }
+    httpx.RespondWithMessage(c, http.StatusOK, "Post restored successfully")
+}
+
+func (h *postHandler) settings(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil { httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID"); return }
+    s, err := h.uc.Settings(id)
+    if err != nil {
+        if err == post.ErrNotFound { httpx.RespondWithError(c, http.StatusNotFound, "Post not found"); return }
+        httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch post settings"); return
+    }
+    httpx.RespondWithSuccess(c, http.StatusOK, s)
+}
 
+func (h *postHandler) updateSettings(c *gin.Context) {
+    id, err := strconv.Atoi(c.Param("id"))
+    if err != nil { httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID"); return }
+    var req post.UpdateSettingsRequest
+    if err := c.ShouldBindJSON(&req); err != nil { httpx.RespondWithError(c, http.StatusBadRequest, err.Error()); return }
+    s, err := h.uc.UpdateSettings(c.MustGet("userID").(int), id, req)
+    if err != nil {
+        if err == post.ErrForbidden { httpx.RespondWithError(c, http.StatusForbidden, "Forbidden"); return }
+        if err == post.ErrNotFound { httpx.RespondWithError(c, http.StatusNotFound, "Post not found"); return }
+        httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to update post settings"); return
+    }
+    httpx.RespondWithSuccess(c, http.StatusOK, s)
+}
@@ -0,0 +1,33 @@
+package apihttp
+
+import (
+	"majoo-case1-rest-api/internal/content"
+	httpx "majoo-case1-rest-api/internal/http"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterPreviewRoutes(rg *gin.RouterGroup) {
+	rg.POST("/preview", preview)
+}
+
+// preview renders content exactly as a post or comment would be, without saving.
+func preview(c *gin.Context) {
+	var req content.PreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	format, err := content.ParseFormat(req.ContentFormat)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Unknown content format")
+		return
+	}
+	html, err := content.Render(format, req.Content)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to render content")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, content.PreviewResponse{ContentFormat: format, ContentHTML: html})
+}
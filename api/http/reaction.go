@@ -0,0 +1,56 @@
+package apihttp
+
+import (
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/reaction"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type reactionHandler struct{ uc *reaction.Usecase }
+
+func RegisterReactionRoutes(read, write *gin.RouterGroup, uc *reaction.Usecase) {
+	h := &reactionHandler{uc: uc}
+	read.GET("/reactions/kinds", h.kinds)
+	write.PUT("/posts/:id/reactions/:kind", h.set(reaction.Post, true))
+	write.DELETE("/posts/:id/reactions/:kind", h.set(reaction.Post, false))
+	write.PUT("/comments/:id/reactions/:kind", h.set(reaction.Comment, true))
+	write.DELETE("/comments/:id/reactions/:kind", h.set(reaction.Comment, false))
+}
+
+func (h *reactionHandler) kinds(c *gin.Context) {
+	httpx.RespondWithSuccess(c, http.StatusOK, gin.H{"kinds": h.uc.Kinds()})
+}
+
+// set adds (on) or removes the caller's reaction and responds with the
+// target's updated summary. Both are idempotent.
+func (h *reactionHandler) set(t reaction.Target, on bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			httpx.RespondWithError(c, http.StatusBadRequest, "Invalid ID")
+			return
+		}
+		userID := c.MustGet("userID").(int)
+		var s reaction.Summary
+		if on {
+			s, err = h.uc.Add(t, id, userID, c.Param("kind"))
+		} else {
+			s, err = h.uc.Remove(t, id, userID, c.Param("kind"))
+		}
+		if err != nil {
+			switch err {
+			case reaction.ErrUnknownKind:
+				httpx.RespondWithError(c, http.StatusBadRequest, "Unknown reaction kind")
+			case reaction.ErrNotFound:
+				httpx.RespondWithError(c, http.StatusNotFound, "Not found")
+			default:
+				httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to update reaction")
+			}
+			return
+		}
+		httpx.RespondWithSuccess(c, http.StatusOK, s)
+	}
+}
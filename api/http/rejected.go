@@ -0,0 +1,21 @@
+package apihttp
+
+import (
+	"errors"
+	"majoo-case1-rest-api/internal/filter"
+	httpx "majoo-case1-rest-api/internal/http"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondRejected answers 422 with the filter's reason when err is a
+// content filter rejection, and reports whether it did.
+func respondRejected(c *gin.Context, err error) bool {
+	var rejected *filter.RejectedError
+	if !errors.As(err, &rejected) {
+		return false
+	}
+	httpx.RespondWithError(c, http.StatusUnprocessableEntity, "Content rejected: "+rejected.Reason)
+	return true
+}
@@ -0,0 +1,118 @@
+package apihttp
+
+import (
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/report"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type reportHandler struct{ uc *report.Usecase }
+
+// RegisterReportRoutes registers reporting on write and report triage on
+// moderators, which must be restricted to moderators.
+func RegisterReportRoutes(write, moderators *gin.RouterGroup, uc *report.Usecase) {
+	h := &reportHandler{uc: uc}
+	write.POST("/posts/:id/report", h.create(report.Post))
+	write.POST("/comments/:id/report", h.create(report.Comment))
+	moderators.GET("/reports", h.list)
+	moderators.GET("/reports/:id", h.get)
+	moderators.POST("/reports/:id/resolve", h.resolve)
+}
+
+func (h *reportHandler) create(t report.Target) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			httpx.RespondWithError(c, http.StatusBadRequest, "Invalid "+string(t)+" ID")
+			return
+		}
+		var req report.CreateReportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		rp, err := h.uc.Report(c.MustGet("userID").(int), t, id, req)
+		if err != nil {
+			switch err {
+			case report.ErrUnknownReason:
+				httpx.RespondWithError(c, http.StatusBadRequest, "Unknown reason")
+			case report.ErrNotFound:
+				httpx.RespondWithError(c, http.StatusNotFound, "Not found")
+			case report.ErrDuplicate:
+				httpx.RespondWithError(c, http.StatusConflict, "You have already reported this "+string(t))
+			default:
+				httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to report "+string(t))
+			}
+			return
+		}
+		httpx.RespondWithSuccess(c, http.StatusCreated, rp)
+	}
+}
+
+func (h *reportHandler) list(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	page, err := h.uc.List(c.Query("status"), report.Target(c.Query("target")), c.Query("cursor"), limit)
+	if err != nil {
+		switch err {
+		case report.ErrInvalidStatus:
+			httpx.RespondWithError(c, http.StatusBadRequest, "status must be open or resolved")
+		case report.ErrUnknownTarget:
+			httpx.RespondWithError(c, http.StatusBadRequest, "target must be post or comment")
+		case report.ErrInvalidCursor:
+			httpx.RespondWithError(c, http.StatusBadRequest, "Invalid cursor")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch reports")
+		}
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, page)
+}
+
+func (h *reportHandler) get(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid report ID")
+		return
+	}
+	rp, err := h.uc.Get(id)
+	if err != nil {
+		if err == report.ErrNotFound {
+			httpx.RespondWithError(c, http.StatusNotFound, "Report not found")
+			return
+		}
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch report")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, rp)
+}
+
+func (h *reportHandler) resolve(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid report ID")
+		return
+	}
+	var req report.ResolveReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	rp, err := h.uc.Resolve(c.MustGet("userID").(int), id, req)
+	if err != nil {
+		switch err {
+		case report.ErrUnknownOutcome:
+			httpx.RespondWithError(c, http.StatusBadRequest, "outcome must be dismissed or removed")
+		case report.ErrNotFound:
+			httpx.RespondWithError(c, http.StatusNotFound, "Report not found")
+		case report.ErrResolved:
+			httpx.RespondWithError(c, http.StatusConflict, "Report already resolved")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to resolve report")
+		}
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, rp)
+}
@@ -0,0 +1,142 @@
+package apihttp
+
+import (
+	"fmt"
+	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/comment"
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/stream"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxStreamPosts caps how many posts one stream may follow.
+	maxStreamPosts = 50
+	// maxStreamReplay caps how many missed events a resuming client is sent.
+	maxStreamReplay = 1000
+)
+
+type streamHandler struct {
+	hub       *stream.Hub
+	comments  *comment.Usecase
+	heartbeat time.Duration
+}
+
+// RegisterStreamRoutes serves the event stream; comments decides which posts
+// a stream may follow.
+func RegisterStreamRoutes(rg *gin.RouterGroup, hub *stream.Hub, comments *comment.Usecase, cfg config.Config) {
+	h := &streamHandler{hub: hub, comments: comments, heartbeat: cfg.StreamHeartbeat}
+	rg.GET("/stream", h.stream)
+}
+
+// stream serves Server-Sent Events for the user's notifications and for
+// comment activity on the posts listed in ?posts=1,2.
+func (h *streamHandler) stream(c *gin.Context) {
+	userID := c.MustGet("userID").(int)
+	channels := []string{stream.UserChannel(userID)}
+	if raw := c.Query("posts"); raw != "" {
+		ids := strings.Split(raw, ",")
+		if len(ids) > maxStreamPosts {
+			httpx.RespondWithError(c, http.StatusBadRequest, fmt.Sprintf("At most %d posts per stream", maxStreamPosts))
+			return
+		}
+		postIDs := make([]int, 0, len(ids))
+		for _, s := range ids {
+			id, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || id <= 0 {
+				httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID")
+				return
+			}
+			postIDs = append(postIDs, id)
+			channels = append(channels, stream.PostChannel(id))
+		}
+		// following a post shows the comments ListByPost would
+		if err := h.comments.CheckReadable(postIDs); err != nil {
+			if err == comment.ErrNotFound {
+				httpx.RespondWithError(c, http.StatusNotFound, "Post not found")
+				return
+			}
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to check posts")
+			return
+		}
+	}
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	var after int64
+	if lastID != "" {
+		var err error
+		if after, err = strconv.ParseInt(lastID, 10, 64); err != nil || after < 0 {
+			httpx.RespondWithError(c, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+	}
+
+	// Subscribe before replaying so nothing committed in between is lost;
+	// the replayed events that also arrive live are skipped below.
+	sub := h.hub.Subscribe(channels)
+	defer sub.Close()
+	var missed []stream.Message
+	if after > 0 {
+		var err error
+		if missed, err = h.hub.Replay(channels, after, maxStreamReplay); err != nil {
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to replay events")
+			return
+		}
+	}
+
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	// IDs are assigned before commit, so an event with a lower ID than one
+	// already sent may still arrive; only the replayed events themselves are
+	// duplicates, and each arrives live at most once.
+	replayed := make(map[int64]bool, len(missed))
+	for _, m := range missed {
+		writeEvent(w, m)
+		replayed[m.ID] = true
+	}
+	w.Flush()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client resumes with Last-Event-ID.
+				return
+			}
+			// Unstored broadcasts have no ID; only live sockets use them.
+			if m.ID == 0 {
+				continue
+			}
+			if replayed[m.ID] {
+				delete(replayed, m.ID)
+				continue
+			}
+			writeEvent(w, m)
+			w.Flush()
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+			w.Flush()
+		}
+	}
+}
+
+func writeEvent(w gin.ResponseWriter, m stream.Message) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", m.ID, m.Type, m.Data)
+}
@@ -0,0 +1,27 @@
+package apihttp
+
+import (
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/tag"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type tagHandler struct{ uc *tag.Usecase }
+
+func RegisterTagRoutes(rg *gin.RouterGroup, uc *tag.Usecase) {
+	h := &tagHandler{uc: uc}
+	rg.GET("/tags", h.list)
+}
+
+func (h *tagHandler) list(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	tags, err := h.uc.List(limit)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch tags")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, gin.H{"tags": tags})
+}
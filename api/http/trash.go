@@ -0,0 +1,30 @@
+package apihttp
+
+import (
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/trash"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type trashHandler struct{ uc *trash.Usecase }
+
+// RegisterTrashRoutes registers the caller's trash on rg, which must require
+// authentication. Restoring lives with the post and comment routes.
+func RegisterTrashRoutes(rg *gin.RouterGroup, uc *trash.Usecase) {
+	h := &trashHandler{uc: uc}
+	rg.GET("/me/trash", h.list)
+}
+
+func (h *trashHandler) list(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	items, err := h.uc.List(c.MustGet("userID").(int), page, limit)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch trash")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, gin.H{"items": items, "page": page, "limit": limit})
+}
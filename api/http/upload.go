@@ -0,0 +1,189 @@
+package apihttp
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/media"
+	"majoo-case1-rest-api/internal/storage"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is slack on top of the file size for multipart framing.
+const multipartOverhead = 1 << 20
+
+type uploadHandler struct{ uc *media.Usecase }
+
+// RegisterUploadRoutes registers a post's attachment list on read, since it is
+// part of displaying the post; everything else is per-user and goes on write.
+func RegisterUploadRoutes(read, write *gin.RouterGroup, uc *media.Usecase) {
+	h := &uploadHandler{uc: uc}
+	write.POST("/uploads", h.upload)
+	write.GET("/uploads/:id", h.get)
+	read.GET("/posts/:id/attachments", h.listByPost)
+	write.POST("/posts/:id/attachments", h.attach)
+	write.DELETE("/posts/:id/attachments/:uploadId", h.detach)
+}
+
+// RegisterFileRoutes serves blobs of the local storage backend. Access is
+// granted by the URL signature, so these routes need no auth cookie.
+func RegisterFileRoutes(rg *gin.RouterGroup, store *storage.Local) {
+	rg.GET("/files/*key", func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("key"), "/")
+		if err := store.Verify(key, c.Query("expires"), c.Query("signature"), time.Now()); err != nil {
+			httpx.RespondWithError(c, http.StatusForbidden, "Invalid or expired link")
+			return
+		}
+		rc, err := store.Get(c.Request.Context(), key)
+		if err != nil {
+			httpx.RespondWithError(c, http.StatusNotFound, "File not found")
+			return
+		}
+		defer rc.Close()
+		c.Header("Cache-Control", "private, max-age=300")
+		if rs, ok := rc.(io.ReadSeeker); ok {
+			http.ServeContent(c.Writer, c.Request, path.Base(key), time.Time{}, rs)
+			return
+		}
+		c.Status(http.StatusOK)
+		_, _ = io.Copy(c.Writer, rc)
+	})
+}
+
+func (h *uploadHandler) upload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.uc.MaxBytes()+multipartOverhead)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httpx.RespondWithError(c, http.StatusRequestEntityTooLarge, "File too large")
+			return
+		}
+		httpx.RespondWithError(c, http.StatusBadRequest, "Multipart field 'file' is required")
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Unreadable file")
+		return
+	}
+	defer f.Close()
+	userID := c.MustGet("userID").(int)
+	up, err := h.uc.Upload(c.Request.Context(), userID, fh.Filename, fh.Size, f)
+	if err != nil {
+		switch err {
+		case media.ErrTooLarge:
+			httpx.RespondWithError(c, http.StatusRequestEntityTooLarge, "File too large")
+		case media.ErrUnsupportedType:
+			httpx.RespondWithError(c, http.StatusUnsupportedMediaType, "Unsupported file type")
+		case media.ErrEmpty:
+			httpx.RespondWithError(c, http.StatusBadRequest, "File is empty")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to store upload")
+		}
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusCreated, up)
+}
+
+func (h *uploadHandler) get(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid upload ID")
+		return
+	}
+	userID := c.MustGet("userID").(int)
+	up, err := h.uc.Get(userID, id)
+	if err != nil {
+		switch err {
+		case media.ErrForbidden, sql.ErrNoRows:
+			httpx.RespondWithError(c, http.StatusNotFound, "Upload not found")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch upload")
+		}
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, up)
+}
+
+func (h *uploadHandler) listByPost(c *gin.Context) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	items, err := h.uc.ListByPost(viewerID(c), postID)
+	if err != nil {
+		if err == media.ErrNotFound {
+			httpx.RespondWithError(c, http.StatusNotFound, "Post not found")
+			return
+		}
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch attachments")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, gin.H{"post_id": postID, "attachments": items})
+}
+
+func (h *uploadHandler) attach(c *gin.Context) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	var req media.AttachRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID := c.MustGet("userID").(int)
+	if err := h.uc.Attach(userID, postID, req); err != nil {
+		switch err {
+		case media.ErrForbidden:
+			httpx.RespondWithError(c, http.StatusForbidden, "Forbidden")
+		case sql.ErrNoRows:
+			httpx.RespondWithError(c, http.StatusNotFound, "Post or upload not found")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to attach upload")
+		}
+		return
+	}
+	items, err := h.uc.ListByPost(userID, postID)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch attachments")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusCreated, gin.H{"post_id": postID, "attachments": items})
+}
+
+func (h *uploadHandler) detach(c *gin.Context) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	uploadID, err := strconv.Atoi(c.Param("uploadId"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid upload ID")
+		return
+	}
+	userID := c.MustGet("userID").(int)
+	if err := h.uc.Detach(userID, postID, uploadID); err != nil {
+		switch err {
+		case media.ErrForbidden:
+			httpx.RespondWithError(c, http.StatusForbidden, "Forbidden")
+		case media.ErrNotFound, sql.ErrNoRows:
+			httpx.RespondWithError(c, http.StatusNotFound, "Attachment not found")
+		default:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to remove attachment")
+		}
+		return
+	}
+	httpx.RespondWithMessage(c, http.StatusOK, "Attachment removed successfully")
+}
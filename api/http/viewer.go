@@ -0,0 +1,11 @@
+package apihttp
+
+import "github.com/gin-gonic/gin"
+
+// viewerID returns the authenticated user's ID, or 0 for an anonymous
+// visitor on a route behind OptionalAuthMiddleware.
+func viewerID(c *gin.Context) int {
+	id, _ := c.Get("userID")
+	uid, _ := id.(int)
+	return uid
+}
@@ -0,0 +1,185 @@
+package apihttp
+
+import (
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/webhook"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type webhookHandler struct{ uc *webhook.Usecase }
+
+// RegisterWebhookRoutes registers webhook administration on rg, which must
+// be restricted to admins.
+func RegisterWebhookRoutes(rg *gin.RouterGroup, uc *webhook.Usecase) {
+	h := &webhookHandler{uc: uc}
+	rg.GET("/webhooks", h.list)
+	rg.POST("/webhooks", h.create)
+	rg.GET("/webhooks/:id", h.get)
+	rg.PATCH("/webhooks/:id", h.update)
+	rg.DELETE("/webhooks/:id", h.delete)
+	rg.POST("/webhooks/:id/rotate-secret", h.rotateSecret)
+	rg.GET("/webhooks/:id/deliveries", h.deliveries)
+	rg.GET("/webhooks/:id/deliveries/:delivery_id", h.delivery)
+	rg.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", h.redeliver)
+}
+
+// respondWebhookError maps webhook errors to responses; fallback is the
+// message for unexpected ones.
+func respondWebhookError(c *gin.Context, err error, fallback string) {
+	switch err {
+	case webhook.ErrNotFound:
+		httpx.RespondWithError(c, http.StatusNotFound, "Not found")
+	case webhook.ErrInvalidURL, webhook.ErrUnknownEvent, webhook.ErrInvalidStatus, webhook.ErrInvalidCursor:
+		httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
+	default:
+		httpx.RespondWithError(c, http.StatusInternalServerError, fallback)
+	}
+}
+
+func webhookID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid webhook ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func deliveryID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, "Invalid delivery ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *webhookHandler) list(c *gin.Context) {
+	hooks, err := h.uc.List()
+	if err != nil {
+		httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch webhooks")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, gin.H{"webhooks": hooks, "event_types": webhook.EventTypes})
+}
+
+func (h *webhookHandler) create(c *gin.Context) {
+	var req webhook.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	w, err := h.uc.Create(c.MustGet("userID").(int), req)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to create webhook")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusCreated, w)
+}
+
+func (h *webhookHandler) get(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	w, err := h.uc.Get(id)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to fetch webhook")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, w)
+}
+
+func (h *webhookHandler) update(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	var req webhook.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		httpx.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	w, err := h.uc.Update(id, req)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to update webhook")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, w)
+}
+
+func (h *webhookHandler) delete(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	if err := h.uc.Delete(id); err != nil {
+		respondWebhookError(c, err, "Failed to delete webhook")
+		return
+	}
+	httpx.RespondWithMessage(c, http.StatusOK, "Webhook deleted")
+}
+
+func (h *webhookHandler) rotateSecret(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	w, err := h.uc.RotateSecret(id)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to rotate secret")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, w)
+}
+
+func (h *webhookHandler) deliveries(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	page, err := h.uc.Deliveries(id, c.Query("status"), c.Query("cursor"), limit)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to fetch deliveries")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, page)
+}
+
+func (h *webhookHandler) delivery(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	did, ok := deliveryID(c)
+	if !ok {
+		return
+	}
+	d, err := h.uc.Delivery(id, did)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to fetch delivery")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusOK, d)
+}
+
+func (h *webhookHandler) redeliver(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	did, ok := deliveryID(c)
+	if !ok {
+		return
+	}
+	d, err := h.uc.Redeliver(id, did)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to redeliver")
+		return
+	}
+	httpx.RespondWithSuccess(c, http.StatusAccepted, d)
+}
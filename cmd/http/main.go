@@ -1,15 +1,35 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	apihttp "majoo-case1-rest-api/api/http"
 	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/block"
+	"majoo-case1-rest-api/internal/bookmark"
 	"majoo-case1-rest-api/internal/comment"
 	"majoo-case1-rest-api/internal/database"
+	"majoo-case1-rest-api/internal/event"
+	"majoo-case1-rest-api/internal/feed"
+	"majoo-case1-rest-api/internal/filter"
+	"majoo-case1-rest-api/internal/follow"
 	"majoo-case1-rest-api/internal/http/middleware"
+	"majoo-case1-rest-api/internal/idempotency"
+	"majoo-case1-rest-api/internal/live"
+	"majoo-case1-rest-api/internal/moderation"
+	"majoo-case1-rest-api/internal/media"
+	"majoo-case1-rest-api/internal/notification"
 	"majoo-case1-rest-api/internal/post"
+	"majoo-case1-rest-api/internal/purge"
+	"majoo-case1-rest-api/internal/reaction"
+	"majoo-case1-rest-api/internal/report"
+	"majoo-case1-rest-api/internal/storage"
+	"majoo-case1-rest-api/internal/stream"
+	"majoo-case1-rest-api/internal/tag"
+	"majoo-case1-rest-api/internal/trash"
 	"majoo-case1-rest-api/internal/user"
+	"majoo-case1-rest-api/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -43,8 +63,9 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, PUT, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, Idempotency-Key")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed, Retry-After")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, PUT, PATCH, DELETE, OPTIONS")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -53,20 +74,101 @@ func main() {
 	})
 
 	// Wiring usecases
+	events := event.NewBus()
 	userRepo := user.NewRepository(db)
 	userUC := user.NewUsecase(userRepo, cfg)
 	postRepo := post.NewRepository(db)
 	postUC := post.NewUsecase(db, postRepo)
+	postUC.SetEventBus(events)
 	commentRepo := comment.NewRepository(db)
 	commentUC := comment.NewUsecase(db, commentRepo)
+	commentUC.SetEventBus(events)
+	commentModeration, err := moderation.ParseMode(cfg.CommentModeration)
+	if err != nil {
+		log.Fatalf("COMMENT_MODERATION: %v", err)
+	}
+	commentUC.SetModeration(commentModeration)
+	contentFilter, err := filter.New(db, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	postUC.SetFilter(contentFilter)
+	commentUC.SetFilter(contentFilter)
+	postUC.SetTrashRetention(cfg.TrashRetention)
+	commentUC.SetTrashRetention(cfg.TrashRetention)
+	trashUC := trash.NewUsecase(trash.NewRepository(db), cfg)
+	reportUC := report.NewUsecase(db, report.NewRepository(db), cfg)
+	tagUC := tag.NewUsecase(tag.NewRepository(db))
+	reactionUC := reaction.NewUsecase(reaction.NewRepository(db), cfg)
+	store, err := storage.New(cfg)
+	if err != nil {
+		log.Fatal("Failed to init storage:", err)
+	}
+	mediaRepo := media.NewRepository(db)
+	imageProc := media.NewProcessor(mediaRepo, store, cfg)
+	imageProc.Start(context.Background())
+	mediaUC := media.NewUsecase(mediaRepo, store, imageProc, cfg)
+	feedUC := feed.NewUsecase(feed.NewRepository(db), postUC, cfg)
+	bookmarkUC := bookmark.NewUsecase(bookmark.NewRepository(db), postUC)
+	followUC := follow.NewUsecase(db, follow.NewRepository(db))
+	followUC.SetEventBus(events)
+	blockUC := block.NewUsecase(block.NewRepository(db))
+	notificationUC := notification.NewUsecase(db, notification.NewRepository(db))
+	notificationUC.Subscribe(events)
+	streamRepo := stream.NewRepository(db)
+	stream.Subscribe(events, streamRepo)
+	streamHub := stream.NewHub(streamRepo, cfg)
+	go streamHub.Run(context.Background())
+	liveUC := live.NewUsecase(live.NewRepository(db), streamHub, commentUC, cfg)
+	webhookRepo := webhook.NewRepository(db)
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, cfg)
+	webhookDispatcher.Start(context.Background())
+	webhookUC := webhook.NewUsecase(webhookRepo, webhookDispatcher)
+	webhookUC.Subscribe(events)
+	purge.NewPurger(purge.NewRepository(db), cfg).Start(context.Background())
+	idempotencyStore := idempotency.NewStore(idempotency.NewRepository(db), cfg)
+	idempotencyStore.Start(context.Background())
 
 	api := r.Group("/api/v1")
 	apihttp.RegisterAuthRoutes(api, userUC, cfg)
+	if local, ok := store.(*storage.Local); ok {
+		apihttp.RegisterFileRoutes(api, local)
+	}
+	apihttp.RegisterFeedRoutes(api, feedUC, cfg)
 
 	protected := api.Group("")
 	protected.Use(middleware.AuthMiddleware(cfg))
-	apihttp.RegisterPostRoutes(protected, postUC)
-	apihttp.RegisterCommentRoutes(protected, commentUC)
+	// Reads of published content; open to anonymous visitors unless disabled
+	public := api.Group("")
+	if cfg.PublicReadAccess {
+		public.Use(middleware.OptionalAuthMiddleware(cfg))
+	} else {
+		public.Use(middleware.AuthMiddleware(cfg))
+	}
+	idempotent := middleware.Idempotency(idempotencyStore)
+	apihttp.RegisterPostRoutes(public, protected, postUC, cfg, idempotent)
+	apihttp.RegisterCommentRoutes(public, protected, commentUC, cfg, idempotent)
+	apihttp.RegisterTagRoutes(public, tagUC)
+	apihttp.RegisterReactionRoutes(public, protected, reactionUC)
+	apihttp.RegisterPreviewRoutes(protected)
+	apihttp.RegisterUploadRoutes(public, protected, mediaUC)
+	apihttp.RegisterBookmarkRoutes(protected, bookmarkUC)
+	apihttp.RegisterFollowRoutes(public, protected, followUC)
+	apihttp.RegisterBlockRoutes(protected, blockUC)
+	apihttp.RegisterTrashRoutes(protected, trashUC)
+	apihttp.RegisterNotificationRoutes(protected, notificationUC)
+	apihttp.RegisterStreamRoutes(protected, streamHub, commentUC, cfg)
+	apihttp.RegisterLiveRoutes(protected, liveUC, cfg)
+
+	admin := protected.Group("/admin")
+	admin.Use(middleware.RequireRole(userUC.Role, user.RoleAdmin))
+	apihttp.RegisterAdminRoutes(admin, userUC)
+	apihttp.RegisterWebhookRoutes(admin, webhookUC)
+
+	moderators := protected.Group("/moderation")
+	moderators.Use(middleware.RequireRole(userUC.Role, user.RoleModerator, user.RoleAdmin))
+	apihttp.RegisterModerationRoutes(moderators, commentUC, postUC)
+	apihttp.RegisterReportRoutes(protected, moderators, reportUC)
 
 	port := cfg.Port
 	if port == "" {
@@ -0,0 +1,52 @@
+// Command purge removes posts and comments that were deleted, or replaced by
+// an edit, longer ago than PURGE_RETENTION, then exits. Use it from cron when
+// the server's own schedule is turned off with PURGE_INTERVAL=0.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/database"
+	"majoo-case1-rest-api/internal/purge"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	envPath := flag.String("env-path", "", "Path to .env file (default: config/.env or .env)")
+	dryRun := flag.Bool("dry-run", false, "Report what would be removed without removing it")
+	flag.Parse()
+
+	if *envPath != "" {
+		if err := godotenv.Load(*envPath); err != nil {
+			log.Printf("Warning: Failed to load env file from %s: %v", *envPath, err)
+		}
+	}
+
+	cfg := config.Load()
+
+	db, err := database.Initialize(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to init DB:", err)
+	}
+	defer db.Close()
+
+	// an interrupted purge keeps the batches it already committed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rep, err := purge.NewPurger(purge.NewRepository(db), cfg).Run(ctx, *dryRun)
+	verb := "Removed"
+	if rep.DryRun {
+		verb = "Would remove"
+	}
+	log.Printf("%s %s deleted more than %s ago", verb, rep, cfg.PurgeRetention)
+	if err != nil {
+		log.Fatal("Purge failed:", err)
+	}
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +15,88 @@ type Config struct {
 	DatabaseURL string
 	JWTSecret   string
 	Port        string
+
+	// PublicBaseURL is how clients reach this server; used to build absolute links.
+	PublicBaseURL string
+	// PublicReadAccess lets anonymous visitors read posts, comments and tags.
+	PublicReadAccess bool
+	// RequireIfMatch makes edits and deletes of posts and comments send the
+	// ETag they last read in If-Match; without it the header is optional.
+	RequireIfMatch bool
+	// IdempotencyTTL is how long the response to a POST sent with an
+	// Idempotency-Key is kept for replay.
+	IdempotencyTTL time.Duration
+	// IdempotencyLockTimeout is how long a request may hold its key before
+	// a retry takes the key over and runs again; keep it well above the
+	// longest a create can take.
+	IdempotencyLockTimeout time.Duration
+
+	StorageBackend  string // "local" or "s3"
+	StorageLocalDir string
+	S3Endpoint      string
+	S3Region        string
+	S3Bucket        string
+	S3AccessKey     string
+	S3SecretKey     string
+	S3PathStyle     bool
+
+	UploadMaxBytes     int64
+	UploadAllowedTypes []string
+	DownloadURLTTL     time.Duration
+
+	ImageVariants  []ImageVariant
+	ImageWorkers   int
+	ImageQueueSize int
+	ImageMaxPixels int64
+
+	ReactionKinds []string
+
+	// CommentModeration is the moderation mode of posts that do not set
+	// their own: "open", "first_time" or "all".
+	CommentModeration string
+	// ReportHideThreshold is how many open reports hide a post or comment
+	// until a moderator resolves them; 0 never hides.
+	ReportHideThreshold int
+
+	// Content filters; each action is "allow", "flag" or "reject".
+	FilterBannedWords       []string
+	FilterBannedWordsAction string
+	FilterMaxLinks          int // negative disables the link limit
+	FilterLinksAction       string
+	FilterDuplicateWindow   time.Duration // 0 disables duplicate detection
+	FilterDuplicateAction   string
+
+	// TrashRetention is how long deleted posts and comments can be restored.
+	TrashRetention time.Duration
+	// PurgeRetention is how long deleted and superseded rows are kept before
+	// they are removed for good; it defaults to TrashRetention.
+	PurgeRetention time.Duration
+	PurgeInterval  time.Duration // 0 leaves purging to the purge command
+	PurgeBatchSize int
+
+	StreamHeartbeat time.Duration
+	StreamRetention time.Duration
+	// LiveAllowedOrigins are the browser origins allowed to open the live
+	// WebSocket; when empty only same-origin pages may.
+	LiveAllowedOrigins []string
+
+	WebhookTimeout      time.Duration
+	WebhookMaxAttempts  int
+	WebhookBackoff      time.Duration
+	WebhookMaxBackoff   time.Duration
+	WebhookPollInterval time.Duration
+	WebhookWorkers      int
+
+	FeedTitle   string
+	FeedSize    int
+	FeedPostURL string // "{slug}" is replaced by the post's slug
+}
+
+// ImageVariant is a named size uploaded images are scaled down to fit within.
+type ImageVariant struct {
+	Name   string
+	Width  int
+	Height int
 }
 
 func Load() Config {
@@ -29,11 +115,72 @@ func Load() Config {
 		DatabaseURL: getenv("DATABASE_URL", ""),
 		JWTSecret:   getenv("JWT_SECRET", "your-secret-key-change-in-production"),
 		Port:        getenv("PORT", "3011"),
+
+		PublicReadAccess:       getenvBool("PUBLIC_READ_ACCESS", true),
+		RequireIfMatch:         getenvBool("REQUIRE_IF_MATCH", false),
+		IdempotencyTTL:         getenvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyLockTimeout: getenvDuration("IDEMPOTENCY_LOCK_TIMEOUT", 10*time.Minute),
+
+		StorageBackend:  getenv("STORAGE_BACKEND", "local"),
+		StorageLocalDir: getenv("STORAGE_LOCAL_DIR", "./uploads"),
+		S3Endpoint:      getenv("S3_ENDPOINT", ""),
+		S3Region:        getenv("S3_REGION", "us-east-1"),
+		S3Bucket:        getenv("S3_BUCKET", ""),
+		S3AccessKey:     getenv("S3_ACCESS_KEY", ""),
+		S3SecretKey:     getenv("S3_SECRET_KEY", ""),
+		S3PathStyle:     getenvBool("S3_PATH_STYLE", true),
+
+		UploadMaxBytes:     getenvInt64("UPLOAD_MAX_BYTES", 10<<20),
+		UploadAllowedTypes: getenvList("UPLOAD_ALLOWED_TYPES", "image/jpeg,image/png,image/gif,image/webp"),
+		DownloadURLTTL:     getenvDuration("DOWNLOAD_URL_TTL", 15*time.Minute),
+
+		ImageVariants:  parseImageVariants(getenv("IMAGE_VARIANTS", "thumbnail:200x200,medium:800x800")),
+		ImageWorkers:   int(getenvInt64("IMAGE_WORKERS", 2)),
+		ImageQueueSize: int(getenvInt64("IMAGE_QUEUE_SIZE", 100)),
+		ImageMaxPixels: getenvInt64("IMAGE_MAX_PIXELS", 40_000_000),
+
+		ReactionKinds: getenvList("REACTION_KINDS", "like,love,laugh,wow,sad,angry"),
+
+		CommentModeration:   getenv("COMMENT_MODERATION", "open"),
+		ReportHideThreshold: int(getenvInt64("REPORT_HIDE_THRESHOLD", 3)),
+
+		FilterBannedWords:       getenvList("FILTER_BANNED_WORDS", ""),
+		FilterBannedWordsAction: getenv("FILTER_BANNED_WORDS_ACTION", "reject"),
+		FilterMaxLinks:          int(getenvInt64("FILTER_MAX_LINKS", 5)),
+		FilterLinksAction:       getenv("FILTER_LINKS_ACTION", "flag"),
+		FilterDuplicateWindow:   getenvDuration("FILTER_DUPLICATE_WINDOW", 10*time.Minute),
+		FilterDuplicateAction:   getenv("FILTER_DUPLICATE_ACTION", "reject"),
+
+		TrashRetention: getenvDuration("TRASH_RETENTION", 30*24*time.Hour),
+		PurgeInterval:  getenvDuration("PURGE_INTERVAL", 24*time.Hour),
+		PurgeBatchSize: int(getenvInt64("PURGE_BATCH_SIZE", 500)),
+
+		StreamHeartbeat: getenvDuration("STREAM_HEARTBEAT", 15*time.Second),
+		StreamRetention: getenvDuration("STREAM_RETENTION", 24*time.Hour),
+
+		LiveAllowedOrigins: getenvList("LIVE_ALLOWED_ORIGINS", ""),
+
+		WebhookTimeout:      getenvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:  int(getenvInt64("WEBHOOK_MAX_ATTEMPTS", 8)),
+		WebhookBackoff:      getenvDuration("WEBHOOK_BACKOFF", 30*time.Second),
+		WebhookMaxBackoff:   getenvDuration("WEBHOOK_MAX_BACKOFF", 6*time.Hour),
+		WebhookPollInterval: getenvDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+		WebhookWorkers:      int(getenvInt64("WEBHOOK_WORKERS", 4)),
+
+		FeedTitle: getenv("FEED_TITLE", "Blog"),
+		FeedSize:  int(getenvInt64("FEED_SIZE", 20)),
 	}
+	cfg.PublicBaseURL = strings.TrimSuffix(getenv("PUBLIC_BASE_URL", "http://localhost:"+cfg.Port), "/")
+	cfg.FeedPostURL = getenv("FEED_POST_URL", cfg.PublicBaseURL+"/api/v1/posts/by-slug/{slug}")
+	cfg.PurgeRetention = getenvDuration("PURGE_RETENTION", cfg.TrashRetention)
 
 	if cfg.DatabaseURL == "" {
 		log.Fatal("DATABASE_URL is required in config/.env file")
 	}
+	// otherwise the trash would offer content that may already be gone
+	if cfg.PurgeRetention < cfg.TrashRetention {
+		log.Fatalf("PURGE_RETENTION (%s) must be at least TRASH_RETENTION (%s)", cfg.PurgeRetention, cfg.TrashRetention)
+	}
 	// PORT defaults to 3011 if not set
 
 	return cfg
@@ -46,3 +193,62 @@ func getenv(key, def string) string {
 	}
 	return val
 }
+
+func getenvBool(key string, def bool) bool {
+	val, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+func getenvInt64(key string, def int64) int64 {
+	val, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+func getenvDuration(key string, def time.Duration) time.Duration {
+	val, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+// getenvList splits a comma-separated value, dropping blanks.
+func getenvList(key, def string) []string {
+	var out []string
+	for _, v := range strings.Split(getenv(key, def), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// parseImageVariants reads "name:WxH" pairs such as "thumbnail:200x200,medium:800x800".
+func parseImageVariants(s string) []ImageVariant {
+	var out []ImageVariant
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var v ImageVariant
+		name, size, ok := strings.Cut(part, ":")
+		if ok {
+			_, err := fmt.Sscanf(size, "%dx%d", &v.Width, &v.Height)
+			ok = err == nil && v.Width > 0 && v.Height > 0
+		}
+		if !ok || name == "" {
+			log.Printf("Ignoring invalid image variant %q, expected name:WIDTHxHEIGHT", part)
+			continue
+		}
+		v.Name = name
+		out = append(out, v)
+	}
+	return out
+}
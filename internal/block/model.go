@@ -0,0 +1,21 @@
+package block
+
+import "time"
+
+// Kind is the relation one user can put another under.
+type Kind string
+
+const (
+	// Block stops the blocked user commenting on the blocker's posts and
+	// replying to their comments.
+	Block Kind = "block"
+	// Mute hides the muted user's posts and comments from the muter's lists.
+	Mute Kind = "mute"
+)
+
+// User is an entry in the caller's blocked or muted list.
+type User struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package block
+
+import "database/sql"
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+// tables maps a kind to its table and the columns naming the acting and the
+// affected user.
+var tables = map[Kind][3]string{
+	Block: {"user_blocks", "blocker_id", "blocked_id"},
+	Mute:  {"user_mutes", "muter_id", "muted_id"},
+}
+
+func (r *Repository) UserExists(id int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id=$1)", id).Scan(&exists)
+	return exists, err
+}
+
+// Add puts targetID under kind for userID; adding twice is a no-op.
+func (r *Repository) Add(k Kind, userID, targetID int) error {
+	t := tables[k]
+	_, err := r.db.Exec("INSERT INTO "+t[0]+" ("+t[1]+", "+t[2]+") VALUES ($1,$2) ON CONFLICT DO NOTHING", userID, targetID)
+	return err
+}
+
+func (r *Repository) Remove(k Kind, userID, targetID int) error {
+	t := tables[k]
+	_, err := r.db.Exec("DELETE FROM "+t[0]+" WHERE "+t[1]+"=$1 AND "+t[2]+"=$2", userID, targetID)
+	return err
+}
+
+// List returns (id, username, created_at) of the users userID put under
+// kind, most recent first.
+func (r *Repository) List(k Kind, userID, limit, offset int) (*sql.Rows, error) {
+	t := tables[k]
+	q := `SELECT u.id, u.username, x.created_at FROM ` + t[0] + ` x JOIN users u ON u.id = x.` + t[2] + `
+          WHERE x.` + t[1] + ` = $1 ORDER BY x.created_at DESC, u.id DESC LIMIT $2 OFFSET $3`
+	return r.db.Query(q, userID, limit, offset)
+}
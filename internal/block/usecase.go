@@ -0,0 +1,57 @@
+package block
+
+type Usecase struct{ repo *Repository }
+
+func NewUsecase(repo *Repository) *Usecase { return &Usecase{repo: repo} }
+
+// Add makes userID block or mute targetID; doing it twice is a no-op.
+func (u *Usecase) Add(k Kind, userID, targetID int) error {
+	if userID == targetID {
+		return ErrSelf
+	}
+	exists, err := u.repo.UserExists(targetID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return u.repo.Add(k, userID, targetID)
+}
+
+func (u *Usecase) Remove(k Kind, userID, targetID int) error {
+	return u.repo.Remove(k, userID, targetID)
+}
+
+// List returns the users userID has blocked or muted.
+func (u *Usecase) List(k Kind, userID, page, limit int) ([]User, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	rows, err := u.repo.List(k, userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []User{}
+	for rows.Next() {
+		var usr User
+		if err := rows.Scan(&usr.ID, &usr.Username, &usr.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, usr)
+	}
+	return out, rows.Err()
+}
+
+var (
+	ErrNotFound = errString("user not found")
+	ErrSelf     = errString("cannot block or mute yourself")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
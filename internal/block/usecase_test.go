@@ -0,0 +1,70 @@
+package block
+
+import (
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUsecase_Add_Self(t *testing.T) {
+	uc := NewUsecase(nil)
+	if err := uc.Add(Block, 3, 3); err != ErrSelf {
+		t.Errorf("expected ErrSelf, got %v", err)
+	}
+}
+
+func TestUsecase_Add_UnknownUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(NewRepository(db))
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM users").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	if err := uc.Add(Mute, 1, 42); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Add_UsesKindTable(t *testing.T) {
+	tests := []struct {
+		kind  Kind
+		query string
+	}{
+		{Block, "INSERT INTO user_blocks \\(blocker_id, blocked_id\\)"},
+		{Mute, "INSERT INTO user_mutes \\(muter_id, muted_id\\)"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New: %v", err)
+			}
+			defer db.Close()
+
+			uc := NewUsecase(NewRepository(db))
+
+			mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM users").
+				WithArgs(2).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			mock.ExpectExec(tt.query).
+				WithArgs(1, 2).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			if err := uc.Add(tt.kind, 1, 2); err != nil {
+				t.Errorf("Add error: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
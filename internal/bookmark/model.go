@@ -0,0 +1,19 @@
+package bookmark
+
+import (
+	"time"
+
+	"majoo-case1-rest-api/internal/post"
+)
+
+type Bookmark struct {
+	BookmarkedAt time.Time `json:"bookmarked_at"`
+	Post         post.Post `json:"post"`
+}
+
+// Page is one page of a user's bookmarks, newest first. NextCursor is empty
+// on the last page.
+type Page struct {
+	Bookmarks  []Bookmark `json:"bookmarks"`
+	NextCursor string     `json:"next_cursor"`
+}
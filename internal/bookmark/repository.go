@@ -0,0 +1,34 @@
+package bookmark
+
+import "database/sql"
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+func (r *Repository) PostExists(id int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM posts WHERE id=$1 AND deleted_at IS NULL AND hidden_at IS NULL)", id).Scan(&exists)
+	return exists, err
+}
+
+// Add bookmarks the post; bookmarking it again is a no-op.
+func (r *Repository) Add(userID, postID int) error {
+	_, err := r.db.Exec("INSERT INTO bookmarks (user_id, post_id) VALUES ($1,$2) ON CONFLICT (user_id, post_id) DO NOTHING", userID, postID)
+	return err
+}
+
+func (r *Repository) Remove(userID, postID int) error {
+	_, err := r.db.Exec("DELETE FROM bookmarks WHERE user_id=$1 AND post_id=$2", userID, postID)
+	return err
+}
+
+// List returns (id, post_id, created_at) of the user's bookmarks on live
+// posts with an id below before (0 for the first page), newest first.
+func (r *Repository) List(userID int, before int64, limit int) (*sql.Rows, error) {
+	const q = `SELECT b.id, b.post_id, b.created_at FROM bookmarks b
+               JOIN posts p ON p.id = b.post_id AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+               WHERE b.user_id = $1 AND ($2 = 0 OR b.id < $2)
+               ORDER BY b.id DESC LIMIT $3`
+	return r.db.Query(q, userID, before, limit)
+}
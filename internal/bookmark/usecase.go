@@ -0,0 +1,123 @@
+package bookmark
+
+import (
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	"majoo-case1-rest-api/internal/post"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type Usecase struct {
+	repo  *Repository
+	posts *post.Usecase
+}
+
+func NewUsecase(repo *Repository, posts *post.Usecase) *Usecase {
+	return &Usecase{repo: repo, posts: posts}
+}
+
+func (u *Usecase) Add(userID, postID int) error {
+	exists, err := u.repo.PostExists(postID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return u.repo.Add(userID, postID)
+}
+
+func (u *Usecase) Remove(userID, postID int) error {
+	return u.repo.Remove(userID, postID)
+}
+
+// List returns the page of the user's bookmarks following cursor, which is
+// empty for the first page. Bookmarks of deleted posts are skipped.
+func (u *Usecase) List(userID int, cursor string, limit int) (Page, error) {
+	if limit < 1 || limit > maxLimit {
+		limit = defaultLimit
+	}
+	before, err := decodeCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+	rows, err := u.repo.List(userID, before, limit+1)
+	if err != nil {
+		return Page{}, err
+	}
+	defer rows.Close()
+	type entry struct {
+		id     int64
+		postID int
+		at     time.Time
+	}
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.id, &e.postID, &e.at); err != nil {
+			return Page{}, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return Page{}, err
+	}
+	page := Page{Bookmarks: []Bookmark{}}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		page.NextCursor = encodeCursor(entries[limit-1].id)
+	}
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		ids[i] = e.postID
+	}
+	posts, err := u.posts.ListByIDs(userID, ids)
+	if err != nil {
+		return Page{}, err
+	}
+	byID := make(map[int]post.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+	for _, e := range entries {
+		// a post deleted between the two queries is simply left out
+		if p, ok := byID[e.postID]; ok {
+			page.Bookmarks = append(page.Bookmarks, Bookmark{BookmarkedAt: e.at, Post: p})
+		}
+	}
+	return page, nil
+}
+
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+var (
+	ErrNotFound      = errString("post not found")
+	ErrInvalidCursor = errString("invalid cursor")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
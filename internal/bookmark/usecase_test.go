@@ -0,0 +1,80 @@
+package bookmark
+
+import (
+	"testing"
+	"time"
+
+	"majoo-case1-rest-api/internal/post"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUsecase_Add_DeletedPost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(NewRepository(db), post.NewUsecase(db, post.NewRepository(db)))
+
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	if err := uc.Add(1, 3); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_List_Paginates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(NewRepository(db), post.NewUsecase(db, post.NewRepository(db)))
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT b.id, b.post_id, b.created_at FROM bookmarks").
+		WithArgs(1, int64(0), 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "created_at"}).
+			AddRow(9, 20, now).
+			AddRow(7, 10, now).
+			AddRow(4, 30, now))
+	mock.ExpectQuery("WHERE p.id = ANY").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "slug", "title", "content", "content_format", "content_html", "created_at", "updated_at", "version", "author"}).
+			AddRow(10, 2, "ten", "Ten", "a", "plain", "<p>a</p>", now, now, 1, "jane").
+			AddRow(20, 2, "twenty", "Twenty", "b", "plain", "<p>b</p>", now, now, 1, "jane"))
+	mock.ExpectQuery("SELECT pt.post_id, t.slug FROM post_tags").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "slug"}))
+	mock.ExpectQuery("FROM post_mentions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "user_id", "username", "start_offset", "length"}))
+	mock.ExpectQuery("FROM post_reactions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "kind", "count", "mine"}))
+
+	page, err := uc.List(1, "", 2)
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(page.Bookmarks) != 2 || page.Bookmarks[0].Post.ID != 20 || page.Bookmarks[1].Post.ID != 10 {
+		t.Fatalf("expected posts 20, 10 in bookmark order, got %+v", page.Bookmarks)
+	}
+	if before, err := decodeCursor(page.NextCursor); err != nil || before != 7 {
+		t.Errorf("expected cursor before 7, got %d (%v)", before, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_List_InvalidCursor(t *testing.T) {
+	uc := NewUsecase(nil, nil)
+	if _, err := uc.List(1, "not a cursor!", 10); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
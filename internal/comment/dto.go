@@ -1,11 +1,18 @@
 package comment
 
 type CreateCommentRequest struct {
-    Content string `json:"content" binding:"required,min=1"`
+    Content       string `json:"content" binding:"required,min=1"`
+    ContentFormat string `json:"content_format" binding:"omitempty,oneof=plain markdown"`
+    ParentID      *int   `json:"parent_id" binding:"omitempty,min=1"`
 }
 
 type UpdateCommentRequest struct {
-    Content *string `json:"content" binding:"omitempty,min=1"`
+    Content       *string `json:"content" binding:"omitempty,min=1"`
+    ContentFormat *string `json:"content_format" binding:"omitempty,oneof=plain markdown"`
 }
 
 
+
+type RejectCommentRequest struct {
+    Note string `json:"note" binding:"max=1000"`
+}
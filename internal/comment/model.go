@@ -1,15 +1,42 @@
 package comment
 
-import "time"
+import (
+    "time"
+
+    "majoo-case1-rest-api/internal/mention"
+    "majoo-case1-rest-api/internal/moderation"
+    "majoo-case1-rest-api/internal/reaction"
+)
 
 type Comment struct {
-    ID        int       `json:"id"`
-    PostID    int       `json:"post_id"`
-    UserID    int       `json:"user_id"`
-    Content   string    `json:"content"`
-    CreatedAt time.Time `json:"created_at"`
-    UpdatedAt time.Time `json:"updated_at"`
-    Author    string    `json:"author,omitempty"`
-}
+    ID            int       `json:"id"`
+    PostID        int       `json:"post_id"`
+    ParentID      *int      `json:"parent_id"`
+    UserID        int       `json:"user_id"`
+    Content       string    `json:"content"`
+    ContentFormat string    `json:"content_format"`
+    ContentHTML   string    `json:"content_html"`
+    CreatedAt     time.Time `json:"created_at"`
+    UpdatedAt     time.Time `json:"updated_at"`
+    Author        string    `json:"author,omitempty"`
+    // Version goes up with every edit; If-Match is checked against it.
+    Version int `json:"version"`
 
+    // Status is approved unless the comment waits for, or failed,
+    // moderation; such comments are shown only to their author and moderators.
+    Status moderation.Status `json:"status"`
+    // ModerationNote says why a comment was held or rejected; it is only
+    // shown in the moderation queue.
+    ModerationNote string `json:"moderation_note,omitempty"`
 
+    // Mentions locates the @usernames in Content that name existing users.
+    Mentions []mention.Entity `json:"mentions"`
+    reaction.Summary
+}
+
+// QueuePage is one page of the moderation queue, oldest first. NextCursor is
+// empty on the last page.
+type QueuePage struct {
+    Comments   []Comment `json:"comments"`
+    NextCursor string    `json:"next_cursor"`
+}
@@ -1,40 +1,130 @@
 package comment
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+
+	"majoo-case1-rest-api/internal/moderation"
+
+	"github.com/lib/pq"
+)
 
 type Repository struct{ db *sql.DB }
 
 func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
 
-func (r *Repository) PostExists(id int) (bool, error) {
-	var exists bool
-	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM posts WHERE id=$1 AND deleted_at IS NULL)", id).Scan(&exists)
-	return exists, err
+// postRules is what a live post allows of new comments.
+type postRules struct {
+	Mode         moderation.Mode // "" when the post follows the global mode
+	Enabled      bool
+	Locked       bool
+	MembersOnly  bool
+	PreModerated bool
+	AuthorID     int
+}
+
+// PostRules returns the comment settings of a live post.
+func (r *Repository) PostRules(id int) (postRules, error) {
+	var p postRules
+	const q = `SELECT COALESCE(comment_moderation, ''), comments_enabled, comments_locked,
+                      comments_members_only, comments_premoderated, user_id
+               FROM posts WHERE id=$1 AND deleted_at IS NULL AND hidden_at IS NULL`
+	err := r.db.QueryRow(q, id).Scan(&p.Mode, &p.Enabled, &p.Locked, &p.MembersOnly, &p.PreModerated, &p.AuthorID)
+	return p, err
+}
+
+// CountLivePosts returns how many of ids are live posts, whose comments can
+// be read.
+func (r *Repository) CountLivePosts(ids []int) (int, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM posts WHERE id = ANY($1) AND deleted_at IS NULL AND hidden_at IS NULL", pq.Array(ids)).Scan(&n)
+	return n, err
+}
+
+// Blocked reports whether userID was blocked by the author of postID or,
+// for a reply, by the author of the parent comment.
+func (r *Repository) Blocked(userID, postID int, parentID *int) (bool, error) {
+	const q = `SELECT EXISTS(SELECT 1 FROM user_blocks b WHERE b.blocked_id = $1
+                   AND (b.blocker_id = (SELECT user_id FROM posts WHERE id = $2)
+                        OR b.blocker_id = (SELECT user_id FROM comments WHERE id = $3)))`
+	var blocked bool
+	err := r.db.QueryRow(q, userID, postID, parentID).Scan(&blocked)
+	return blocked, err
+}
+
+// IsFollower reports whether userID follows authorID.
+func (r *Repository) IsFollower(userID, authorID int) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM follows WHERE follower_id=$1 AND followee_id=$2)", userID, authorID).Scan(&ok)
+	return ok, err
+}
+
+// Trust reports whether userID may skip pre-moderation on postID, being a
+// moderator or the post's author, and whether they have an approved comment.
+func (r *Repository) Trust(userID, postID int) (trusted, hasApproved bool, err error) {
+	const q = `SELECT u.role IN ('moderator', 'admin') OR p.user_id = u.id,
+                      EXISTS(SELECT 1 FROM comments c WHERE c.user_id = u.id AND c.status = 'approved')
+               FROM users u, posts p WHERE u.id = $1 AND p.id = $2`
+	err = r.db.QueryRow(q, userID, postID).Scan(&trusted, &hasApproved)
+	return trusted, hasApproved, err
+}
+
+func (r *Repository) IsModerator(userID int) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow("SELECT role IN ('moderator', 'admin') FROM users WHERE id=$1", userID).Scan(&ok)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return ok, err
 }
 
-func (r *Repository) ListByPost(postID int) (*sql.Rows, error) {
-	const q = `SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, c.updated_at, u.username as author
-               FROM comments c JOIN users u ON c.user_id = u.id
-               WHERE c.post_id = $1 AND c.deleted_at IS NULL
+// livePost joins the post of comment c, so that comments are only read
+// while their post can be.
+const livePost = "JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL AND p.hidden_at IS NULL"
+
+// ListByPost returns the live comments on a post, leaving out those by
+// users viewerID has muted.
+func (r *Repository) ListByPost(postID, viewerID int) (*sql.Rows, error) {
+	const q = `SELECT c.id, c.post_id, c.parent_id, c.user_id, c.content, c.content_format, c.content_html, c.status, c.created_at, c.updated_at, c.version, u.username as author
+               FROM comments c JOIN users u ON c.user_id = u.id ` + livePost + `
+               WHERE c.post_id = $1 AND c.deleted_at IS NULL AND c.hidden_at IS NULL
+                 AND NOT EXISTS (SELECT 1 FROM user_mutes m WHERE m.muter_id = $2 AND m.muted_id = c.user_id)
                ORDER BY c.created_at ASC`
-	return r.db.Query(q, postID)
+	return r.db.Query(q, postID, viewerID)
 }
 
 func (r *Repository) GetByID(id int) (*sql.Row, error) {
-	const q = `SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, c.updated_at, u.username as author
-               FROM comments c JOIN users u ON c.user_id = u.id WHERE c.id=$1 AND c.deleted_at IS NULL`
+	const q = `SELECT c.id, c.post_id, c.parent_id, c.user_id, c.content, c.content_format, c.content_html, c.status, c.created_at, c.updated_at, c.version, u.username as author
+               FROM comments c JOIN users u ON c.user_id = u.id ` + livePost + `
+               WHERE c.id=$1 AND c.deleted_at IS NULL AND c.hidden_at IS NULL`
 	return r.db.QueryRow(q, id), nil
 }
 
+// ParentPostID returns the post a live, approved comment belongs to, for
+// validating replies.
+func (r *Repository) ParentPostID(id int) (int, error) {
+	var postID int
+	err := r.db.QueryRow("SELECT post_id FROM comments WHERE id=$1 AND deleted_at IS NULL AND hidden_at IS NULL AND status='approved'", id).Scan(&postID)
+	return postID, err
+}
+
 func (r *Repository) GetOwnerID(id int) (int, error) {
 	var uid int
 	err := r.db.QueryRow("SELECT user_id FROM comments WHERE id=$1 AND deleted_at IS NULL", id).Scan(&uid)
 	return uid, err
 }
 
-func (r *Repository) CreateTx(tx *sql.Tx, postID, userID int, content string) (int, error) {
+// VersionTx locks the live comment and returns its version.
+func (r *Repository) VersionTx(tx *sql.Tx, id int) (int, error) {
+	var v int
+	err := tx.QueryRow("SELECT version FROM comments WHERE id=$1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&v)
+	return v, err
+}
+
+func (r *Repository) CreateTx(tx *sql.Tx, postID int, parentID *int, userID int, content, format, html string, status moderation.Status) (int, error) {
 	var id int
-	err := tx.QueryRow("INSERT INTO comments (post_id, user_id, content) VALUES ($1,$2,$3) RETURNING id", postID, userID, content).Scan(&id)
+	err := tx.QueryRow("INSERT INTO comments (post_id, parent_id, user_id, content, content_format, content_html, status) VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id",
+		postID, parentID, userID, content, format, html, status).Scan(&id)
 	return id, err
 }
 
@@ -56,7 +146,132 @@ func (r *Repository) UpdateTx(tx *sql.Tx, id int, content *string) (int, error)
 	return newID, nil
 }
 
+// RelinkTx carries what UpdateTx does not copy over to the replacement row
+// and moves rows referencing the old comment to it.
+func (r *Repository) RelinkTx(tx *sql.Tx, oldID, newID int) error {
+	stmts := []string{
+		`UPDATE comments n SET parent_id = o.parent_id, content_format = o.content_format, content_html = o.content_html,
+             status = o.status, moderated_by = o.moderated_by, moderated_at = o.moderated_at, moderation_note = o.moderation_note,
+             hidden_at = o.hidden_at, version = o.version + 1
+         FROM comments o WHERE o.id = $1 AND n.id = $2`,
+		"UPDATE comments SET superseded_by=$2 WHERE id=$1",
+		"UPDATE comments SET parent_id=$2 WHERE parent_id=$1",
+		"UPDATE comment_reactions SET comment_id=$2 WHERE comment_id=$1",
+		"UPDATE notifications SET comment_id=$2 WHERE comment_id=$1",
+		"UPDATE reports SET target_id=$2 WHERE target='comment' AND target_id=$1",
+	}
+	for _, q := range stmts {
+		if _, err := tx.Exec(q, oldID, newID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ContentTx reads the source content and format of a comment inside tx.
+func (r *Repository) ContentTx(tx *sql.Tx, id int) (string, string, error) {
+	var content, format string
+	err := tx.QueryRow("SELECT content, content_format FROM comments WHERE id=$1", id).Scan(&content, &format)
+	return content, format, err
+}
+
+func (r *Repository) PostIDTx(tx *sql.Tx, id int) (int, error) {
+	var postID int
+	err := tx.QueryRow("SELECT post_id FROM comments WHERE id=$1", id).Scan(&postID)
+	return postID, err
+}
+
+// StatusTx reads a comment's moderation status inside tx.
+func (r *Repository) StatusTx(tx *sql.Tx, id int) (moderation.Status, error) {
+	var status moderation.Status
+	err := tx.QueryRow("SELECT status FROM comments WHERE id=$1", id).Scan(&status)
+	return status, err
+}
+
+// HoldTx returns a comment to the moderation queue with a note for
+// moderators, and returns its previous status.
+func (r *Repository) HoldTx(tx *sql.Tx, id int, note string) (moderation.Status, error) {
+	const q = `UPDATE comments c SET status = 'pending', moderation_note = $2
+               FROM (SELECT status FROM comments WHERE id = $1) old
+               WHERE c.id = $1 RETURNING old.status`
+	var prev moderation.Status
+	err := tx.QueryRow(q, id, note).Scan(&prev)
+	return prev, err
+}
+
+// Queue lists live comments in status on live posts with an id above
+// afterID, oldest first.
+func (r *Repository) Queue(status moderation.Status, afterID, limit int) (*sql.Rows, error) {
+	const q = `SELECT c.id, c.post_id, c.parent_id, c.user_id, c.content, c.content_format, c.content_html, c.status, c.created_at, c.updated_at, c.version, u.username as author,
+                      COALESCE(c.moderation_note, '')
+               FROM comments c JOIN users u ON c.user_id = u.id ` + livePost + `
+               WHERE c.status = $1 AND c.id > $2 AND c.deleted_at IS NULL
+               ORDER BY c.id LIMIT $3`
+	return r.db.Query(q, status, afterID, limit)
+}
+
+// moderated describes a comment whose status SetStatusTx changed.
+type moderated struct {
+	Previous moderation.Status
+	PostID   int
+	ParentID sql.NullInt64
+	UserID   int
+	Content  string
+}
+
+// SetStatusTx records a moderator's decision on a live comment on a live
+// post and returns the comment with its previous status.
+func (r *Repository) SetStatusTx(tx *sql.Tx, id int, status moderation.Status, moderatorID int, note string) (moderated, error) {
+	const q = `WITH old AS (
+                    SELECT c.id, c.status FROM comments c ` + livePost + `
+                    WHERE c.id = $1 AND c.deleted_at IS NULL FOR UPDATE OF c
+                )
+                UPDATE comments c SET status = $2, moderated_by = $3, moderated_at = CURRENT_TIMESTAMP, moderation_note = NULLIF($4, '')
+                FROM old WHERE c.id = old.id
+                RETURNING old.status, c.post_id, c.parent_id, c.user_id, c.content`
+	var m moderated
+	err := tx.QueryRow(q, id, status, moderatorID, note).Scan(&m.Previous, &m.PostID, &m.ParentID, &m.UserID, &m.Content)
+	return m, err
+}
+
+func (r *Repository) SetRenderedTx(tx *sql.Tx, id int, format, html string) error {
+	_, err := tx.Exec("UPDATE comments SET content_format=$2, content_html=$3 WHERE id=$1", id, format, html)
+	return err
+}
+
 func (r *Repository) DeleteTx(tx *sql.Tx, id int) error {
 	_, err := tx.Exec("UPDATE comments SET deleted_at=CURRENT_TIMESTAMP WHERE id=$1 AND deleted_at IS NULL", id)
 	return err
 }
+
+// trashed is a deleted comment as Restore needs it.
+type trashed struct {
+	UserID   int
+	PostID   int
+	ParentID sql.NullInt64
+	Content  string
+	WithPost bool // deleted along with its post
+	Expired  bool // deleted longer than the retention ago
+}
+
+// Trashed returns a deleted comment; old versions left by edits are not
+// trashed.
+func (r *Repository) Trashed(id int, retention time.Duration) (trashed, error) {
+	const q = `SELECT user_id, post_id, parent_id, content, deleted_with_post, deleted_at <= CURRENT_TIMESTAMP - $2 * INTERVAL '1 second'
+               FROM comments WHERE id=$1 AND deleted_at IS NOT NULL AND superseded_by IS NULL AND NOT legacy_deleted`
+	var t trashed
+	err := r.db.QueryRow(q, id, retention.Seconds()).Scan(&t.UserID, &t.PostID, &t.ParentID, &t.Content, &t.WithPost, &t.Expired)
+	return t, err
+}
+
+// LockLiveTx locks a comment that is not deleted against deletion until tx
+// ends, returning sql.ErrNoRows when it is deleted.
+func (r *Repository) LockLiveTx(tx *sql.Tx, id int) error {
+	var one int
+	return tx.QueryRow("SELECT 1 FROM comments WHERE id=$1 AND deleted_at IS NULL FOR SHARE", id).Scan(&one)
+}
+
+func (r *Repository) RestoreTx(tx *sql.Tx, id int) error {
+	_, err := tx.Exec("UPDATE comments SET deleted_at=NULL WHERE id=$1", id)
+	return err
+}
@@ -1,6 +1,7 @@
 package comment
 
 import (
+    "database/sql"
     "regexp"
     "testing"
 
@@ -48,3 +49,18 @@ func TestRepository_UpdateTx_SoftUpdate(t *testing.T) {
 }
 
 
+
+func TestRepository_GetByID_RequiresLivePost(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock.New: %v", err) }
+    defer db.Close()
+
+    repo := NewRepository(db)
+
+    mock.ExpectQuery(regexp.QuoteMeta("JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL AND p.hidden_at IS NULL")).
+        WithArgs(4).WillReturnRows(sqlmock.NewRows(commentColumns))
+
+    row, _ := repo.GetByID(4)
+    if _, err := scanComment(row); err != sql.ErrNoRows { t.Fatalf("expected sql.ErrNoRows, got %v", err) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet: %v", err) }
+}
@@ -1,74 +1,477 @@
 package comment
 
-import "database/sql"
+import (
+    "database/sql"
+    "encoding/base64"
+    "strconv"
+    "time"
+
+    "majoo-case1-rest-api/internal/content"
+    "majoo-case1-rest-api/internal/event"
+    "majoo-case1-rest-api/internal/filter"
+    "majoo-case1-rest-api/internal/mention"
+    "majoo-case1-rest-api/internal/moderation"
+    "majoo-case1-rest-api/internal/reaction"
+)
 
 type Usecase struct {
-    repo *Repository
-    db   *sql.DB
+    repo      *Repository
+    reactions *reaction.Repository
+    mentions  *mention.Repository
+    events    *event.Bus
+    mode      moderation.Mode
+    filter    *filter.Pipeline
+    retention time.Duration
+    db        *sql.DB
+}
+
+func NewUsecase(db *sql.DB, repo *Repository) *Usecase {
+    return &Usecase{db: db, repo: repo, reactions: reaction.NewRepository(db), mentions: mention.NewRepository(db), mode: moderation.ModeOpen}
 }
 
-func NewUsecase(db *sql.DB, repo *Repository) *Usecase { return &Usecase{db: db, repo: repo} }
+// SetModeration sets the moderation mode of posts that do not set their own.
+func (u *Usecase) SetModeration(mode moderation.Mode) { u.mode = mode }
 
-func (u *Usecase) ListByPost(postID int) ([]Comment, error) {
-    rows, err := u.repo.ListByPost(postID)
+// SetFilter makes the usecase screen new and edited comments with p:
+// rejected comments fail with a *filter.RejectedError and flagged ones wait
+// for a moderator.
+func (u *Usecase) SetFilter(p *filter.Pipeline) { u.filter = p }
+
+// SetEventBus makes the usecase publish comment events on bus inside its
+// write transactions.
+func (u *Usecase) SetEventBus(bus *event.Bus) { u.events = bus }
+
+// ListByPost returns the post's comments oldest first. viewerID identifies
+// the reader for my_reactions and for which unapproved comments they may
+// see; 0 means anonymous.
+func (u *Usecase) ListByPost(viewerID, postID int) ([]Comment, error) {
+    rows, err := u.repo.ListByPost(postID, viewerID)
     if err != nil { return nil, err }
     defer rows.Close()
     var out []Comment
     for rows.Next() {
-        var c Comment
-        if err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Content, &c.CreatedAt, &c.UpdatedAt, &c.Author); err != nil { return nil, err }
+        c, err := scanComment(rows)
+        if err != nil { return nil, err }
         out = append(out, c)
     }
+    if err := rows.Err(); err != nil { return nil, err }
+    if out, err = u.visible(viewerID, out); err != nil { return nil, err }
+    if err := u.load(viewerID, out); err != nil { return nil, err }
     return out, nil
 }
 
-func (u *Usecase) Get(id int) (Comment, error) {
+// Get returns a comment, or sql.ErrNoRows when viewerID may not see it.
+func (u *Usecase) Get(viewerID, id int) (Comment, error) {
     row, _ := u.repo.GetByID(id)
+    c, err := scanComment(row)
+    if err != nil { return Comment{}, err }
+    comments, err := u.visible(viewerID, []Comment{c})
+    if err != nil { return Comment{}, err }
+    if len(comments) == 0 { return Comment{}, sql.ErrNoRows }
+    if err := u.load(viewerID, comments); err != nil { return Comment{}, err }
+    return comments[0], nil
+}
+
+// visible filters out the unapproved comments viewerID may not see: those
+// are shown only to their author and to moderators. The viewer's role is
+// only looked up when it matters.
+func (u *Usecase) visible(viewerID int, comments []Comment) ([]Comment, error) {
+    out := comments[:0]
+    checked, moderator := false, false
+    for _, c := range comments {
+        if c.Status != moderation.StatusApproved && c.UserID != viewerID {
+            if viewerID == 0 { continue }
+            if !checked {
+                var err error
+                if moderator, err = u.repo.IsModerator(viewerID); err != nil { return nil, err }
+                checked = true
+            }
+            if !moderator { continue }
+        }
+        out = append(out, c)
+    }
+    return out, nil
+}
+
+// load fills the fields kept outside the comments table, one query per field
+// for the whole batch.
+func (u *Usecase) load(viewerID int, comments []Comment) error {
+    if err := u.loadMentions(comments); err != nil { return err }
+    return u.loadReactions(viewerID, comments)
+}
+
+// loadMentions fills Mentions on every comment with one query.
+func (u *Usecase) loadMentions(comments []Comment) error {
+    if len(comments) == 0 { return nil }
+    ids := make([]int, len(comments))
+    for i := range comments { ids[i] = comments[i].ID }
+    entities, err := u.mentions.Entities(mention.Comment, ids)
+    if err != nil { return err }
+    for i := range comments {
+        comments[i].Mentions = entities[comments[i].ID]
+        if comments[i].Mentions == nil { comments[i].Mentions = []mention.Entity{} }
+    }
+    return nil
+}
+
+// loadReactions fills the reaction summary of every comment with one query.
+func (u *Usecase) loadReactions(viewerID int, comments []Comment) error {
+    if len(comments) == 0 { return nil }
+    ids := make([]int, len(comments))
+    for i := range comments { ids[i] = comments[i].ID }
+    summaries, err := u.reactions.Summaries(reaction.Comment, ids, viewerID)
+    if err != nil { return err }
+    for i := range comments {
+        if s, ok := summaries[comments[i].ID]; ok {
+            comments[i].Summary = s
+        } else {
+            comments[i].Summary = reaction.Empty()
+        }
+    }
+    return nil
+}
+
+type scanner interface {
+    Scan(dest ...interface{}) error
+}
+
+// scanComment reads the comment columns, then any extra ones into extra.
+func scanComment(s scanner, extra ...interface{}) (Comment, error) {
     var c Comment
-    if err := row.Scan(&c.ID, &c.PostID, &c.UserID, &c.Content, &c.CreatedAt, &c.UpdatedAt, &c.Author); err != nil { return Comment{}, err }
+    var parentID sql.NullInt64
+    dest := []interface{}{&c.ID, &c.PostID, &parentID, &c.UserID, &c.Content, &c.ContentFormat, &c.ContentHTML, &c.Status, &c.CreatedAt, &c.UpdatedAt, &c.Version, &c.Author}
+    err := s.Scan(append(dest, extra...)...)
+    if err != nil { return Comment{}, err }
+    if parentID.Valid {
+        id := int(parentID.Int64)
+        c.ParentID = &id
+    }
+    if c.ContentHTML == "" {
+        // rows written before content rendering existed
+        c.ContentHTML, _ = content.Render(content.Format(c.ContentFormat), c.Content)
+    }
     return c, nil
 }
 
-func (u *Usecase) Create(postID, userID int, content string) (Comment, error) {
-    exists, err := u.repo.PostExists(postID)
+func (u *Usecase) Create(postID, userID int, req CreateCommentRequest) (Comment, error) {
+    format, err := content.ParseFormat(req.ContentFormat)
+    if err != nil { return Comment{}, err }
+    html, err := content.Render(format, req.Content)
+    if err != nil { return Comment{}, err }
+    status, err := u.status(postID, userID)
     if err != nil { return Comment{}, err }
-    if !exists { return Comment{}, ErrNotFound }
+    res, err := u.filter.Run(filter.Input{UserID: userID, Target: "comment", Text: req.Content})
+    if err != nil { return Comment{}, err }
+    if err := res.Err(); err != nil { return Comment{}, err }
+    if res.Verdict == filter.Flag { status = moderation.StatusPending }
+    if req.ParentID != nil {
+        parentPostID, err := u.repo.ParentPostID(*req.ParentID)
+        if err == sql.ErrNoRows || (err == nil && parentPostID != postID) { return Comment{}, ErrInvalidParent }
+        if err != nil { return Comment{}, err }
+    }
+    blocked, err := u.repo.Blocked(userID, postID, req.ParentID)
+    if err != nil { return Comment{}, err }
+    if blocked { return Comment{}, ErrBlocked }
     tx, err := u.db.Begin()
     if err != nil { return Comment{}, err }
     defer tx.Rollback()
-    id, err := u.repo.CreateTx(tx, postID, userID, content)
+    id, err := u.repo.CreateTx(tx, postID, req.ParentID, userID, req.Content, string(format), html, status)
     if err != nil { return Comment{}, err }
+    if err := u.mentions.SaveTx(tx, mention.Comment, id, req.Content); err != nil { return Comment{}, err }
+    if res.Verdict == filter.Flag {
+        if _, err := u.repo.HoldTx(tx, id, flagNote(res)); err != nil { return Comment{}, err }
+    }
+    // a held comment is announced when a moderator approves it
+    if status == moderation.StatusApproved {
+        e := event.Event{Type: event.CommentCreated, ActorID: userID, PostID: postID, CommentID: id, Content: req.Content}
+        if req.ParentID != nil { e.ParentID = *req.ParentID }
+        if err := u.events.PublishTx(tx, e); err != nil { return Comment{}, err }
+    }
     if err := tx.Commit(); err != nil { return Comment{}, err }
-    return u.Get(id)
+    return u.Get(userID, id)
 }
 
-func (u *Usecase) Update(userID, id int, content *string) (Comment, error) {
+// status decides the moderation status of userID's new comment on postID,
+// failing with ErrNotFound when the post does not exist and with one of the
+// settings errors when the post does not take the comment.
+func (u *Usecase) status(postID, userID int) (moderation.Status, error) {
+    rules, err := u.repo.PostRules(postID)
+    if err == sql.ErrNoRows { return "", ErrNotFound }
+    if err != nil { return "", err }
+    if !rules.Enabled { return "", ErrCommentsDisabled }
+    if rules.Locked { return "", ErrCommentsLocked }
+    if rules.MembersOnly && userID != rules.AuthorID {
+        member, err := u.repo.IsFollower(userID, rules.AuthorID)
+        if err != nil { return "", err }
+        if !member { return "", ErrMembersOnly }
+    }
+    mode := rules.Mode
+    if rules.PreModerated { mode = moderation.ModeAll }
+    if mode == "" { mode = u.mode }
+    if mode == moderation.ModeOpen { return moderation.StatusApproved, nil }
+    trusted, hasApproved, err := u.repo.Trust(userID, postID)
+    if err != nil { return "", err }
+    return moderation.Decide(mode, trusted, hasApproved), nil
+}
+
+// CheckParticipant returns the error Create would give userID on postID for
+// who they are, whatever the state of the discussion: ErrNotFound,
+// ErrMembersOnly or ErrBlocked. Live rooms use it to keep out users who may
+// not take part.
+func (u *Usecase) CheckParticipant(userID, postID int) error {
+    rules, err := u.repo.PostRules(postID)
+    if err == sql.ErrNoRows { return ErrNotFound }
+    if err != nil { return err }
+    if rules.MembersOnly && userID != rules.AuthorID {
+        member, err := u.repo.IsFollower(userID, rules.AuthorID)
+        if err != nil { return err }
+        if !member { return ErrMembersOnly }
+    }
+    blocked, err := u.repo.Blocked(userID, postID, nil)
+    if err != nil { return err }
+    if blocked { return ErrBlocked }
+    return nil
+}
+
+// CheckReadable returns ErrNotFound unless the comments of every post in
+// postIDs can be read. Streams use it before following posts' activity.
+func (u *Usecase) CheckReadable(postIDs []int) error {
+    seen := make(map[int]bool, len(postIDs))
+    ids := make([]int, 0, len(postIDs))
+    for _, id := range postIDs {
+        if !seen[id] { seen[id] = true; ids = append(ids, id) }
+    }
+    n, err := u.repo.CountLivePosts(ids)
+    if err != nil { return err }
+    if n != len(ids) { return ErrNotFound }
+    return nil
+}
+
+// flagNote tells moderators why a filter held a comment.
+func flagNote(res filter.Result) string { return "flagged by " + res.Filter + ": " + res.Reason }
+
+// Update edits the comment. A non-zero version is the version the caller
+// last saw; the edit fails with ErrVersionMismatch if the comment has moved on.
+func (u *Usecase) Update(userID, id int, req UpdateCommentRequest, version int) (Comment, error) {
+    if req.ContentFormat != nil {
+        if _, err := content.ParseFormat(*req.ContentFormat); err != nil { return Comment{}, err }
+    }
     ownerID, err := u.repo.GetOwnerID(id)
+    // edited or deleted since the caller read it
+    if err == sql.ErrNoRows && version != 0 { return Comment{}, ErrVersionMismatch }
     if err != nil { return Comment{}, err }
     if ownerID != userID { return Comment{}, ErrForbidden }
+    var res filter.Result
+    if req.Content != nil {
+        if res, err = u.filter.Run(filter.Input{UserID: userID, Target: "comment", ID: id, Text: *req.Content}); err != nil { return Comment{}, err }
+        if err := res.Err(); err != nil { return Comment{}, err }
+    }
     tx, err := u.db.Begin()
     if err != nil { return Comment{}, err }
     defer tx.Rollback()
-    newID, err := u.repo.UpdateTx(tx, id, content)
+    if err := u.checkVersionTx(tx, id, version); err != nil { return Comment{}, err }
+    newID, err := u.repo.UpdateTx(tx, id, req.Content)
+    if err != nil { return Comment{}, err }
+    if err := u.repo.RelinkTx(tx, id, newID); err != nil { return Comment{}, err }
+    if req.Content != nil || req.ContentFormat != nil {
+        if err := u.rerenderTx(tx, newID, req.ContentFormat); err != nil { return Comment{}, err }
+    }
+    src, _, err := u.repo.ContentTx(tx, newID)
     if err != nil { return Comment{}, err }
+    if err := u.mentions.SaveTx(tx, mention.Comment, newID, src); err != nil { return Comment{}, err }
+    if res.Verdict == filter.Flag {
+        // readers saw the comment before the edit; it leaves their view until approved
+        prev, err := u.repo.HoldTx(tx, newID, flagNote(res))
+        if err != nil { return Comment{}, err }
+        if prev == moderation.StatusApproved {
+            postID, err := u.repo.PostIDTx(tx, newID)
+            if err != nil { return Comment{}, err }
+            if err := u.events.PublishTx(tx, event.Event{Type: event.CommentDeleted, ActorID: userID, PostID: postID, CommentID: id}); err != nil { return Comment{}, err }
+        }
+    } else if err := u.publishTx(tx, newID, event.Event{Type: event.CommentUpdated, ActorID: userID, CommentID: newID, PreviousID: id, Content: src}); err != nil { return Comment{}, err }
     if err := tx.Commit(); err != nil { return Comment{}, err }
-    return u.Get(newID)
+    return u.Get(userID, newID)
 }
 
-func (u *Usecase) Delete(userID, id int) error {
+// rerenderTx refreshes content_html from the comment's merged content,
+// switching to format first when one is given.
+func (u *Usecase) rerenderTx(tx *sql.Tx, id int, format *string) error {
+    src, current, err := u.repo.ContentTx(tx, id)
+    if err != nil { return err }
+    if format != nil { current = *format }
+    f, err := content.ParseFormat(current)
+    if err != nil { return err }
+    html, err := content.Render(f, src)
+    if err != nil { return err }
+    return u.repo.SetRenderedTx(tx, id, string(f), html)
+}
+
+// Delete moves the comment to the trash; version works as in Update.
+func (u *Usecase) Delete(userID, id, version int) error {
     ownerID, err := u.repo.GetOwnerID(id)
+    if err == sql.ErrNoRows && version != 0 { return ErrVersionMismatch }
     if err != nil { return err }
     if ownerID != userID { return ErrForbidden }
     tx, err := u.db.Begin()
     if err != nil { return err }
     defer tx.Rollback()
+    if err := u.checkVersionTx(tx, id, version); err != nil { return err }
     if err := u.repo.DeleteTx(tx, id); err != nil { return err }
+    if err := u.publishTx(tx, id, event.Event{Type: event.CommentDeleted, ActorID: userID, CommentID: id}); err != nil { return err }
     return tx.Commit()
 }
 
+// checkVersionTx locks the comment for the rest of tx and fails unless it is
+// still at version; an edit or delete since then leaves no live row under id.
+// Zero skips the check.
+func (u *Usecase) checkVersionTx(tx *sql.Tx, id, version int) error {
+    if version == 0 { return nil }
+    current, err := u.repo.VersionTx(tx, id)
+    if err == sql.ErrNoRows { return ErrVersionMismatch }
+    if err != nil { return err }
+    if current != version { return ErrVersionMismatch }
+    return nil
+}
+
+// SetTrashRetention sets how long a deleted comment can be restored.
+func (u *Usecase) SetTrashRetention(d time.Duration) { u.retention = d }
+
+// Restore brings back a comment its author deleted within the trash
+// retention. Comments deleted along with their post come back only with it.
+func (u *Usecase) Restore(userID, id int) error {
+    t, err := u.repo.Trashed(id, u.retention)
+    if err == sql.ErrNoRows { return ErrNotFound }
+    if err != nil { return err }
+    if t.UserID != userID { return ErrForbidden }
+    if t.Expired { return ErrExpired }
+    if t.WithPost { return ErrPostDeleted }
+    if _, err := u.repo.PostRules(t.PostID); err != nil {
+        if err == sql.ErrNoRows { return ErrPostDeleted }
+        return err
+    }
+    tx, err := u.db.Begin()
+    if err != nil { return err }
+    defer tx.Rollback()
+    if t.ParentID.Valid {
+        // a reply cannot come back under a comment still in the trash
+        err := u.repo.LockLiveTx(tx, int(t.ParentID.Int64))
+        if err == sql.ErrNoRows { return ErrParentDeleted }
+        if err != nil { return err }
+    }
+    if err := u.repo.RestoreTx(tx, id); err != nil { return err }
+    e := event.Event{Type: event.CommentCreated, ActorID: userID, CommentID: id, ParentID: int(t.ParentID.Int64), Content: t.Content, Restored: true}
+    if err := u.publishTx(tx, id, e); err != nil { return err }
+    return tx.Commit()
+}
+
+// publishTx fills in the post of comment id and publishes e, unless the
+// comment was never approved: readers never saw it, so there is nothing to
+// announce.
+func (u *Usecase) publishTx(tx *sql.Tx, id int, e event.Event) error {
+    status, err := u.repo.StatusTx(tx, id)
+    if err != nil { return err }
+    if status != moderation.StatusApproved { return nil }
+    if e.PostID, err = u.repo.PostIDTx(tx, id); err != nil { return err }
+    return u.events.PublishTx(tx, e)
+}
+
+// Queue returns a page of live comments in status, pending by default,
+// oldest first, following cursor, which is empty for the first page.
+func (u *Usecase) Queue(status moderation.Status, cursor string, limit int) (QueuePage, error) {
+    if status == "" { status = moderation.StatusPending }
+    if status != moderation.StatusPending && status != moderation.StatusRejected { return QueuePage{}, ErrInvalidStatus }
+    if limit < 1 || limit > maxQueueLimit { limit = defaultQueueLimit }
+    after, err := decodeCursor(cursor)
+    if err != nil { return QueuePage{}, err }
+    rows, err := u.repo.Queue(status, after, limit+1)
+    if err != nil { return QueuePage{}, err }
+    defer rows.Close()
+    page := QueuePage{Comments: []Comment{}}
+    for rows.Next() {
+        var note string
+        c, err := scanComment(rows, &note)
+        if err != nil { return QueuePage{}, err }
+        c.ModerationNote = note
+        page.Comments = append(page.Comments, c)
+    }
+    if err := rows.Err(); err != nil { return QueuePage{}, err }
+    if len(page.Comments) > limit {
+        page.Comments = page.Comments[:limit]
+        page.NextCursor = encodeCursor(page.Comments[limit-1].ID)
+    }
+    if err := u.load(0, page.Comments); err != nil { return QueuePage{}, err }
+    return page, nil
+}
+
+// Approve publishes a held or rejected comment, announcing it as created.
+func (u *Usecase) Approve(moderatorID, id int) (Comment, error) {
+    return u.moderate(moderatorID, id, moderation.StatusApproved, "")
+}
+
+// Reject hides a comment from everyone but its author and moderators. An
+// approved comment disappears from readers as if deleted.
+func (u *Usecase) Reject(moderatorID, id int, note string) (Comment, error) {
+    return u.moderate(moderatorID, id, moderation.StatusRejected, note)
+}
+
+func (u *Usecase) moderate(moderatorID, id int, status moderation.Status, note string) (Comment, error) {
+    tx, err := u.db.Begin()
+    if err != nil { return Comment{}, err }
+    defer tx.Rollback()
+    m, err := u.repo.SetStatusTx(tx, id, status, moderatorID, note)
+    if err == sql.ErrNoRows { return Comment{}, ErrNotFound }
+    if err != nil { return Comment{}, err }
+    var e event.Event
+    switch {
+    case status == moderation.StatusApproved && m.Previous != moderation.StatusApproved:
+        // the author, not the moderator, is who readers and notifications credit
+        e = event.Event{Type: event.CommentCreated, ActorID: m.UserID, PostID: m.PostID, CommentID: id, ParentID: int(m.ParentID.Int64), Content: m.Content}
+    case status == moderation.StatusRejected && m.Previous == moderation.StatusApproved:
+        e = event.Event{Type: event.CommentDeleted, ActorID: moderatorID, PostID: m.PostID, CommentID: id}
+    }
+    if e.Type != "" {
+        if err := u.events.PublishTx(tx, e); err != nil { return Comment{}, err }
+    }
+    if err := tx.Commit(); err != nil { return Comment{}, err }
+    return u.Get(moderatorID, id)
+}
+
+const (
+    defaultQueueLimit = 20
+    maxQueueLimit     = 100
+)
+
+func encodeCursor(id int) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeCursor(s string) (int, error) {
+    if s == "" { return 0, nil }
+    b, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil { return 0, ErrInvalidCursor }
+    id, err := strconv.Atoi(string(b))
+    if err != nil || id <= 0 { return 0, ErrInvalidCursor }
+    return id, nil
+}
+
 var (
-    ErrForbidden = errString("forbidden")
-    ErrNotFound  = errString("not_found")
+    ErrForbidden     = errString("forbidden")
+    ErrNotFound      = errString("not_found")
+    ErrInvalidParent = errString("invalid_parent")
+    ErrInvalidStatus = errString("invalid_status")
+    ErrInvalidCursor = errString("invalid_cursor")
+    // The post's settings refuse the new comment.
+    ErrCommentsDisabled = errString("comments_disabled")
+    ErrCommentsLocked   = errString("comments_locked")
+    ErrMembersOnly      = errString("members_only")
+    // The post's or parent comment's author blocked the commenter.
+    ErrBlocked = errString("blocked")
+    // Restore refusals.
+    ErrExpired       = errString("expired")
+    ErrPostDeleted   = errString("post_deleted")
+    ErrParentDeleted = errString("parent_deleted")
+    // The comment changed since the version the caller last saw.
+    ErrVersionMismatch = errString("version_mismatch")
 )
 
 type errString string
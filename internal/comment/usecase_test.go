@@ -4,6 +4,11 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
+
+	"majoo-case1-rest-api/internal/event"
+	"majoo-case1-rest-api/internal/filter"
+	"majoo-case1-rest-api/internal/moderation"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 )
@@ -19,11 +24,11 @@ func TestUsecase_Create_PostNotFound(t *testing.T) {
 	uc := NewUsecase(db, repo)
 
 	// Mock: post doesn't exist
-	mock.ExpectQuery("SELECT EXISTS").
+	mock.ExpectQuery("SELECT COALESCE\\(comment_moderation").
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		WillReturnError(sql.ErrNoRows)
 
-	_, err = uc.Create(1, 1, "comment")
+	_, err = uc.Create(1, 1, CreateCommentRequest{Content: "comment"})
 	if err != ErrNotFound {
 		t.Errorf("expected ErrNotFound, got %v", err)
 	}
@@ -43,11 +48,11 @@ func TestUsecase_Create_DatabaseError(t *testing.T) {
 	uc := NewUsecase(db, repo)
 
 	// Mock: database error on post exists check
-	mock.ExpectQuery("SELECT EXISTS").
+	mock.ExpectQuery("SELECT COALESCE\\(comment_moderation").
 		WithArgs(1).
 		WillReturnError(errors.New("database error"))
 
-	_, err = uc.Create(1, 1, "comment")
+	_, err = uc.Create(1, 1, CreateCommentRequest{Content: "comment"})
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -72,7 +77,7 @@ func TestUsecase_Update_Forbidden(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(999))
 
 	content := "updated"
-	_, err = uc.Update(1, 1, &content)
+	_, err = uc.Update(1, 1, UpdateCommentRequest{Content: &content}, 0)
 	if err != ErrForbidden {
 		t.Errorf("expected ErrForbidden, got %v", err)
 	}
@@ -97,7 +102,7 @@ func TestUsecase_Update_NotFound(t *testing.T) {
 		WillReturnError(sql.ErrNoRows)
 
 	content := "updated"
-	_, err = uc.Update(1, 1, &content)
+	_, err = uc.Update(1, 1, UpdateCommentRequest{Content: &content}, 0)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -121,7 +126,7 @@ func TestUsecase_Delete_Forbidden(t *testing.T) {
 		WithArgs(1).
 		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(999))
 
-	err = uc.Delete(1, 1)
+	err = uc.Delete(1, 1, 0)
 	if err != ErrForbidden {
 		t.Errorf("expected ErrForbidden, got %v", err)
 	}
@@ -145,7 +150,7 @@ func TestUsecase_Delete_NotFound(t *testing.T) {
 		WithArgs(1).
 		WillReturnError(sql.ErrNoRows)
 
-	err = uc.Delete(1, 1)
+	err = uc.Delete(1, 1, 0)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -154,6 +159,34 @@ func TestUsecase_Delete_NotFound(t *testing.T) {
 	}
 }
 
+func TestUsecase_Update_SupersededVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	// Someone else's edit already replaced the row the caller read
+	mock.ExpectQuery("SELECT user_id FROM comments").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version FROM comments WHERE id=\\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	content := "edited"
+	if _, err := uc.Update(1, 1, UpdateCommentRequest{Content: &content}, 2); err != ErrVersionMismatch {
+		t.Errorf("expected ErrVersionMismatch, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
 func TestUsecase_Get_NotFound(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -169,7 +202,7 @@ func TestUsecase_Get_NotFound(t *testing.T) {
 		WithArgs(1).
 		WillReturnError(sql.ErrNoRows)
 
-	_, err = uc.Get(1)
+	_, err = uc.Get(0, 1)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -189,14 +222,17 @@ func TestUsecase_Create_TransactionError(t *testing.T) {
 	uc := NewUsecase(db, repo)
 
 	// Mock: post exists
-	mock.ExpectQuery("SELECT EXISTS").
+	mock.ExpectQuery("SELECT COALESCE\\(comment_moderation").
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		WillReturnRows(postRows().AddRow("", true, false, false, false, 5))
 
 	// Mock: transaction begin fails
+	mock.ExpectQuery("FROM user_blocks").
+		WithArgs(1, 1, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"blocked"}).AddRow(false))
 	mock.ExpectBegin().WillReturnError(errors.New("tx begin error"))
 
-	_, err = uc.Create(1, 1, "comment")
+	_, err = uc.Create(1, 1, CreateCommentRequest{Content: "comment"})
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -205,3 +241,455 @@ func TestUsecase_Create_TransactionError(t *testing.T) {
 	}
 }
 
+
+func TestUsecase_Create_ParentOnOtherPost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	mock.ExpectQuery("SELECT COALESCE\\(comment_moderation").
+		WithArgs(1).
+		WillReturnRows(postRows().AddRow("", true, false, false, false, 5))
+	mock.ExpectQuery("SELECT post_id FROM comments").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id"}).AddRow(2))
+
+	parentID := 7
+	_, err = uc.Create(1, 1, CreateCommentRequest{Content: "reply", ParentID: &parentID})
+	if err != ErrInvalidParent {
+		t.Errorf("expected ErrInvalidParent, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Create_HeldForModeration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	uc.SetModeration(moderation.ModeFirstTime)
+	bus := event.NewBus()
+	bus.Subscribe(event.CommentCreated, func(*sql.Tx, event.Event) error {
+		t.Error("held comment was announced")
+		return nil
+	})
+	uc.SetEventBus(bus)
+
+	mock.ExpectQuery("SELECT COALESCE\\(comment_moderation").
+		WithArgs(1).
+		WillReturnRows(postRows().AddRow("", true, false, false, false, 5))
+	mock.ExpectQuery("SELECT u.role IN").
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"trusted", "has_approved"}).AddRow(false, false))
+	mock.ExpectQuery("FROM user_blocks").
+		WithArgs(2, 1, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"blocked"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO comments").
+		WithArgs(1, nil, 2, "first!", "plain", sqlmock.AnyArg(), moderation.StatusPending).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT c.id, c.post_id").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(commentColumns).AddRow(9, 1, nil, 2, "first!", "plain", "<p>first!</p>", "pending", time.Now(), time.Now(), 1, "bob"))
+	mock.ExpectQuery("FROM comment_mentions").WillReturnRows(sqlmock.NewRows([]string{"comment_id", "user_id", "username", "start_offset", "length"}))
+	mock.ExpectQuery("FROM comment_reactions").WillReturnRows(sqlmock.NewRows([]string{"comment_id", "kind", "count", "mine"}))
+
+	c, err := uc.Create(1, 2, CreateCommentRequest{Content: "first!"})
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if c.Status != moderation.StatusPending {
+		t.Errorf("expected pending comment, got %q", c.Status)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Get_PendingHiddenFromOthers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	mock.ExpectQuery("SELECT c.id, c.post_id").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(commentColumns).AddRow(9, 1, nil, 2, "first!", "plain", "<p>first!</p>", "pending", time.Now(), time.Now(), 1, "bob"))
+	mock.ExpectQuery("SELECT role IN").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"moderator"}).AddRow(false))
+
+	if _, err := uc.Get(3, 9); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Approve_AnnouncesAsAuthor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	bus := event.NewBus()
+	var got []event.Event
+	bus.Subscribe(event.CommentCreated, func(_ *sql.Tx, e event.Event) error {
+		got = append(got, e)
+		return nil
+	})
+	uc.SetEventBus(bus)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE comments c SET status").
+		WithArgs(9, moderation.StatusApproved, 5, "").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "post_id", "parent_id", "user_id", "content"}).AddRow("pending", 1, 4, 2, "first!"))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT c.id, c.post_id").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(commentColumns).AddRow(9, 1, 4, 2, "first!", "plain", "<p>first!</p>", "approved", time.Now(), time.Now(), 1, "bob"))
+	mock.ExpectQuery("FROM comment_mentions").WillReturnRows(sqlmock.NewRows([]string{"comment_id", "user_id", "username", "start_offset", "length"}))
+	mock.ExpectQuery("FROM comment_reactions").WillReturnRows(sqlmock.NewRows([]string{"comment_id", "kind", "count", "mine"}))
+
+	if _, err := uc.Approve(5, 9); err != nil {
+		t.Fatalf("Approve error: %v", err)
+	}
+	want := event.Event{Type: event.CommentCreated, ActorID: 2, PostID: 1, CommentID: 9, ParentID: 4, Content: "first!"}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Approve_PostGone(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	bus := event.NewBus()
+	published := false
+	bus.Subscribe(event.CommentCreated, func(_ *sql.Tx, e event.Event) error {
+		published = true
+		return nil
+	})
+	uc.SetEventBus(bus)
+
+	// the comment's post was deleted or hidden while it waited
+	mock.ExpectBegin()
+	mock.ExpectQuery("JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL AND p.hidden_at IS NULL").
+		WithArgs(9, moderation.StatusApproved, 5, "").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	if _, err := uc.Approve(5, 9); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if published {
+		t.Error("expected no event for a comment on a gone post")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+var commentColumns = []string{"id", "post_id", "parent_id", "user_id", "content", "content_format", "content_html", "status", "created_at", "updated_at", "version", "author"}
+
+func postRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"comment_moderation", "comments_enabled", "comments_locked", "comments_members_only", "comments_premoderated", "user_id"})
+}
+
+func TestUsecase_Create_FlaggedByFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	uc.SetFilter(filter.NewPipeline(filter.NewLinkLimit(0, filter.Flag)))
+
+	mock.ExpectQuery("SELECT COALESCE\\(comment_moderation").
+		WithArgs(1).
+		WillReturnRows(postRows().AddRow("", true, false, false, false, 5))
+	mock.ExpectQuery("FROM user_blocks").
+		WithArgs(2, 1, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"blocked"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO comments").
+		WithArgs(1, nil, 2, "https://spam.example", "plain", sqlmock.AnyArg(), moderation.StatusPending).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectQuery("UPDATE comments c SET status = 'pending', moderation_note").
+		WithArgs(9, "flagged by link_limit: contains more than 0 links").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("pending"))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT c.id, c.post_id").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(commentColumns).AddRow(9, 1, nil, 2, "https://spam.example", "plain", "", "pending", time.Now(), time.Now(), 1, "bob"))
+	mock.ExpectQuery("FROM comment_mentions").WillReturnRows(sqlmock.NewRows([]string{"comment_id", "user_id", "username", "start_offset", "length"}))
+	mock.ExpectQuery("FROM comment_reactions").WillReturnRows(sqlmock.NewRows([]string{"comment_id", "kind", "count", "mine"}))
+
+	c, err := uc.Create(1, 2, CreateCommentRequest{Content: "https://spam.example"})
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if c.Status != moderation.StatusPending {
+		t.Errorf("expected pending comment, got %q", c.Status)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Create_RefusedByPostSettings(t *testing.T) {
+	tests := []struct {
+		name                         string
+		enabled, locked, membersOnly bool
+		want                         error
+	}{
+		{"disabled", false, false, false, ErrCommentsDisabled},
+		{"locked", true, true, false, ErrCommentsLocked},
+		{"members only", true, false, true, ErrMembersOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New: %v", err)
+			}
+			defer db.Close()
+
+			uc := NewUsecase(db, NewRepository(db))
+
+			mock.ExpectQuery("SELECT COALESCE\\(comment_moderation").
+				WithArgs(1).
+				WillReturnRows(postRows().AddRow("", tt.enabled, tt.locked, tt.membersOnly, false, 5))
+			if tt.membersOnly {
+				mock.ExpectQuery("FROM follows").
+					WithArgs(2, 5).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			}
+
+			_, err = uc.Create(1, 2, CreateCommentRequest{Content: "hello"})
+			if err != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestUsecase_Create_PreModeratedPost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	mock.ExpectQuery("SELECT COALESCE\\(comment_moderation").
+		WithArgs(1).
+		WillReturnRows(postRows().AddRow("", true, false, false, true, 5))
+	mock.ExpectQuery("SELECT u.role IN").
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"trusted", "has_approved"}).AddRow(false, true))
+	mock.ExpectQuery("FROM user_blocks").
+		WithArgs(2, 1, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"blocked"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO comments").
+		WithArgs(1, nil, 2, "hello", "plain", sqlmock.AnyArg(), moderation.StatusPending).
+		WillReturnError(errors.New("stop"))
+	mock.ExpectRollback()
+
+	if _, err := uc.Create(1, 2, CreateCommentRequest{Content: "hello"}); err == nil {
+		t.Error("expected error, got nil")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Create_BlockedByAuthor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	mock.ExpectQuery("SELECT COALESCE\\(comment_moderation").
+		WithArgs(1).
+		WillReturnRows(postRows().AddRow("", true, false, false, false, 5))
+	mock.ExpectQuery("SELECT post_id FROM comments").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id"}).AddRow(1))
+	mock.ExpectQuery("FROM user_blocks").
+		WithArgs(2, 1, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"blocked"}).AddRow(true))
+
+	parentID := 7
+	_, err = uc.Create(1, 2, CreateCommentRequest{Content: "reply", ParentID: &parentID})
+	if err != ErrBlocked {
+		t.Errorf("expected ErrBlocked, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Restore_DeletedWithPost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	uc.SetTrashRetention(time.Hour)
+
+	mock.ExpectQuery("SELECT user_id, post_id, parent_id, content, deleted_with_post").
+		WithArgs(9, float64(3600)).
+		WillReturnRows(sqlmock.NewRows(trashedColumns).AddRow(2, 1, nil, "hi", true, false))
+
+	if err := uc.Restore(2, 9); err != ErrPostDeleted {
+		t.Errorf("expected ErrPostDeleted, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+var trashedColumns = []string{"user_id", "post_id", "parent_id", "content", "deleted_with_post", "expired"}
+
+func TestUsecase_Restore_ParentDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	uc.SetTrashRetention(time.Hour)
+
+	mock.ExpectQuery("SELECT user_id, post_id, parent_id, content, deleted_with_post").
+		WithArgs(9, float64(3600)).
+		WillReturnRows(sqlmock.NewRows(trashedColumns).AddRow(2, 1, 4, "reply", false, false))
+	mock.ExpectQuery("SELECT COALESCE\\(comment_moderation").
+		WithArgs(1).
+		WillReturnRows(postRows().AddRow("", true, false, false, false, 3))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT 1 FROM comments WHERE id=\\$1 AND deleted_at IS NULL FOR SHARE").
+		WithArgs(4).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	if err := uc.Restore(2, 9); err != ErrParentDeleted {
+		t.Errorf("expected ErrParentDeleted, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Restore_AnnouncesAsCreated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	uc.SetTrashRetention(time.Hour)
+	bus := event.NewBus()
+	var got []event.Event
+	bus.Subscribe(event.CommentCreated, func(_ *sql.Tx, e event.Event) error {
+		got = append(got, e)
+		return nil
+	})
+	uc.SetEventBus(bus)
+
+	mock.ExpectQuery("SELECT user_id, post_id, parent_id, content, deleted_with_post").
+		WithArgs(9, float64(3600)).
+		WillReturnRows(sqlmock.NewRows(trashedColumns).AddRow(2, 1, 4, "back again", false, false))
+	mock.ExpectQuery("SELECT COALESCE\\(comment_moderation").
+		WithArgs(1).
+		WillReturnRows(postRows().AddRow("", true, false, false, false, 3))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT 1 FROM comments").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectExec("UPDATE comments SET deleted_at=NULL").
+		WithArgs(9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT status FROM comments").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("approved"))
+	mock.ExpectQuery("SELECT post_id FROM comments").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	if err := uc.Restore(2, 9); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	want := event.Event{Type: event.CommentCreated, ActorID: 2, PostID: 1, CommentID: 9, ParentID: 4, Content: "back again", Restored: true}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_CheckReadable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	// post 2 is hidden; the repeated ID is only looked up once
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM posts WHERE id = ANY").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	if err := uc.CheckReadable([]int{1, 2, 1}); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM posts WHERE id = ANY").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	if err := uc.CheckReadable([]int{1, 2}); err != nil {
+		t.Errorf("expected the posts to be readable, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+package content
+
+type PreviewRequest struct {
+	Content       string `json:"content" binding:"required"`
+	ContentFormat string `json:"content_format" binding:"omitempty,oneof=plain markdown"`
+}
+
+type PreviewResponse struct {
+	ContentFormat Format `json:"content_format"`
+	ContentHTML   string `json:"content_html"`
+}
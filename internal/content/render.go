@@ -0,0 +1,75 @@
+package content
+
+import (
+	"bytes"
+	"html"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+type Format string
+
+const (
+	FormatPlain    Format = "plain"
+	FormatMarkdown Format = "markdown"
+)
+
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+	// policy is applied to everything we render, so stored HTML is safe to
+	// embed as-is regardless of what the renderer let through.
+	policy = bluemonday.UGCPolicy()
+)
+
+// ParseFormat validates a client-supplied format; empty means plain.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatPlain:
+		return FormatPlain, nil
+	case FormatMarkdown:
+		return FormatMarkdown, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Render turns src into sanitized HTML. Plain text is escaped, with blank
+// lines separating paragraphs and single newlines kept as line breaks.
+func Render(f Format, src string) (string, error) {
+	switch f {
+	case FormatPlain, "":
+		return policy.Sanitize(renderPlain(src)), nil
+	case FormatMarkdown:
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(src), &buf); err != nil {
+			return "", err
+		}
+		return policy.Sanitize(buf.String()), nil
+	}
+	return "", ErrUnknownFormat
+}
+
+func renderPlain(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var b strings.Builder
+	for _, para := range strings.Split(src, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+var (
+	ErrUnknownFormat = errString("unknown_content_format")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -0,0 +1,54 @@
+package content
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat(""); err != nil || f != FormatPlain {
+		t.Errorf("expected plain for empty format, got %q, %v", f, err)
+	}
+	if f, err := ParseFormat("markdown"); err != nil || f != FormatMarkdown {
+		t.Errorf("expected markdown, got %q, %v", f, err)
+	}
+	if _, err := ParseFormat("html"); err != ErrUnknownFormat {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestRender_Plain(t *testing.T) {
+	out, err := Render(FormatPlain, "Hello <b>world</b>\nline two\n\nsecond *para*")
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	want := "<p>Hello &lt;b&gt;world&lt;/b&gt;<br>line two</p>\n<p>second *para*</p>\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestRender_Markdown(t *testing.T) {
+	out, err := Render(FormatMarkdown, "# Title\n\nSome **bold** and [a link](https://example.com).")
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	for _, want := range []string{"<h1>Title</h1>", "<strong>bold</strong>", `href="https://example.com"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in %q", want, out)
+		}
+	}
+}
+
+func TestRender_MarkdownIsSanitized(t *testing.T) {
+	src := "<script>alert(1)</script>\n\n[x](javascript:alert(1)) <img src=x onerror=alert(1)>"
+	out, err := Render(FormatMarkdown, src)
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	for _, bad := range []string{"<script", "javascript:", "onerror"} {
+		if strings.Contains(out, bad) {
+			t.Errorf("unexpected %q in %q", bad, out)
+		}
+	}
+}
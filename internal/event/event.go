@@ -0,0 +1,74 @@
+// Package event lets usecases announce changes inside their transaction so
+// other subsystems can react atomically without the producers knowing them.
+package event
+
+import (
+	"database/sql"
+	"sync"
+)
+
+type Type string
+
+const (
+	PostCreated    Type = "post.created"
+	PostUpdated    Type = "post.updated"
+	PostDeleted    Type = "post.deleted"
+	CommentCreated Type = "comment.created"
+	CommentUpdated Type = "comment.updated"
+	CommentDeleted Type = "comment.deleted"
+	UserFollowed   Type = "user.followed"
+
+	NotificationCreated Type = "notification.created"
+)
+
+// Event describes a change. Fields that don't apply to the type are zero.
+// For updates, PostID/CommentID are the replacement row's ID and PreviousID
+// the row it superseded.
+type Event struct {
+	Type       Type
+	ActorID    int
+	PostID     int
+	CommentID  int
+	ParentID   int // comment replied to
+	UserID     int // user acted upon, e.g. the one followed
+	PreviousID int
+	Content    string
+	// Restored marks a created event for content brought back from the trash.
+	Restored bool
+
+	NotificationID int64
+}
+
+// Handler runs inside the producer's transaction; an error rolls it back.
+type Handler func(tx *sql.Tx, e Event) error
+
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[Type][]Handler
+}
+
+func NewBus() *Bus { return &Bus{handlers: map[Type][]Handler{}} }
+
+func (b *Bus) Subscribe(t Type, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[t] = append(b.handlers[t], h)
+}
+
+// PublishTx runs the handlers subscribed to e.Type in order, stopping at the
+// first error. Publishing on a nil Bus does nothing, so producers work
+// unchanged when no bus is wired in.
+func (b *Bus) PublishTx(tx *sql.Tx, e Event) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.RLock()
+	hs := b.handlers[e.Type]
+	b.mu.RUnlock()
+	for _, h := range hs {
+		if err := h(tx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
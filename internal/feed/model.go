@@ -0,0 +1,23 @@
+package feed
+
+import "time"
+
+// Feed is a format-neutral list of recent posts, rendered by RSS or Atom.
+type Feed struct {
+	Title   string
+	Link    string
+	SelfURL string
+	Updated time.Time
+	Entries []Entry
+}
+
+type Entry struct {
+	ID        string
+	Title     string
+	Link      string
+	Author    string
+	Tags      []string
+	HTML      string
+	Published time.Time
+	Updated   time.Time
+}
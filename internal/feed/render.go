@@ -0,0 +1,145 @@
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+const (
+	RSSContentType  = "application/rss+xml; charset=utf-8"
+	AtomContentType = "application/atom+xml; charset=utf-8"
+)
+
+type rss struct {
+	XMLName   xml.Name  `xml:"rss"`
+	Version   string    `xml:"version,attr"`
+	AtomNS    string    `xml:"xmlns:atom,attr"`
+	DCNS      string    `xml:"xmlns:dc,attr"`
+	Title     string    `xml:"channel>title"`
+	Link      string    `xml:"channel>link"`
+	Self      atomLink  `xml:"channel>atom:link"`
+	Desc      string    `xml:"channel>description"`
+	LastBuild string    `xml:"channel>lastBuildDate,omitempty"`
+	Items     []rssItem `xml:"channel>item"`
+}
+
+type rssItem struct {
+	Title      string   `xml:"title"`
+	Link       string   `xml:"link"`
+	GUID       rssGUID  `xml:"guid"`
+	Creator    string   `xml:"dc:creator,omitempty"`
+	Categories []string `xml:"category"`
+	PubDate    string   `xml:"pubDate"`
+	Desc       string   `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// RSS renders f as an RSS 2.0 document.
+func RSS(f Feed) ([]byte, error) {
+	doc := rss{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Title:   f.Title,
+		Link:    f.Link,
+		Self:    atomLink{Href: f.SelfURL, Rel: "self", Type: "application/rss+xml"},
+		Desc:    f.Title,
+	}
+	if !f.Updated.IsZero() {
+		doc.LastBuild = f.Updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, e := range f.Entries {
+		doc.Items = append(doc.Items, rssItem{
+			Title:      e.Title,
+			Link:       e.Link,
+			GUID:       rssGUID{IsPermaLink: e.ID == e.Link, Value: e.ID},
+			Creator:    e.Author,
+			Categories: e.Tags,
+			PubDate:    e.Published.UTC().Format(time.RFC1123Z),
+			Desc:       e.HTML,
+		})
+	}
+	return marshal(doc)
+}
+
+type atom struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Atom renders f as an Atom 1.0 document.
+func Atom(f Feed) ([]byte, error) {
+	doc := atom{
+		ID:      f.SelfURL,
+		Title:   f.Title,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.SelfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.Link, Rel: "alternate"},
+		},
+	}
+	for _, e := range f.Entries {
+		ae := atomEntry{
+			ID:        e.ID,
+			Title:     e.Title,
+			Link:      atomLink{Href: e.Link, Rel: "alternate"},
+			Published: e.Published.UTC().Format(time.RFC3339),
+			Updated:   e.Updated.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "html", Value: e.HTML},
+		}
+		if e.Author != "" {
+			ae.Author = &atomAuthor{Name: e.Author}
+		}
+		for _, t := range e.Tags {
+			ae.Categories = append(ae.Categories, atomCategory{Term: t})
+		}
+		doc.Entries = append(doc.Entries, ae)
+	}
+	return marshal(doc)
+}
+
+func marshal(doc interface{}) ([]byte, error) {
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
@@ -0,0 +1,86 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleFeed() Feed {
+	t := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return Feed{
+		Title:   "Blog",
+		Link:    "https://example.com",
+		SelfURL: "https://example.com/api/v1/feed.rss",
+		Updated: t,
+		Entries: []Entry{{
+			ID:        "https://example.com/posts/hello",
+			Title:     "Hello & welcome",
+			Link:      "https://example.com/posts/hello",
+			Author:    "johndoe",
+			Tags:      []string{"go"},
+			HTML:      "<p>hi</p>",
+			Published: t,
+			Updated:   t,
+		}},
+	}
+}
+
+func TestRSS(t *testing.T) {
+	b, err := RSS(sampleFeed())
+	if err != nil {
+		t.Fatalf("RSS error: %v", err)
+	}
+	out := string(b)
+	for _, want := range []string{
+		`<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/">`,
+		`<atom:link href="https://example.com/api/v1/feed.rss" rel="self" type="application/rss+xml"></atom:link>`,
+		`<title>Hello &amp; welcome</title>`,
+		`<guid isPermaLink="true">https://example.com/posts/hello</guid>`,
+		`<dc:creator>johndoe</dc:creator>`,
+		`<pubDate>Tue, 02 Jan 2024 03:04:05 +0000</pubDate>`,
+		`<description>&lt;p&gt;hi&lt;/p&gt;</description>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in\n%s", want, out)
+		}
+	}
+	if err := xml.Unmarshal(b, new(interface{})); err != nil {
+		t.Errorf("output is not well-formed: %v", err)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	b, err := Atom(sampleFeed())
+	if err != nil {
+		t.Fatalf("Atom error: %v", err)
+	}
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Content struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"content"`
+			Author struct {
+				Name string `xml:"name"`
+			} `xml:"author"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, b)
+	}
+	if doc.Updated != "2024-01-02T03:04:05Z" {
+		t.Errorf("updated = %q", doc.Updated)
+	}
+	if len(doc.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(doc.Entries))
+	}
+	e := doc.Entries[0]
+	if e.Content.Type != "html" || e.Content.Value != "<p>hi</p>" || e.Author.Name != "johndoe" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
@@ -0,0 +1,19 @@
+package feed
+
+import "database/sql"
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+func (r *Repository) Username(userID int) (string, error) {
+	var name string
+	err := r.db.QueryRow("SELECT username FROM users WHERE id = $1", userID).Scan(&name)
+	return name, err
+}
+
+func (r *Repository) TagName(slug string) (string, error) {
+	var name string
+	err := r.db.QueryRow("SELECT name FROM tags WHERE slug = $1", slug).Scan(&name)
+	return name, err
+}
@@ -0,0 +1,88 @@
+package feed
+
+import (
+	"database/sql"
+	"strings"
+
+	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/post"
+)
+
+type Usecase struct {
+	repo    *Repository
+	posts   *post.Usecase
+	title   string
+	link    string
+	size    int
+	postURL string
+}
+
+func NewUsecase(repo *Repository, posts *post.Usecase, cfg config.Config) *Usecase {
+	size := cfg.FeedSize
+	if size < 1 {
+		size = 20
+	}
+	return &Usecase{repo: repo, posts: posts, title: cfg.FeedTitle, link: cfg.PublicBaseURL, size: size, postURL: cfg.FeedPostURL}
+}
+
+// Site is the feed of the most recent posts by anyone.
+func (u *Usecase) Site() (Feed, error) {
+	return u.build(u.title, post.ListFilter{})
+}
+
+// Author is the feed of one user's posts.
+func (u *Usecase) Author(userID int) (Feed, error) {
+	name, err := u.repo.Username(userID)
+	if err == sql.ErrNoRows {
+		return Feed{}, ErrNotFound
+	}
+	if err != nil {
+		return Feed{}, err
+	}
+	return u.build(u.title+" - "+name, post.ListFilter{AuthorID: userID})
+}
+
+// Tag is the feed of posts carrying the tag with the given slug.
+func (u *Usecase) Tag(slug string) (Feed, error) {
+	name, err := u.repo.TagName(slug)
+	if err == sql.ErrNoRows {
+		return Feed{}, ErrNotFound
+	}
+	if err != nil {
+		return Feed{}, err
+	}
+	return u.build(u.title+" - #"+name, post.ListFilter{Tags: []string{slug}})
+}
+
+func (u *Usecase) build(title string, f post.ListFilter) (Feed, error) {
+	posts, err := u.posts.List(0, 1, u.size, f)
+	if err != nil {
+		return Feed{}, err
+	}
+	out := Feed{Title: title, Link: u.link, Entries: make([]Entry, 0, len(posts))}
+	for _, p := range posts {
+		link := strings.ReplaceAll(u.postURL, "{slug}", p.Slug)
+		out.Entries = append(out.Entries, Entry{
+			ID:        link,
+			Title:     p.Title,
+			Link:      link,
+			Author:    p.Author,
+			Tags:      p.Tags,
+			HTML:      p.ContentHTML,
+			Published: p.CreatedAt,
+			Updated:   p.UpdatedAt,
+		})
+		if p.UpdatedAt.After(out.Updated) {
+			out.Updated = p.UpdatedAt
+		}
+	}
+	return out, nil
+}
+
+var (
+	ErrNotFound = errString("feed not found")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -0,0 +1,78 @@
+package feed
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/post"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUsecase_Author_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(NewRepository(db), post.NewUsecase(db, post.NewRepository(db)), config.Config{})
+
+	mock.ExpectQuery("SELECT username FROM users").
+		WithArgs(7).
+		WillReturnError(sql.ErrNoRows)
+
+	if _, err := uc.Author(7); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Tag_BuildsEntries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	cfg := config.Config{FeedTitle: "Blog", FeedSize: 5, FeedPostURL: "https://example.com/p/{slug}"}
+	uc := NewUsecase(NewRepository(db), post.NewUsecase(db, post.NewRepository(db)), cfg)
+
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	mock.ExpectQuery("SELECT name FROM tags").
+		WithArgs("go").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Go"))
+	mock.ExpectQuery("SELECT p.id, p.user_id").
+		WithArgs(5, 0, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "slug", "title", "content", "content_format", "content_html", "created_at", "updated_at", "version", "author"}).
+			AddRow(2, 1, "second", "Second", "b", "plain", "<p>b</p>", older, newer, 2, "johndoe").
+			AddRow(1, 1, "first", "First", "a", "plain", "<p>a</p>", older, older, 1, "johndoe"))
+	mock.ExpectQuery("SELECT pt.post_id, t.slug FROM post_tags").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "slug"}).AddRow(1, "go").AddRow(2, "go"))
+	mock.ExpectQuery("FROM post_mentions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "user_id", "username", "start_offset", "length"}))
+	mock.ExpectQuery("FROM post_reactions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "kind", "count", "mine"}))
+
+	f, err := uc.Tag("go")
+	if err != nil {
+		t.Fatalf("Tag error: %v", err)
+	}
+	if f.Title != "Blog - #Go" {
+		t.Errorf("title = %q", f.Title)
+	}
+	if !f.Updated.Equal(newer) {
+		t.Errorf("expected feed updated at newest post edit, got %v", f.Updated)
+	}
+	if len(f.Entries) != 2 || f.Entries[0].Link != "https://example.com/p/second" {
+		t.Errorf("unexpected entries %+v", f.Entries)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package filter
+
+import (
+	"database/sql"
+	"fmt"
+
+	"majoo-case1-rest-api/config"
+)
+
+// New builds the pipeline configured by cfg, leaving out disabled filters.
+func New(db *sql.DB, cfg config.Config) (*Pipeline, error) {
+	var filters []Filter
+	if len(cfg.FilterBannedWords) > 0 {
+		action, err := ParseVerdict(cfg.FilterBannedWordsAction)
+		if err != nil {
+			return nil, fmt.Errorf("FILTER_BANNED_WORDS_ACTION: %w", err)
+		}
+		filters = append(filters, NewBannedWords(cfg.FilterBannedWords, action))
+	}
+	if cfg.FilterMaxLinks >= 0 {
+		action, err := ParseVerdict(cfg.FilterLinksAction)
+		if err != nil {
+			return nil, fmt.Errorf("FILTER_LINKS_ACTION: %w", err)
+		}
+		filters = append(filters, NewLinkLimit(cfg.FilterMaxLinks, action))
+	}
+	if cfg.FilterDuplicateWindow > 0 {
+		action, err := ParseVerdict(cfg.FilterDuplicateAction)
+		if err != nil {
+			return nil, fmt.Errorf("FILTER_DUPLICATE_ACTION: %w", err)
+		}
+		filters = append(filters, NewDuplicate(db, cfg.FilterDuplicateWindow, action))
+	}
+	return NewPipeline(filters...), nil
+}
@@ -0,0 +1,49 @@
+package filter
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// texts maps a target to the SQL expression of its text, matching what
+// usecases pass as Input.Text, and its table.
+var texts = map[string][2]string{
+	"post":    {"title || E'\\n\\n' || content", "posts"},
+	"comment": {"content", "comments"},
+}
+
+// Duplicate acts on content identical, up to case and whitespace, to a post
+// or comment of the same kind the author wrote within window.
+type Duplicate struct {
+	db     *sql.DB
+	window time.Duration
+	action Verdict
+}
+
+func NewDuplicate(db *sql.DB, window time.Duration, action Verdict) *Duplicate {
+	return &Duplicate{db: db, window: window, action: action}
+}
+
+func (f *Duplicate) Name() string { return "duplicate" }
+
+func (f *Duplicate) Check(in Input) (Verdict, string, error) {
+	t, ok := texts[in.Target]
+	if !ok {
+		return Allow, "", fmt.Errorf("filter: unknown target %q", in.Target)
+	}
+	q := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE user_id = $1 AND id <> $2 AND deleted_at IS NULL AND created_at > CURRENT_TIMESTAMP - $3 * INTERVAL '1 second'
+                      AND lower(regexp_replace(btrim(%s), '\s+', ' ', 'g')) = $4)`, t[1], t[0])
+	var dup bool
+	if err := f.db.QueryRow(q, in.UserID, in.ID, f.window.Seconds(), normalize(in.Text)).Scan(&dup); err != nil {
+		return Allow, "", err
+	}
+	if dup {
+		return f.action, "duplicates your recent " + in.Target, nil
+	}
+	return Allow, "", nil
+}
+
+// normalize lowercases s and collapses its whitespace, as the query does.
+func normalize(s string) string { return strings.ToLower(strings.Join(strings.Fields(s), " ")) }
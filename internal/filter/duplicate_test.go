@@ -0,0 +1,33 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDuplicate_ComparesNormalizedText(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	f := NewDuplicate(db, 10*time.Minute, Reject)
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM comments WHERE user_id = \\$1 AND id <> \\$2").
+		WithArgs(3, 8, 600.0, "buy my stuff").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	v, reason, err := f.Check(Input{UserID: 3, Target: "comment", ID: 8, Text: "  Buy my\n\nSTUFF "})
+	if err != nil {
+		t.Fatalf("Check error: %v", err)
+	}
+	if v != Reject || reason != "duplicates your recent comment" {
+		t.Errorf("got %s %q", v, reason)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+// Package filter screens user content for spam and abuse before it is
+// stored. Each filter returns a verdict; a pipeline runs them in order and
+// keeps the most severe.
+package filter
+
+import (
+	"errors"
+	"strings"
+)
+
+// Verdict is a filter's decision on a piece of content, least severe first.
+type Verdict int
+
+const (
+	// Allow stores the content as usual.
+	Allow Verdict = iota
+	// Flag stores the content but holds it for a moderator.
+	Flag
+	// Reject refuses the content.
+	Reject
+)
+
+var verdicts = []string{"allow", "flag", "reject"}
+
+func (v Verdict) String() string { return verdicts[v] }
+
+var ErrUnknownVerdict = errors.New("unknown filter action")
+
+// ParseVerdict reads a configured action: "allow", "flag" or "reject".
+func ParseVerdict(s string) (Verdict, error) {
+	for i, name := range verdicts {
+		if strings.EqualFold(s, name) {
+			return Verdict(i), nil
+		}
+	}
+	return Allow, ErrUnknownVerdict
+}
+
+// Input is the content to check.
+type Input struct {
+	UserID int
+	// Target is "post" or "comment".
+	Target string
+	// ID is the post or comment being edited, or 0 for new content.
+	ID   int
+	Text string
+}
+
+// Result is a pipeline's decision; Filter and Reason explain a verdict
+// other than Allow.
+type Result struct {
+	Verdict Verdict
+	Filter  string
+	Reason  string
+}
+
+// Filter checks content. reason explains a verdict other than Allow to the
+// author and to moderators.
+type Filter interface {
+	Name() string
+	Check(in Input) (v Verdict, reason string, err error)
+}
+
+// Pipeline runs filters in order.
+type Pipeline struct{ filters []Filter }
+
+func NewPipeline(filters ...Filter) *Pipeline { return &Pipeline{filters: filters} }
+
+// Run returns the most severe verdict of the filters, stopping at the first
+// rejection. A nil Pipeline allows everything.
+func (p *Pipeline) Run(in Input) (Result, error) {
+	res := Result{Verdict: Allow}
+	if p == nil {
+		return res, nil
+	}
+	for _, f := range p.filters {
+		v, reason, err := f.Check(in)
+		if err != nil {
+			return Result{}, err
+		}
+		if v > res.Verdict {
+			res = Result{Verdict: v, Filter: f.Name(), Reason: reason}
+		}
+		if v == Reject {
+			break
+		}
+	}
+	return res, nil
+}
+
+// RejectedError is returned by usecases for content a filter rejected.
+type RejectedError struct{ Result }
+
+func (e *RejectedError) Error() string { return "content rejected: " + e.Reason }
+
+// Err returns a *RejectedError for a rejection and nil otherwise.
+func (r Result) Err() error {
+	if r.Verdict != Reject {
+		return nil
+	}
+	return &RejectedError{r}
+}
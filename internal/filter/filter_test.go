@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+)
+
+type fixed struct {
+	name string
+	v    Verdict
+}
+
+func (f fixed) Name() string                         { return f.name }
+func (f fixed) Check(Input) (Verdict, string, error) { return f.v, f.name, nil }
+
+func TestPipeline_KeepsMostSevere(t *testing.T) {
+	p := NewPipeline(fixed{"a", Allow}, fixed{"b", Flag}, fixed{"c", Flag}, fixed{"d", Allow})
+	res, err := p.Run(Input{})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if res.Verdict != Flag || res.Filter != "b" {
+		t.Errorf("expected flag by b, got %+v", res)
+	}
+	if res.Err() != nil {
+		t.Errorf("flag should not be an error")
+	}
+}
+
+func TestPipeline_StopsAtReject(t *testing.T) {
+	p := NewPipeline(fixed{"a", Reject}, fixed{"b", Reject})
+	res, _ := p.Run(Input{})
+	var rejected *RejectedError
+	if !errors.As(res.Err(), &rejected) || rejected.Filter != "a" {
+		t.Errorf("expected rejection by a, got %v", res.Err())
+	}
+}
+
+func TestPipeline_NilAllows(t *testing.T) {
+	var p *Pipeline
+	if res, err := p.Run(Input{Text: "anything"}); err != nil || res.Verdict != Allow {
+		t.Errorf("expected allow, got %+v, %v", res, err)
+	}
+}
+
+func TestBannedWords(t *testing.T) {
+	f := NewBannedWords([]string{"ass", "free money"}, Reject)
+	cases := map[string]Verdict{
+		"a classy assessment":      Allow,
+		"what an ASS":              Reject,
+		"what an @$$!":             Reject,
+		"get FR33 m0n3y now":       Reject,
+		"free, money!":             Reject,
+		"free of charge, no money": Allow,
+		"spam! ass!":               Reject,
+	}
+	for text, want := range cases {
+		if v, _, _ := f.Check(Input{Text: text}); v != want {
+			t.Errorf("Check(%q) = %s, want %s", text, v, want)
+		}
+	}
+}
+
+func TestLinkLimit(t *testing.T) {
+	f := NewLinkLimit(1, Flag)
+	if v, _, _ := f.Check(Input{Text: "see https://a.example"}); v != Allow {
+		t.Errorf("one link: got %s", v)
+	}
+	if v, _, _ := f.Check(Input{Text: "see https://a.example and www.b.example"}); v != Flag {
+		t.Errorf("two links: got %s", v)
+	}
+}
+
+func TestParseVerdict(t *testing.T) {
+	if v, err := ParseVerdict("Flag"); err != nil || v != Flag {
+		t.Errorf("ParseVerdict(Flag) = %s, %v", v, err)
+	}
+	if _, err := ParseVerdict("block"); err != ErrUnknownVerdict {
+		t.Errorf("expected ErrUnknownVerdict, got %v", err)
+	}
+}
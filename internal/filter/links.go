@@ -0,0 +1,25 @@
+package filter
+
+import (
+	"regexp"
+	"strconv"
+)
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// LinkLimit acts on content with more than max links.
+type LinkLimit struct {
+	max    int
+	action Verdict
+}
+
+func NewLinkLimit(max int, action Verdict) *LinkLimit { return &LinkLimit{max: max, action: action} }
+
+func (f *LinkLimit) Name() string { return "link_limit" }
+
+func (f *LinkLimit) Check(in Input) (Verdict, string, error) {
+	if n := len(linkPattern.FindAllStringIndex(in.Text, -1)); n > f.max {
+		return f.action, "contains more than " + strconv.Itoa(f.max) + " links", nil
+	}
+	return Allow, "", nil
+}
@@ -0,0 +1,80 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// leet maps characters commonly substituted for letters back to them.
+var leet = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+	'@': 'a', '$': 's', '!': 'i', '|': 'l', '+': 't',
+}
+
+// words splits s into lowercase words. With unleet it first undoes
+// leetspeak, so "Fr33 M0n3y" yields "free" and "money"; punctuation ending
+// a word, as in "@$$!", is dropped first.
+func words(s string, unleet bool) []string {
+	s = strings.ToLower(s)
+	if unleet {
+		var b strings.Builder
+		for _, tok := range strings.Fields(s) {
+			for _, r := range strings.TrimRight(tok, ".,!?;:'\")") {
+				if l, ok := leet[r]; ok {
+					r = l
+				}
+				b.WriteRune(r)
+			}
+			b.WriteByte(' ')
+		}
+		s = b.String()
+	}
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// BannedWords matches whole words and phrases of a list, ignoring case and
+// leetspeak, so "class" does not match "ass" but "@$$" does. Text is also
+// read literally, since undoing leetspeak garbles words like "spam!".
+type BannedWords struct {
+	phrases [][]string
+	action  Verdict
+}
+
+func NewBannedWords(list []string, action Verdict) *BannedWords {
+	f := &BannedWords{action: action}
+	for _, w := range list {
+		if p := words(w, false); len(p) > 0 {
+			f.phrases = append(f.phrases, p)
+		}
+	}
+	return f
+}
+
+func (f *BannedWords) Name() string { return "banned_words" }
+
+func (f *BannedWords) Check(in Input) (Verdict, string, error) {
+	for _, text := range [][]string{words(in.Text, false), words(in.Text, true)} {
+		for i := range text {
+			for _, p := range f.phrases {
+				if hasPrefix(text[i:], p) {
+					return f.action, "contains a banned word", nil
+				}
+			}
+		}
+	}
+	return Allow, "", nil
+}
+
+func hasPrefix(text, phrase []string) bool {
+	if len(text) < len(phrase) {
+		return false
+	}
+	for i := range phrase {
+		if text[i] != phrase[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,10 @@
+package follow
+
+import "time"
+
+// User is an entry in a followers or following list.
+type User struct {
+	ID         int       `json:"id"`
+	Username   string    `json:"username"`
+	FollowedAt time.Time `json:"followed_at"`
+}
@@ -0,0 +1,43 @@
+package follow
+
+import "database/sql"
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+func (r *Repository) UserExists(id int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id=$1)", id).Scan(&exists)
+	return exists, err
+}
+
+// FollowTx records the follow and reports whether it is new.
+func (r *Repository) FollowTx(tx *sql.Tx, followerID, followeeID int) (bool, error) {
+	res, err := tx.Exec(`INSERT INTO follows (follower_id, followee_id) VALUES ($1,$2)
+                           ON CONFLICT (follower_id, followee_id) DO NOTHING`, followerID, followeeID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *Repository) Unfollow(followerID, followeeID int) error {
+	_, err := r.db.Exec("DELETE FROM follows WHERE follower_id=$1 AND followee_id=$2", followerID, followeeID)
+	return err
+}
+
+// Followers returns (id, username, followed_at) of the users following userID, most recent first.
+func (r *Repository) Followers(userID, limit, offset int) (*sql.Rows, error) {
+	const q = `SELECT u.id, u.username, f.created_at FROM follows f JOIN users u ON u.id = f.follower_id
+               WHERE f.followee_id = $1 ORDER BY f.created_at DESC, u.id DESC LIMIT $2 OFFSET $3`
+	return r.db.Query(q, userID, limit, offset)
+}
+
+// Following returns (id, username, followed_at) of the users userID follows, most recent first.
+func (r *Repository) Following(userID, limit, offset int) (*sql.Rows, error) {
+	const q = `SELECT u.id, u.username, f.created_at FROM follows f JOIN users u ON u.id = f.followee_id
+               WHERE f.follower_id = $1 ORDER BY f.created_at DESC, u.id DESC LIMIT $2 OFFSET $3`
+	return r.db.Query(q, userID, limit, offset)
+}
@@ -0,0 +1,98 @@
+package follow
+
+import (
+	"database/sql"
+
+	"majoo-case1-rest-api/internal/event"
+)
+
+type Usecase struct {
+	repo   *Repository
+	events *event.Bus
+	db     *sql.DB
+}
+
+func NewUsecase(db *sql.DB, repo *Repository) *Usecase { return &Usecase{db: db, repo: repo} }
+
+// SetEventBus makes the usecase publish user.followed for new follows.
+func (u *Usecase) SetEventBus(bus *event.Bus) { u.events = bus }
+
+// Follow makes followerID follow followeeID; following twice is a no-op.
+func (u *Usecase) Follow(followerID, followeeID int) error {
+	if followerID == followeeID {
+		return ErrSelf
+	}
+	exists, err := u.repo.UserExists(followeeID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	tx, err := u.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	added, err := u.repo.FollowTx(tx, followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	if added {
+		if err := u.events.PublishTx(tx, event.Event{Type: event.UserFollowed, ActorID: followerID, UserID: followeeID}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (u *Usecase) Unfollow(followerID, followeeID int) error {
+	return u.repo.Unfollow(followerID, followeeID)
+}
+
+func (u *Usecase) Followers(userID, page, limit int) ([]User, error) {
+	return u.list(u.repo.Followers, userID, page, limit)
+}
+
+func (u *Usecase) Following(userID, page, limit int) ([]User, error) {
+	return u.list(u.repo.Following, userID, page, limit)
+}
+
+func (u *Usecase) list(query func(userID, limit, offset int) (*sql.Rows, error), userID, page, limit int) ([]User, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	exists, err := u.repo.UserExists(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+	rows, err := query(userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []User{}
+	for rows.Next() {
+		var usr User
+		if err := rows.Scan(&usr.ID, &usr.Username, &usr.FollowedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, usr)
+	}
+	return out, rows.Err()
+}
+
+var (
+	ErrNotFound = errString("user not found")
+	ErrSelf     = errString("cannot follow yourself")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -0,0 +1,61 @@
+package follow
+
+import (
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUsecase_Follow_Self(t *testing.T) {
+	uc := NewUsecase(nil, nil)
+	if err := uc.Follow(3, 3); err != ErrSelf {
+		t.Errorf("expected ErrSelf, got %v", err)
+	}
+}
+
+func TestUsecase_Follow_UnknownUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM users").
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	if err := uc.Follow(1, 42); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Follow_Idempotent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM users").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO follows").
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if err := uc.Follow(1, 2); err != nil {
+		t.Errorf("expected repeated follow to succeed, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package httpx
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotModified sets the ETag and Last-Modified validators on the response and,
+// when the request's If-None-Match or If-Modified-Since shows the client
+// already has this representation, writes 304 and returns true. As in RFC 9110,
+// If-Modified-Since is ignored when If-None-Match is present. Either validator
+// may be left empty or zero.
+func NotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+	fresh := false
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		fresh = etag != "" && ETagMatches(inm, etag, true)
+	} else if ims := c.GetHeader("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		fresh = err == nil && !lastModified.Truncate(time.Second).After(t)
+	}
+	if fresh {
+		c.AbortWithStatus(http.StatusNotModified)
+	}
+	return fresh
+}
+
+// ETagMatches reports whether an If-Match or If-None-Match header value lists
+// etag or is "*". weak selects the weak comparison If-None-Match uses, which
+// ignores a W/ prefix on either side.
+func ETagMatches(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		} else if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
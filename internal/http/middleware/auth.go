@@ -31,3 +31,21 @@ func AuthMiddleware(cfg config.Config) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuthMiddleware identifies the caller like AuthMiddleware when a valid
+// cookie is sent but lets anonymous requests through, leaving userID unset.
+// A missing, invalid or expired token is treated as anonymous rather than
+// rejected so stale cookies don't lock visitors out of public pages.
+func OptionalAuthMiddleware(cfg config.Config) gin.HandlerFunc {
+	secret := []byte(cfg.JWTSecret)
+	return func(c *gin.Context) {
+		if token, err := c.Cookie("token"); err == nil && token != "" {
+			if claims, err := security.ValidateToken(secret, token); err == nil {
+				c.Set("userID", claims.UserID)
+				c.Set("username", claims.Username)
+				c.Set("email", claims.Email)
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/idempotency"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxIdempotencyKey is the longest Idempotency-Key accepted.
+const maxIdempotencyKey = 255
+
+// Idempotency makes a POST sent with an Idempotency-Key header safe to
+// retry: the first request under a key runs and its response is stored,
+// later ones with the same method, path and body get that response back with
+// Idempotent-Replayed set. Reusing a key for a different request, or
+// retrying while the first is still running, fails with 409. Server errors
+// are not stored, so a retry after one runs again. Keys are scoped to the
+// caller, so it must run after AuthMiddleware; requests without the header
+// pass through.
+func Idempotency(store *idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			httpx.RespondWithError(c, http.StatusBadRequest, "Idempotency-Key is too long")
+			c.Abort()
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			httpx.RespondWithError(c, http.StatusBadRequest, "Failed to read request body")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		userID := c.MustGet("userID").(int)
+
+		claim, rec, err := store.Begin(userID, key, idempotency.Fingerprint(c.Request.Method, c.Request.URL.Path, body))
+		switch {
+		case err == idempotency.ErrKeyReused:
+			httpx.RespondWithErrorCode(c, http.StatusConflict, "idempotency_key_reused", "Idempotency-Key was already used with a different request")
+			c.Abort()
+			return
+		case err == idempotency.ErrInProgress:
+			c.Header("Retry-After", "1")
+			httpx.RespondWithErrorCode(c, http.StatusConflict, "idempotency_key_in_progress", "A request with this Idempotency-Key is still in progress")
+			c.Abort()
+			return
+		case err != nil:
+			httpx.RespondWithError(c, http.StatusInternalServerError, "Failed to check Idempotency-Key")
+			c.Abort()
+			return
+		case rec != nil:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(rec.Status, rec.ContentType, rec.Body)
+			c.Abort()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		finished := false
+		// a panicking handler must not hold the key until the lock timeout
+		defer func() {
+			if !finished {
+				if err := claim.Abandon(); err != nil {
+					log.Printf("idempotency: %v", err)
+				}
+			}
+		}()
+		c.Next()
+		if status := w.Status(); status < http.StatusInternalServerError {
+			if err := claim.Finish(status, w.Header().Get("Content-Type"), w.body.Bytes()); err != nil {
+				log.Printf("idempotency: %v", err)
+			}
+			finished = true
+		}
+	}
+}
+
+// recordingWriter keeps a copy of the response body as it is written.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	httpx "majoo-case1-rest-api/internal/http"
+	"majoo-case1-rest-api/internal/user"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole admits authenticated users holding one of roles and stores
+// the caller's role as "role". It must run after AuthMiddleware. The role is
+// looked up per request so promotions and demotions apply immediately.
+func RequireRole(lookup func(userID int) (user.Role, error), roles ...user.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := lookup(c.MustGet("userID").(int))
+		if err != nil {
+			httpx.RespondWithError(c, http.StatusForbidden, "Forbidden")
+			c.Abort()
+			return
+		}
+		for _, r := range roles {
+			if r == role {
+				c.Set("role", role)
+				c.Next()
+				return
+			}
+		}
+		httpx.RespondWithError(c, http.StatusForbidden, "Forbidden")
+		c.Abort()
+	}
+}
@@ -9,12 +9,19 @@ import (
 type ErrorResponse struct {
     Error   string `json:"error"`
     Message string `json:"message,omitempty"`
+    // Code tells apart errors that share a status, for clients to act on.
+    Code string `json:"code,omitempty"`
 }
 
 func RespondWithError(c *gin.Context, statusCode int, message string) {
     c.JSON(statusCode, ErrorResponse{Error: http.StatusText(statusCode), Message: message})
 }
 
+// RespondWithErrorCode is RespondWithError with a machine-readable code.
+func RespondWithErrorCode(c *gin.Context, statusCode int, code, message string) {
+    c.JSON(statusCode, ErrorResponse{Error: http.StatusText(statusCode), Message: message, Code: code})
+}
+
 func RespondWithSuccess(c *gin.Context, statusCode int, data interface{}) {
     c.JSON(statusCode, data)
 }
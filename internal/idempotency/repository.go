@@ -0,0 +1,73 @@
+package idempotency
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+// Claim records that the user's request under key is being handled by the
+// holder of token, and reports whether it got the key. An expired key, or
+// one whose request has been in flight for longer than abandonAfter, is
+// taken over.
+func (r *Repository) Claim(userID int, key, fingerprint, token string, ttl, abandonAfter time.Duration) (bool, error) {
+	const q = `INSERT INTO idempotency_keys (user_id, key, fingerprint, owner, expires_at)
+               VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + $5 * INTERVAL '1 second')
+               ON CONFLICT (user_id, key) DO UPDATE
+               SET fingerprint = EXCLUDED.fingerprint, owner = EXCLUDED.owner, status = NULL, content_type = NULL,
+                   body = NULL, created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+               WHERE idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+                  OR (idempotency_keys.status IS NULL
+                      AND idempotency_keys.created_at <= CURRENT_TIMESTAMP - $6 * INTERVAL '1 second')
+               RETURNING 1`
+	var one int
+	err := r.db.QueryRow(q, userID, key, fingerprint, token, ttl.Seconds(), abandonAfter.Seconds()).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Get returns what is stored under the user's key.
+func (r *Repository) Get(userID int, key string) (Record, error) {
+	var rec Record
+	var status sql.NullInt64
+	var contentType sql.NullString
+	err := r.db.QueryRow(`SELECT fingerprint, status, content_type, body FROM idempotency_keys
+                          WHERE user_id = $1 AND key = $2 AND expires_at > CURRENT_TIMESTAMP`, userID, key).
+		Scan(&rec.Fingerprint, &status, &contentType, &rec.Body)
+	rec.Status = int(status.Int64)
+	rec.ContentType = contentType.String
+	return rec, err
+}
+
+// Complete stores the response to the request token holds the key for, and
+// reports whether it still did.
+func (r *Repository) Complete(userID int, key, token string, status int, contentType string, body []byte) (bool, error) {
+	res, err := r.db.Exec(`UPDATE idempotency_keys SET status = $4, content_type = $5, body = $6
+                           WHERE user_id = $1 AND key = $2 AND owner = $3 AND status IS NULL`,
+		userID, key, token, status, contentType, body)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Release frees a key token holds without storing a response.
+func (r *Repository) Release(userID int, key, token string) error {
+	_, err := r.db.Exec("DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND owner = $3 AND status IS NULL", userID, key, token)
+	return err
+}
+
+// DeleteExpired removes keys past their TTL and returns how many went.
+func (r *Repository) DeleteExpired() (int64, error) {
+	res, err := r.db.Exec("DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
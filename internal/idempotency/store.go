@@ -0,0 +1,147 @@
+// Package idempotency remembers the responses to POST requests sent with an
+// Idempotency-Key header so that a client retrying one gets the original
+// response instead of creating a duplicate.
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"majoo-case1-rest-api/config"
+)
+
+// sweepInterval is how often expired keys are deleted.
+const sweepInterval = time.Hour
+
+// Record is a stored request: its fingerprint and, once handled, the
+// response. Status is 0 while the request is in flight.
+type Record struct {
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+type Store struct {
+	repo *Repository
+	ttl  time.Duration
+	// abandonAfter is how long a request may hold its key before a retry
+	// may assume it died with its server and run it again.
+	abandonAfter time.Duration
+}
+
+func NewStore(repo *Repository, cfg config.Config) *Store {
+	ttl := cfg.IdempotencyTTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	abandonAfter := cfg.IdempotencyLockTimeout
+	if abandonAfter <= 0 {
+		abandonAfter = 10 * time.Minute
+	}
+	return &Store{repo: repo, ttl: ttl, abandonAfter: abandonAfter}
+}
+
+// Fingerprint identifies a request by its method, path and body, so a key is
+// only ever replayed for the request it was first used with.
+func Fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Claim is a key held by the request being handled under it. Each claim has
+// its own token, so a request whose key was taken over by a retry after the
+// lock timeout can no longer store or release it.
+type Claim struct {
+	repo   *Repository
+	userID int
+	key    string
+	token  string
+}
+
+// Begin claims the user's key for a request with fingerprint. When it gets
+// the key it returns a Claim, and the caller must handle the request, then
+// call Finish or Abandon; otherwise it returns the stored response of an
+// earlier identical request to replay. ErrKeyReused means the key came with
+// a different request and ErrInProgress that an identical one is still
+// being handled.
+func (s *Store) Begin(userID int, key, fingerprint string) (*Claim, *Record, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, nil, err
+	}
+	c := &Claim{repo: s.repo, userID: userID, key: key, token: hex.EncodeToString(b)}
+	claimed, err := s.repo.Claim(userID, key, fingerprint, c.token, s.ttl, s.abandonAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if claimed {
+		return c, nil, nil
+	}
+	rec, err := s.repo.Get(userID, key)
+	if err == sql.ErrNoRows {
+		// released or expired since the claim failed; a retry will get it
+		return nil, nil, ErrInProgress
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if rec.Fingerprint != fingerprint {
+		return nil, nil, ErrKeyReused
+	}
+	if rec.Status == 0 {
+		return nil, nil, ErrInProgress
+	}
+	return nil, &rec, nil
+}
+
+// Finish stores the response to the claimed request. It fails with
+// ErrClaimLost if a retry has taken the key over in the meantime.
+func (c *Claim) Finish(status int, contentType string, body []byte) error {
+	ok, err := c.repo.Complete(c.userID, c.key, c.token, status, contentType, body)
+	if err == nil && !ok {
+		return ErrClaimLost
+	}
+	return err
+}
+
+// Abandon frees the key without storing a response, so that a retry runs
+// the request again.
+func (c *Claim) Abandon() error {
+	return c.repo.Release(c.userID, c.key, c.token)
+}
+
+// Start deletes expired keys every sweepInterval until ctx is done.
+func (s *Store) Start(ctx context.Context) {
+	go func() {
+		t := time.NewTicker(sweepInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if _, err := s.repo.DeleteExpired(); err != nil {
+					log.Printf("idempotency: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+var (
+	ErrKeyReused  = errString("idempotency key was used with a different request")
+	ErrInProgress = errString("a request with this idempotency key is in progress")
+	ErrClaimLost  = errString("idempotency key was taken over by a retry")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -0,0 +1,98 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"majoo-case1-rest-api/config"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+var storedColumns = []string{"fingerprint", "status", "content_type", "body"}
+
+func TestStore_Begin_ClaimsNewKeyForOneOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := NewStore(NewRepository(db), config.Config{IdempotencyTTL: time.Hour, IdempotencyLockTimeout: 5 * time.Minute})
+	fp := Fingerprint("POST", "/api/v1/posts", []byte(`{"title":"Hi"}`))
+
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WithArgs(1, "k1", fp, sqlmock.AnyArg(), float64(3600), float64(300)).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+
+	claim, rec, err := s.Begin(1, "k1", fp)
+	if err != nil || claim == nil || rec != nil {
+		t.Fatalf("expected the key to be claimed, got %+v, %+v, %v", claim, rec, err)
+	}
+
+	// a retry took the key over after the lock timeout
+	mock.ExpectExec("UPDATE idempotency_keys SET status").
+		WithArgs(1, "k1", claim.token, 201, "application/json", []byte(`{}`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := claim.Finish(201, "application/json", []byte(`{}`)); err != ErrClaimLost {
+		t.Errorf("expected ErrClaimLost, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestStore_Begin_ReplaysStoredResponse(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := NewStore(NewRepository(db), config.Config{IdempotencyTTL: time.Hour})
+	fp := Fingerprint("POST", "/api/v1/posts", []byte(`{"title":"Hi"}`))
+
+	mock.ExpectQuery("INSERT INTO idempotency_keys").WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
+	mock.ExpectQuery("SELECT fingerprint, status, content_type, body FROM idempotency_keys").
+		WithArgs(1, "k1").
+		WillReturnRows(sqlmock.NewRows(storedColumns).AddRow(fp, 201, "application/json", []byte(`{"id":7}`)))
+
+	_, rec, err := s.Begin(1, "k1", fp)
+	if err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	if rec == nil || rec.Status != 201 || string(rec.Body) != `{"id":7}` {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestStore_Begin_RefusesOtherRequestsAndInFlightOnes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := NewStore(NewRepository(db), config.Config{IdempotencyTTL: time.Hour})
+	fp := Fingerprint("POST", "/api/v1/posts", []byte(`{"title":"Hi"}`))
+	other := Fingerprint("POST", "/api/v1/posts", []byte(`{"title":"Bye"}`))
+
+	mock.ExpectQuery("INSERT INTO idempotency_keys").WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
+	mock.ExpectQuery("SELECT fingerprint").WillReturnRows(sqlmock.NewRows(storedColumns).AddRow(fp, 201, "application/json", []byte(`{}`)))
+	if _, _, err := s.Begin(1, "k1", other); err != ErrKeyReused {
+		t.Errorf("expected ErrKeyReused, got %v", err)
+	}
+
+	// the first request has not finished yet
+	mock.ExpectQuery("INSERT INTO idempotency_keys").WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
+	mock.ExpectQuery("SELECT fingerprint").WillReturnRows(sqlmock.NewRows(storedColumns).AddRow(fp, nil, nil, nil))
+	if _, _, err := s.Begin(1, "k1", fp); err != ErrInProgress {
+		t.Errorf("expected ErrInProgress, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
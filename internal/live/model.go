@@ -0,0 +1,42 @@
+package live
+
+import (
+	"encoding/json"
+
+	"majoo-case1-rest-api/internal/comment"
+)
+
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+// Command is a message from the client. Comment fields are only read for
+// "comment" commands.
+type Command struct {
+	Type   string `json:"type"`
+	Ref    string `json:"ref,omitempty"`
+	PostID int    `json:"post_id"`
+	comment.CreateCommentRequest
+}
+
+// Frame is a message to the client: a reply to a command, carrying its Ref,
+// or an event in one of the joined rooms.
+type Frame struct {
+	Type   string      `json:"type"`
+	Ref    string      `json:"ref,omitempty"`
+	PostID int         `json:"post_id,omitempty"`
+	ID     int64       `json:"id,omitempty"`
+	Data   interface{} `json:"data,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// userEvent is the payload of presence and typing broadcasts.
+type userEvent struct {
+	User User `json:"user"`
+}
+
+func (e userEvent) marshal() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
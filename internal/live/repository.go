@@ -0,0 +1,62 @@
+package live
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+func (r *Repository) User(id int) (User, error) {
+	u := User{ID: id}
+	err := r.db.QueryRow("SELECT username FROM users WHERE id=$1", id).Scan(&u.Username)
+	return u, err
+}
+
+// Join records connID in the post's room, refreshing it if already there.
+func (r *Repository) Join(connID string, postID, userID int) error {
+	_, err := r.db.Exec(`INSERT INTO live_presence (conn_id, post_id, user_id) VALUES ($1,$2,$3)
+                         ON CONFLICT (conn_id, post_id) DO UPDATE SET seen_at = CURRENT_TIMESTAMP`, connID, postID, userID)
+	return err
+}
+
+// Touch marks every room of connID as still occupied.
+func (r *Repository) Touch(connID string) error {
+	_, err := r.db.Exec("UPDATE live_presence SET seen_at = CURRENT_TIMESTAMP WHERE conn_id=$1", connID)
+	return err
+}
+
+func (r *Repository) Leave(connID string, postID int) error {
+	_, err := r.db.Exec("DELETE FROM live_presence WHERE conn_id=$1 AND post_id=$2", connID, postID)
+	return err
+}
+
+func (r *Repository) LeaveAll(connID string) error {
+	_, err := r.db.Exec("DELETE FROM live_presence WHERE conn_id=$1", connID)
+	return err
+}
+
+// Present lists the users with a connection in the post's room seen since
+// since, and drops rows that went stale before it.
+func (r *Repository) Present(postID int, since time.Time) ([]User, error) {
+	if _, err := r.db.Exec("DELETE FROM live_presence WHERE seen_at < $1", since); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(`SELECT DISTINCT u.id, u.username FROM live_presence lp JOIN users u ON u.id = lp.user_id
+                             WHERE lp.post_id=$1 AND lp.seen_at >= $2 ORDER BY u.username`, postID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []User{}
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Username); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
@@ -0,0 +1,250 @@
+package live
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/comment"
+	"majoo-case1-rest-api/internal/filter"
+	"majoo-case1-rest-api/internal/stream"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// maxRooms caps how many posts one connection may join.
+	maxRooms = 20
+	// typingInterval throttles typing broadcasts per connection and room.
+	typingInterval = 3 * time.Second
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
+
+var (
+	ErrNotFound       = errString("not_found")
+	ErrNotJoined      = errString("not_joined")
+	ErrTooManyRooms   = errString("too_many_rooms")
+	ErrUnknownCommand = errString("unknown_command")
+)
+
+// Usecase runs live comment rooms on top of the stream hub: comment events
+// come from the stored stream, presence and typing are broadcast unstored.
+type Usecase struct {
+	repo     *Repository
+	hub      *stream.Hub
+	comments *comment.Usecase
+	// presenceTTL is how long a connection counts as present without a Touch.
+	presenceTTL time.Duration
+}
+
+func NewUsecase(repo *Repository, hub *stream.Hub, comments *comment.Usecase, cfg config.Config) *Usecase {
+	return &Usecase{repo: repo, hub: hub, comments: comments, presenceTTL: 3 * cfg.StreamHeartbeat}
+}
+
+// Session is one client connection. Handle is called for each command and
+// Frame for each message received on Events; they may run concurrently.
+type Session struct {
+	uc   *Usecase
+	id   string
+	user User
+	sub  *stream.Subscription
+
+	mu    sync.Mutex
+	rooms map[int]bool
+	typed map[int]time.Time
+}
+
+func (u *Usecase) Open(userID int) (*Session, error) {
+	user, err := u.repo.User(userID)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &Session{
+		uc:    u,
+		id:    hex.EncodeToString(b),
+		user:  user,
+		sub:   u.hub.Subscribe(nil),
+		rooms: map[int]bool{},
+		typed: map[int]time.Time{},
+	}, nil
+}
+
+// Events delivers the messages of the joined rooms. It is closed when the
+// session falls too far behind; the client should reconnect and rejoin.
+func (s *Session) Events() <-chan stream.Message { return s.sub.C }
+
+// Handle runs a command and returns the reply, if any. Failures are replied
+// to with an "error" frame.
+func (s *Session) Handle(cmd Command) (Frame, bool) {
+	var data interface{}
+	var err error
+	switch cmd.Type {
+	case "join":
+		data, err = s.join(cmd.PostID)
+	case "leave":
+		err = s.leave(cmd.PostID)
+	case "typing":
+		if err = s.typing(cmd.PostID); err == nil {
+			return Frame{}, false
+		}
+	case "comment":
+		if err = binding.Validator.ValidateStruct(&cmd.CreateCommentRequest); err == nil {
+			data, err = s.uc.comments.Create(cmd.PostID, s.user.ID, cmd.CreateCommentRequest)
+		}
+	default:
+		err = ErrUnknownCommand
+	}
+	f := Frame{Type: cmd.Type, Ref: cmd.Ref, PostID: cmd.PostID, Data: data}
+	if err != nil {
+		f.Type, f.Data, f.Error = "error", nil, errorCode(err)
+	}
+	return f, true
+}
+
+// errorCode returns the code sent to the client for err, hiding internal
+// errors. Comment errors keep the codes the comment package gives them.
+func errorCode(err error) string {
+	if _, ok := err.(validator.ValidationErrors); ok {
+		return "invalid_request"
+	}
+	if _, ok := err.(*filter.RejectedError); ok {
+		return "rejected"
+	}
+	switch err {
+	case ErrNotFound, ErrNotJoined, ErrTooManyRooms, ErrUnknownCommand,
+		comment.ErrNotFound, comment.ErrInvalidParent, comment.ErrForbidden,
+		comment.ErrCommentsDisabled, comment.ErrCommentsLocked, comment.ErrMembersOnly, comment.ErrBlocked:
+		return err.Error()
+	}
+	log.Printf("live: %v", err)
+	return "internal_error"
+}
+
+// join enters the post's room. Users the post's settings or author keep
+// from commenting are kept out as well, so they neither receive its comments
+// nor show up in its presence list.
+func (s *Session) join(postID int) ([]User, error) {
+	if err := s.uc.comments.CheckParticipant(s.user.ID, postID); err != nil {
+		if err == comment.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	s.mu.Lock()
+	if !s.rooms[postID] && len(s.rooms) >= maxRooms {
+		s.mu.Unlock()
+		return nil, ErrTooManyRooms
+	}
+	s.rooms[postID] = true
+	s.mu.Unlock()
+	s.sub.Add(stream.PostChannel(postID))
+	if err := s.uc.repo.Join(s.id, postID, s.user.ID); err != nil {
+		return nil, err
+	}
+	s.broadcast(postID, "presence.joined")
+	return s.uc.repo.Present(postID, time.Now().Add(-s.uc.presenceTTL))
+}
+
+func (s *Session) leave(postID int) error {
+	s.mu.Lock()
+	joined := s.rooms[postID]
+	delete(s.rooms, postID)
+	delete(s.typed, postID)
+	s.mu.Unlock()
+	if !joined {
+		return ErrNotJoined
+	}
+	s.sub.Remove(stream.PostChannel(postID))
+	if err := s.uc.repo.Leave(s.id, postID); err != nil {
+		return err
+	}
+	s.broadcast(postID, "presence.left")
+	return nil
+}
+
+func (s *Session) typing(postID int) error {
+	s.mu.Lock()
+	if !s.rooms[postID] {
+		s.mu.Unlock()
+		return ErrNotJoined
+	}
+	now := time.Now()
+	if now.Sub(s.typed[postID]) < typingInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.typed[postID] = now
+	s.mu.Unlock()
+	s.broadcast(postID, "typing")
+	return nil
+}
+
+// broadcast tells the room about the session's user. Presence and typing are
+// best effort, so failures are only logged.
+func (s *Session) broadcast(postID int, typ string) {
+	payload := userEvent{User: s.user}.marshal()
+	if err := s.uc.hub.Broadcast(stream.PostChannel(postID), typ, payload); err != nil {
+		log.Printf("live: broadcasting %s: %v", typ, err)
+	}
+}
+
+// Frame converts a room message for the client. It returns false for
+// messages the client should not see, such as its own typing indicator.
+func (s *Session) Frame(m stream.Message) (Frame, bool) {
+	postID, _ := strconv.Atoi(strings.TrimPrefix(m.Channel, "post:"))
+	switch m.Type {
+	case "typing":
+		var e userEvent
+		if json.Unmarshal(m.Data, &e) == nil && e.User.ID == s.user.ID {
+			return Frame{}, false
+		}
+	case "post.updated":
+		// The hub already follows the post to its new ID; move the room too.
+		var p struct {
+			ID int `json:"id"`
+		}
+		if json.Unmarshal(m.Data, &p) == nil && p.ID != 0 {
+			s.mu.Lock()
+			if s.rooms[postID] {
+				delete(s.rooms, postID)
+				s.rooms[p.ID] = true
+			}
+			s.mu.Unlock()
+			s.sub.Remove(m.Channel)
+		}
+	}
+	return Frame{Type: m.Type, PostID: postID, ID: m.ID, Data: m.Data}, true
+}
+
+// Touch keeps the session's presence fresh; call it more often than the
+// presence TTL.
+func (s *Session) Touch() error { return s.uc.repo.Touch(s.id) }
+
+// Close leaves every room.
+func (s *Session) Close() {
+	s.sub.Close()
+	s.mu.Lock()
+	rooms := s.rooms
+	s.rooms = map[int]bool{}
+	s.mu.Unlock()
+	if err := s.uc.repo.LeaveAll(s.id); err != nil {
+		log.Printf("live: leaving rooms: %v", err)
+	}
+	for postID := range rooms {
+		s.broadcast(postID, "presence.left")
+	}
+}
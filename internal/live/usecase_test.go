@@ -0,0 +1,131 @@
+package live
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/comment"
+	"majoo-case1-rest-api/internal/stream"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func openSession(t *testing.T) (*Session, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	cfg := config.Config{StreamHeartbeat: 15 * time.Second}
+	comments := comment.NewUsecase(db, comment.NewRepository(db))
+	uc := NewUsecase(NewRepository(db), stream.NewHub(stream.NewRepository(db), cfg), comments, cfg)
+
+	mock.ExpectQuery("SELECT username FROM users").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"username"}).AddRow("johndoe"))
+	s, err := uc.Open(1)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return s, mock
+}
+
+func TestSession_JoinListsPresence(t *testing.T) {
+	s, mock := openSession(t)
+
+	expectPostRules(mock, 10, false)
+	mock.ExpectQuery("FROM user_blocks").
+		WithArgs(1, 10, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec("INSERT INTO live_presence").
+		WithArgs(s.id, 10, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SELECT pg_notify").
+		WithArgs("stream_ephemeral", `{"channel":"post:10","type":"presence.joined","data":{"user":{"id":1,"username":"johndoe"}}}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM live_presence WHERE seen_at").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT DISTINCT u.id, u.username FROM live_presence").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(2, "janedoe").AddRow(1, "johndoe"))
+
+	f, ok := s.Handle(Command{Type: "join", Ref: "r1", PostID: 10})
+	if !ok || f.Type != "join" || f.Ref != "r1" {
+		t.Fatalf("unexpected reply %+v", f)
+	}
+	if users := f.Data.([]User); len(users) != 2 {
+		t.Fatalf("expected 2 present users, got %+v", users)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSession_JoinRefusesBlockedAndNonMembers(t *testing.T) {
+	s, mock := openSession(t)
+
+	expectPostRules(mock, 10, false)
+	mock.ExpectQuery("FROM user_blocks").
+		WithArgs(1, 10, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	if f, _ := s.Handle(Command{Type: "join", PostID: 10}); f.Error != "blocked" {
+		t.Errorf("blocked by the author: got %+v", f)
+	}
+
+	expectPostRules(mock, 11, true)
+	mock.ExpectQuery("FROM follows").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	if f, _ := s.Handle(Command{Type: "join", PostID: 11}); f.Error != "members_only" {
+		t.Errorf("members-only post: got %+v", f)
+	}
+	if len(s.rooms) != 0 {
+		t.Errorf("expected no rooms joined, got %v", s.rooms)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+// expectPostRules mocks the settings of post id, written by user 2.
+func expectPostRules(mock sqlmock.Sqlmock, id int, membersOnly bool) {
+	mock.ExpectQuery("SELECT COALESCE\\(comment_moderation, ''\\)").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"comment_moderation", "comments_enabled", "comments_locked", "comments_members_only", "comments_premoderated", "user_id"}).
+			AddRow("", true, false, membersOnly, false, 2))
+}
+
+func TestSession_RejectsUnjoinedAndInvalid(t *testing.T) {
+	s, _ := openSession(t)
+
+	if f, _ := s.Handle(Command{Type: "typing", PostID: 10}); f.Error != "not_joined" {
+		t.Errorf("typing outside a room: got %+v", f)
+	}
+	if f, _ := s.Handle(Command{Type: "comment", PostID: 10}); f.Error != "invalid_request" {
+		t.Errorf("empty comment: got %+v", f)
+	}
+	if f, _ := s.Handle(Command{Type: "shout"}); f.Error != "unknown_command" {
+		t.Errorf("unknown command: got %+v", f)
+	}
+}
+
+func TestSession_Frame(t *testing.T) {
+	s, _ := openSession(t)
+	s.rooms[10] = true
+
+	self, _ := json.Marshal(userEvent{User: User{ID: 1}})
+	if _, ok := s.Frame(stream.Message{Channel: "post:10", Type: "typing", Data: self}); ok {
+		t.Error("own typing indicator should be skipped")
+	}
+
+	data, _ := json.Marshal(map[string]int{"id": 12, "previous_id": 10})
+	f, ok := s.Frame(stream.Message{ID: 5, Channel: "post:10", Type: "post.updated", Data: data})
+	if !ok || f.PostID != 10 || f.ID != 5 {
+		t.Fatalf("unexpected frame %+v", f)
+	}
+	if s.rooms[10] || !s.rooms[12] {
+		t.Errorf("room should follow the post to its new ID, rooms = %v", s.rooms)
+	}
+}
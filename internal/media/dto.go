@@ -0,0 +1,6 @@
+package media
+
+type AttachRequest struct {
+	UploadID int `json:"upload_id" binding:"required,min=1"`
+	Position int `json:"position" binding:"min=0"`
+}
@@ -0,0 +1,148 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const jpegQuality = 85
+
+// processable lists the upload types we generate variants for.
+var processable = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// decodeImage decodes b with its EXIF orientation applied, refusing images
+// above maxPixels before allocating for them.
+func decodeImage(b []byte, contentType string, maxPixels int64) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	switch contentType {
+	case "image/jpeg":
+		img = orient(img, jpegOrientation(b))
+	case "image/webp":
+		img = orient(img, webpOrientation(b))
+	}
+	return img, nil
+}
+
+// orient transforms img so that it displays upright for the given EXIF orientation.
+func orient(img image.Image, o int) image.Image {
+	if o <= 1 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	src := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		for dx := 0; dx < dw; dx++ {
+			var sx, sy int
+			switch o {
+			case 2:
+				sx, sy = w-1-dx, dy
+			case 3:
+				sx, sy = w-1-dx, h-1-dy
+			case 4:
+				sx, sy = dx, h-1-dy
+			case 5:
+				sx, sy = dy, dx
+			case 6:
+				sx, sy = dy, h-1-dx
+			case 7:
+				sx, sy = w-1-dy, h-1-dx
+			case 8:
+				sx, sy = w-1-dy, dx
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// fit scales img down to fit within width x height, keeping its aspect
+// ratio. Images that already fit are returned unchanged.
+func fit(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= width && h <= height {
+		return img
+	}
+	nw, nh := width, h*width/w
+	if nh > height {
+		nw, nh = w*height/h, height
+	}
+	if nw < 1 {
+		nw = 1
+	}
+	if nh < 1 {
+		nh = 1
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, nw, nh))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// encodeVariant re-encodes img, which also leaves all source metadata behind.
+// Formats that may carry transparency become PNG, everything else JPEG.
+func encodeVariant(img image.Image, sourceType string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch sourceType {
+	case "image/png", "image/gif":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// sanitizeOriginal removes metadata from the stored original. JPEGs that
+// rely on EXIF orientation are re-encoded upright, since dropping the tag
+// would otherwise leave them sideways; WebPs keep the orientation alone.
+func sanitizeOriginal(b []byte, contentType string, img image.Image) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		if jpegOrientation(b) > 1 {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 92}); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		}
+		return stripJPEGMetadata(b), nil
+	case "image/png":
+		return stripPNGMetadata(b), nil
+	case "image/webp":
+		return stripWebPMetadata(b, webpOrientation(b)), nil
+	case "image/gif":
+		return stripGIFMetadata(b), nil
+	}
+	return b, nil
+}
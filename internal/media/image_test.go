@@ -0,0 +1,207 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// withOrientation inserts a minimal EXIF APP1 segment into a JPEG.
+func withOrientation(t *testing.T, jpg []byte, o uint16) []byte {
+	t.Helper()
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	ifd := make([]byte, 2+12+4)
+	binary.BigEndian.PutUint16(ifd[0:], 1)
+	binary.BigEndian.PutUint16(ifd[2:], exifTagOrientation)
+	binary.BigEndian.PutUint16(ifd[4:], 3) // SHORT
+	binary.BigEndian.PutUint32(ifd[6:], 1)
+	binary.BigEndian.PutUint16(ifd[10:], o)
+	payload := append([]byte("Exif\x00\x00"), append(tiff, ifd...)...)
+	seg := []byte{0xFF, jpegAPP1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	seg = append(seg, payload...)
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, seg...)
+	return append(out, jpg[2:]...)
+}
+
+func testJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	plain := testJPEG(t, 4, 2)
+	if o := jpegOrientation(plain); o != 1 {
+		t.Errorf("expected 1 without EXIF, got %d", o)
+	}
+	if o := jpegOrientation(withOrientation(t, plain, 6)); o != 6 {
+		t.Errorf("expected 6, got %d", o)
+	}
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	tagged := withOrientation(t, testJPEG(t, 4, 2), 6)
+	clean := stripJPEGMetadata(tagged)
+	if bytes.Contains(clean, []byte("Exif\x00\x00")) {
+		t.Error("EXIF segment survived stripping")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(clean)); err != nil {
+		t.Errorf("stripped JPEG no longer decodes: %v", err)
+	}
+}
+
+func TestDecodeImage_AppliesOrientation(t *testing.T) {
+	img, err := decodeImage(withOrientation(t, testJPEG(t, 40, 20), 6), "image/jpeg", 1<<20)
+	if err != nil {
+		t.Fatalf("decodeImage: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Errorf("expected 20x40 after rotation, got %dx%d", b.Dx(), b.Dy())
+	}
+	if _, err := decodeImage(testJPEG(t, 40, 20), "image/jpeg", 100); err != ErrImageTooLarge {
+		t.Errorf("expected ErrImageTooLarge, got %v", err)
+	}
+}
+
+// lossless4x1 is a 4x1 WebP in the simple format, which cannot carry metadata.
+var lossless4x1 = []byte("VP8L\x0d\x00\x00\x00\x2f\x03\x00\x00\x10\x07\x10\x11\x11\x88\x88\xfe\x07\x00")
+
+// testWebP wraps lossless4x1 in the extended format with an EXIF chunk
+// holding orientation o and an XMP chunk.
+func testWebP(t *testing.T, o int) []byte {
+	t.Helper()
+	chunk := func(fourCC string, data []byte) []byte {
+		c := append([]byte(fourCC), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(c[4:], uint32(len(data)))
+		c = append(c, data...)
+		if len(data)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+	vp8x := []byte{webpFlagEXIF | webpFlagXMP, 0, 0, 0, 3, 0, 0, 0, 0, 0}
+	exif := append(orientationEXIF(o), []byte("GPS 52.52N 13.40E")...)
+	b := []byte("RIFF\x00\x00\x00\x00WEBP")
+	b = append(b, chunk("VP8X", vp8x)...)
+	b = append(b, lossless4x1...)
+	b = append(b, chunk("EXIF", exif)...)
+	b = append(b, chunk("XMP ", []byte("<x:xmpmeta>secret</x:xmpmeta>"))...)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)-8))
+	return b
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	tagged := testWebP(t, 6)
+	if o := webpOrientation(tagged); o != 6 {
+		t.Fatalf("expected orientation 6, got %d", o)
+	}
+	clean, err := sanitizeOriginal(tagged, "image/webp", nil)
+	if err != nil {
+		t.Fatalf("sanitizeOriginal: %v", err)
+	}
+	if bytes.Contains(clean, []byte("GPS")) || bytes.Contains(clean, []byte("xmpmeta")) {
+		t.Error("metadata survived stripping")
+	}
+	if o := webpOrientation(clean); o != 6 {
+		t.Errorf("expected the orientation to be kept, got %d", o)
+	}
+	if _, err := webp.Decode(bytes.NewReader(clean)); err != nil {
+		t.Errorf("stripped WebP no longer decodes: %v", err)
+	}
+
+	upright, _ := sanitizeOriginal(testWebP(t, 1), "image/webp", nil)
+	for _, c := range webpChunks(upright) {
+		if c.fourCC == "EXIF" || c.fourCC == "XMP " {
+			t.Errorf("expected no %s chunk, got one", c.fourCC)
+		}
+		if c.fourCC == "VP8X" && c.data[0]&(webpFlagEXIF|webpFlagXMP) != 0 {
+			t.Errorf("expected the metadata flags to be cleared, got %#x", c.data[0])
+		}
+	}
+}
+
+func TestDecodeImage_AppliesWebPOrientation(t *testing.T) {
+	img, err := decodeImage(testWebP(t, 6), "image/webp", 1<<20)
+	if err != nil {
+		t.Fatalf("decodeImage: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 1 || b.Dy() != 4 {
+		t.Errorf("expected 1x4 after rotation, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestStripGIFMetadata(t *testing.T) {
+	frame := image.NewPaletted(image.Rect(0, 0, 4, 2), palette.Plan9)
+	var buf bytes.Buffer
+	// a looping animation gets a NETSCAPE2.0 block, which must survive
+	if err := gif.EncodeAll(&buf, &gif.GIF{Image: []*image.Paletted{frame, frame}, Delay: []int{10, 10}}); err != nil {
+		t.Fatalf("gif.EncodeAll: %v", err)
+	}
+	b := buf.Bytes()
+	at := bytes.Index(b, []byte{gifExtension, gifApp})
+	meta := []byte{gifExtension, gifComment, 6}
+	meta = append(meta, "secret"...)
+	meta = append(meta, 0, gifExtension, gifApp, 11)
+	meta = append(meta, "XMP DataXMP"...)
+	meta = append(meta, 7)
+	meta = append(meta, "GPS 52N"...)
+	meta = append(meta, 0)
+	tagged := append(append(append([]byte{}, b[:at]...), meta...), b[at:]...)
+
+	clean, err := sanitizeOriginal(tagged, "image/gif", nil)
+	if err != nil {
+		t.Fatalf("sanitizeOriginal: %v", err)
+	}
+	if !bytes.Equal(clean, b) {
+		t.Errorf("expected only the added extensions to be dropped, got %d bytes for %d", len(clean), len(b))
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(clean))
+	if err != nil {
+		t.Fatalf("stripped GIF no longer decodes: %v", err)
+	}
+	if len(g.Image) != 2 {
+		t.Errorf("expected 2 frames, got %d", len(g.Image))
+	}
+}
+
+func TestOrient_Rotate90(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	red := color.NRGBA{R: 255, A: 255}
+	src.Set(0, 0, red) // top-left
+	out := orient(src, 6)
+	if b := out.Bounds(); b.Dx() != 2 || b.Dy() != 3 {
+		t.Fatalf("expected 2x3, got %dx%d", b.Dx(), b.Dy())
+	}
+	// rotating clockwise moves the top-left pixel to the top-right
+	if got := out.At(1, 0); got != color.Color(red) {
+		t.Errorf("expected red at (1,0), got %v", got)
+	}
+	if got := out.At(0, 0); got == color.Color(red) {
+		t.Errorf("expected (0,0) not to be red")
+	}
+}
+
+func TestFit(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1000, 500))
+	out := fit(img, 200, 200)
+	if b := out.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+		t.Errorf("expected 200x100, got %dx%d", b.Dx(), b.Dy())
+	}
+	small := image.NewNRGBA(image.Rect(0, 0, 50, 50))
+	if fit(small, 200, 200) != image.Image(small) {
+		t.Error("expected images that already fit to be returned unchanged")
+	}
+}
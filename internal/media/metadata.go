@@ -0,0 +1,319 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	jpegSOI            = 0xD8
+	jpegSOS            = 0xDA
+	jpegAPP1           = 0xE1
+	jpegAPPD           = 0xED // Photoshop/IPTC
+	exifTagOrientation = 0x0112
+)
+
+const (
+	webpFlagEXIF = 0x08 // VP8X flags announcing the metadata chunks
+	webpFlagXMP  = 0x04
+	gifExtension = 0x21
+	gifImage     = 0x2C
+	gifTrailer   = 0x3B
+	gifComment   = 0xFE
+	gifApp       = 0xFF
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	// PNG chunks carrying free-form or EXIF metadata
+	pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+	// GIF application extensions that control animation rather than
+	// describe the file; every other one, XMP included, is dropped
+	gifAnimationApps = map[string]bool{"NETSCAPE2.0": true, "ANIMEXTS1.0": true}
+)
+
+// jpegOrientation returns the EXIF orientation (1-8) of a JPEG, or 1 when
+// the file has none or it cannot be read.
+func jpegOrientation(b []byte) int {
+	for _, seg := range jpegSegments(b) {
+		if seg.marker != jpegAPP1 || !bytes.HasPrefix(seg.data, []byte("Exif\x00\x00")) {
+			continue
+		}
+		if o := tiffOrientation(seg.data[6:]); o >= 1 && o <= 8 {
+			return o
+		}
+	}
+	return 1
+}
+
+// webpOrientation is jpegOrientation for a WebP's EXIF chunk.
+func webpOrientation(b []byte) int {
+	for _, c := range webpChunks(b) {
+		if c.fourCC != "EXIF" {
+			continue
+		}
+		// some writers keep the JPEG APP1 prefix
+		if o := tiffOrientation(bytes.TrimPrefix(c.data, []byte("Exif\x00\x00"))); o >= 1 && o <= 8 {
+			return o
+		}
+	}
+	return 1
+}
+
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(t[4:8]))
+	if ifd+2 > len(t) {
+		return 0
+	}
+	n := int(order.Uint16(t[ifd:]))
+	for i := 0; i < n; i++ {
+		e := ifd + 2 + i*12
+		if e+12 > len(t) {
+			return 0
+		}
+		if order.Uint16(t[e:]) == exifTagOrientation {
+			return int(order.Uint16(t[e+8:]))
+		}
+	}
+	return 0
+}
+
+type jpegSegment struct {
+	marker byte
+	start  int // offset of the 0xFF marker byte
+	end    int // offset just past the segment
+	data   []byte
+}
+
+// jpegSegments lists the marker segments before the image data starts.
+func jpegSegments(b []byte) []jpegSegment {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != jpegSOI {
+		return nil
+	}
+	var out []jpegSegment
+	for i := 2; i+4 <= len(b) && b[i] == 0xFF; {
+		marker := b[i+1]
+		if marker == jpegSOS {
+			break
+		}
+		n := int(binary.BigEndian.Uint16(b[i+2:]))
+		if n < 2 || i+2+n > len(b) {
+			break
+		}
+		out = append(out, jpegSegment{marker: marker, start: i, end: i + 2 + n, data: b[i+4 : i+2+n]})
+		i += 2 + n
+	}
+	return out
+}
+
+// stripJPEGMetadata drops EXIF/XMP (APP1) and IPTC (APP13) segments without
+// re-encoding the image.
+func stripJPEGMetadata(b []byte) []byte {
+	segs := jpegSegments(b)
+	if len(segs) == 0 {
+		return b
+	}
+	out := make([]byte, 0, len(b))
+	out = append(out, b[:2]...)
+	last := 2
+	for _, seg := range segs {
+		if seg.marker == jpegAPP1 || seg.marker == jpegAPPD {
+			out = append(out, b[last:seg.start]...)
+			last = seg.end
+		}
+	}
+	return append(out, b[last:]...)
+}
+
+// stripPNGMetadata drops textual and EXIF chunks from a PNG.
+func stripPNGMetadata(b []byte) []byte {
+	if !bytes.HasPrefix(b, pngSignature) {
+		return b
+	}
+	out := make([]byte, 0, len(b))
+	out = append(out, pngSignature...)
+	for i := len(pngSignature); i+12 <= len(b); {
+		n := int(binary.BigEndian.Uint32(b[i:]))
+		end := i + 12 + n
+		if n < 0 || end > len(b) {
+			return b
+		}
+		if !pngMetadataChunks[string(b[i+4:i+8])] {
+			out = append(out, b[i:end]...)
+		}
+		i = end
+	}
+	return out
+}
+
+type webpChunk struct {
+	fourCC string
+	start  int // offset of the FourCC
+	end    int // offset just past the chunk and its padding
+	data   []byte
+}
+
+// webpChunks lists the chunks of a RIFF WebP file, or nil when b is not one
+// or a chunk runs past its end.
+func webpChunks(b []byte) []webpChunk {
+	if len(b) < 12 || string(b[:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return nil
+	}
+	var out []webpChunk
+	for i := 12; i < len(b); {
+		if i+8 > len(b) {
+			return nil
+		}
+		n := int(binary.LittleEndian.Uint32(b[i+4:]))
+		if n < 0 || i+8+n > len(b) {
+			return nil
+		}
+		end := i + 8 + n + n&1
+		if end > len(b) {
+			end = len(b) // tolerate a missing final pad byte
+		}
+		out = append(out, webpChunk{fourCC: string(b[i : i+4]), start: i, end: end, data: b[i+8 : i+8+n]})
+		i = end
+	}
+	return out
+}
+
+// stripWebPMetadata drops the EXIF and XMP chunks from a WebP without
+// re-encoding it. WebP cannot be re-encoded here, so an orientation other
+// than 1 is written back as an EXIF chunk holding nothing else, which keeps
+// the image upright without keeping the rest of the metadata.
+func stripWebPMetadata(b []byte, orientation int) []byte {
+	chunks := webpChunks(b)
+	if len(chunks) == 0 {
+		return b
+	}
+	out := make([]byte, 0, len(b))
+	out = append(out, b[:12]...)
+	extended := false
+	for _, c := range chunks {
+		switch c.fourCC {
+		case "EXIF", "XMP ":
+			continue
+		case "VP8X":
+			if len(c.data) < 1 {
+				return b
+			}
+			extended = true
+			flags := len(out) + 8
+			out = append(out, b[c.start:c.end]...)
+			out[flags] &^= webpFlagEXIF | webpFlagXMP
+			if orientation > 1 {
+				out[flags] |= webpFlagEXIF
+			}
+			continue
+		}
+		out = append(out, b[c.start:c.end]...)
+	}
+	if orientation > 1 && extended {
+		// the spec puts EXIF after the image data
+		exif := orientationEXIF(orientation)
+		out = append(out, "EXIF"...)
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(exif)))
+		out = append(out, exif...)
+		if len(exif)%2 == 1 {
+			out = append(out, 0)
+		}
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+// orientationEXIF is a TIFF structure whose only entry is the orientation.
+func orientationEXIF(o int) []byte {
+	t := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	t = binary.BigEndian.AppendUint16(t, 1)
+	t = binary.BigEndian.AppendUint16(t, exifTagOrientation)
+	t = binary.BigEndian.AppendUint16(t, 3) // SHORT
+	t = binary.BigEndian.AppendUint32(t, 1)
+	t = binary.BigEndian.AppendUint16(t, uint16(o))
+	t = append(t, 0, 0)          // value padding
+	return append(t, 0, 0, 0, 0) // no next IFD
+}
+
+// stripGIFMetadata drops comment extensions and the application extensions,
+// such as XMP, that do not control animation, leaving the frames as they
+// are. A GIF it cannot walk is returned unchanged.
+func stripGIFMetadata(b []byte) []byte {
+	if len(b) < 13 || (string(b[:6]) != "GIF87a" && string(b[:6]) != "GIF89a") {
+		return b
+	}
+	i := 13
+	if b[10]&0x80 != 0 {
+		i += 3 << (b[10]&7 + 1)
+	}
+	if i > len(b) {
+		return b
+	}
+	out := make([]byte, 0, len(b))
+	out = append(out, b[:i]...)
+	for i < len(b) {
+		start := i
+		switch b[i] {
+		case gifTrailer:
+			return append(out, b[i:]...)
+		case gifExtension:
+			if i+2 > len(b) {
+				return b
+			}
+			label := b[i+1]
+			end, ok := gifSubBlocks(b, i+2)
+			if !ok {
+				return b
+			}
+			i = end
+			if label == gifComment {
+				continue
+			}
+			// an application block starts with an 11-byte identifier
+			if label == gifApp && (start+3+11 > len(b) || b[start+2] != 11 || !gifAnimationApps[string(b[start+3:start+14])]) {
+				continue
+			}
+		case gifImage:
+			i += 10
+			if i > len(b) {
+				return b
+			}
+			if b[i-1]&0x80 != 0 {
+				i += 3 << (b[i-1]&7 + 1)
+			}
+			end, ok := gifSubBlocks(b, i+1) // past the LZW code size
+			if !ok {
+				return b
+			}
+			i = end
+		default:
+			return b
+		}
+		out = append(out, b[start:i]...)
+	}
+	return out
+}
+
+// gifSubBlocks returns the offset just past the data sub-blocks starting at i.
+func gifSubBlocks(b []byte, i int) (int, bool) {
+	for i < len(b) {
+		n := int(b[i])
+		i += 1 + n
+		if n == 0 {
+			return i, i <= len(b)
+		}
+	}
+	return 0, false
+}
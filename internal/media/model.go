@@ -0,0 +1,36 @@
+package media
+
+import "time"
+
+type Upload struct {
+	ID           int    `json:"id"`
+	UserID       int    `json:"user_id"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	OriginalName string `json:"original_name"`
+	// Status is pending or processing while image variants are generated,
+	// then ready or failed.
+	Status       string             `json:"status"`
+	URL          string             `json:"url"`
+	URLExpiresAt time.Time          `json:"url_expires_at"`
+	Variants     map[string]Variant `json:"variants,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	StorageKey   string             `json:"-"`
+}
+
+// Variant is a resized copy of an uploaded image.
+type Variant struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int64  `json:"size"`
+	StorageKey  string `json:"-"`
+}
+
+// Attachment is an upload linked to a post; the upload's fields are inlined.
+type Attachment struct {
+	PostID   int `json:"post_id"`
+	Position int `json:"position"`
+	Upload
+}
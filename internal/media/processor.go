@@ -0,0 +1,186 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/storage"
+)
+
+// sweepInterval is how often the processor looks for uploads that never made
+// it into the queue (full queue, restart) or whose worker died.
+const sweepInterval = time.Minute
+
+// Processor generates image variants in the background with a fixed pool of
+// workers, so uploads return as soon as the original is stored.
+type Processor struct {
+	repo      *Repository
+	store     storage.Storage
+	variants  []config.ImageVariant
+	maxBytes  int64
+	maxPixels int64
+	workers   int
+	jobs      chan int
+	wg        sync.WaitGroup
+}
+
+func NewProcessor(repo *Repository, store storage.Storage, cfg config.Config) *Processor {
+	workers := cfg.ImageWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	size := cfg.ImageQueueSize
+	if size < 1 {
+		size = 1
+	}
+	return &Processor{
+		repo:      repo,
+		store:     store,
+		variants:  cfg.ImageVariants,
+		maxBytes:  cfg.UploadMaxBytes,
+		maxPixels: cfg.ImageMaxPixels,
+		workers:   workers,
+		jobs:      make(chan int, size),
+	}
+}
+
+// Start runs the workers and the sweeper until ctx is cancelled.
+func (p *Processor) Start(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-p.jobs:
+					if err := p.process(ctx, id); err != nil {
+						log.Printf("media: processing upload %d: %v", id, err)
+					}
+				}
+			}
+		}()
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		t := time.NewTicker(sweepInterval)
+		defer t.Stop()
+		for {
+			p.sweep()
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until the workers have exited after the Start context ends.
+func (p *Processor) Wait() { p.wg.Wait() }
+
+// Enqueue schedules an upload without blocking. When the queue is full the
+// upload stays pending and the sweeper picks it up later.
+func (p *Processor) Enqueue(id int) bool {
+	select {
+	case p.jobs <- id:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Processor) sweep() {
+	ids, err := p.repo.ProcessableUploadIDs(cap(p.jobs))
+	if err != nil {
+		log.Printf("media: listing pending uploads: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if !p.Enqueue(id) {
+			return
+		}
+	}
+}
+
+func (p *Processor) process(ctx context.Context, id int) error {
+	key, contentType, err := p.repo.ClaimForProcessing(id)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := p.generate(ctx, id, key, contentType); err != nil {
+		if ferr := p.repo.FinishProcessing(id, "failed", err.Error()); ferr != nil {
+			log.Printf("media: marking upload %d failed: %v", id, ferr)
+		}
+		return err
+	}
+	return p.repo.FinishProcessing(id, "ready", "")
+}
+
+func (p *Processor) generate(ctx context.Context, id int, key, contentType string) error {
+	rc, err := p.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	b, err := io.ReadAll(io.LimitReader(rc, p.maxBytes+1))
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	img, err := decodeImage(b, contentType, p.maxPixels)
+	if err != nil {
+		return err
+	}
+	clean, err := sanitizeOriginal(b, contentType, img)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(clean, b) {
+		if err := p.store.Put(ctx, key, bytes.NewReader(clean), int64(len(clean)), contentType); err != nil {
+			return err
+		}
+		if err := p.repo.SetUploadSize(id, int64(len(clean))); err != nil {
+			return err
+		}
+	}
+	for _, spec := range p.variants {
+		out := fit(img, spec.Width, spec.Height)
+		data, vt, err := encodeVariant(out, contentType)
+		if err != nil {
+			return err
+		}
+		v := Variant{
+			StorageKey:  variantKey(key, spec.Name, vt),
+			ContentType: vt,
+			Width:       out.Bounds().Dx(),
+			Height:      out.Bounds().Dy(),
+			Size:        int64(len(data)),
+		}
+		if err := p.store.Put(ctx, v.StorageKey, bytes.NewReader(data), v.Size, vt); err != nil {
+			return err
+		}
+		if err := p.repo.UpsertVariant(id, spec.Name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// variantKey derives "2024/01/abc_thumbnail.jpg" from "2024/01/abc.png".
+func variantKey(key, name, contentType string) string {
+	ext := extensions[contentType]
+	return strings.TrimSuffix(key, path.Ext(key)) + "_" + name + ext
+}
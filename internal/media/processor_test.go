@@ -0,0 +1,89 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+
+	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/storage"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestProcessor_GeneratesVariants(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	store, _ := storage.NewLocal(t.TempDir(), "http://example.test/files", []byte("secret"))
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 1000, 500)))
+	if err := store.Put(context.Background(), "2024/01/abc.png", bytes.NewReader(buf.Bytes()), int64(buf.Len()), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	cfg := config.Config{
+		UploadMaxBytes: 10 << 20,
+		ImageMaxPixels: 10_000_000,
+		ImageVariants:  []config.ImageVariant{{Name: "thumbnail", Width: 200, Height: 200}},
+	}
+	p := NewProcessor(NewRepository(db), store, cfg)
+
+	mock.ExpectQuery("UPDATE uploads SET status = 'processing'").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"storage_key", "content_type"}).AddRow("2024/01/abc.png", "image/png"))
+	mock.ExpectExec("INSERT INTO upload_variants").
+		WithArgs(3, "thumbnail", "2024/01/abc_thumbnail.png", "image/png", 200, 100, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE uploads SET status").
+		WithArgs(3, "ready", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := p.process(context.Background(), 3); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	rc, err := store.Get(context.Background(), "2024/01/abc_thumbnail.png")
+	if err != nil {
+		t.Fatalf("variant not stored: %v", err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if cfg, err := png.DecodeConfig(bytes.NewReader(b)); err != nil || cfg.Width != 200 {
+		t.Errorf("unexpected variant: %+v, %v", cfg, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestProcessor_MarksUndecodableFailed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	store, _ := storage.NewLocal(t.TempDir(), "http://example.test/files", []byte("secret"))
+	store.Put(context.Background(), "bad.png", bytes.NewReader([]byte("\x89PNG\r\n\x1a\ngarbage")), 16, "image/png")
+
+	p := NewProcessor(NewRepository(db), store, config.Config{UploadMaxBytes: 1 << 20, ImageMaxPixels: 1 << 20})
+
+	mock.ExpectQuery("UPDATE uploads SET status = 'processing'").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"storage_key", "content_type"}).AddRow("bad.png", "image/png"))
+	mock.ExpectExec("UPDATE uploads SET status").
+		WithArgs(4, "failed", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := p.process(context.Background(), 4); err == nil {
+		t.Error("expected error, got nil")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
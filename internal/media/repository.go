@@ -0,0 +1,130 @@
+package media
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// staleAfter is how long an upload may sit in processing before another
+// worker assumes its processor died and takes it over.
+const staleAfter = "10 minutes"
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+func (r *Repository) CreateUpload(userID int, key, contentType string, size int64, name, status string) (int, error) {
+	var id int
+	err := r.db.QueryRow(`INSERT INTO uploads (user_id, storage_key, content_type, size_bytes, original_name, status)
+                          VALUES ($1,$2,$3,$4,$5,$6) RETURNING id`, userID, key, contentType, size, name, status).Scan(&id)
+	return id, err
+}
+
+func (r *Repository) GetUpload(id int) (*sql.Row, error) {
+	const q = `SELECT id, user_id, storage_key, content_type, size_bytes, original_name, status, created_at
+               FROM uploads WHERE id=$1`
+	return r.db.QueryRow(q, id), nil
+}
+
+func (r *Repository) PostOwnerID(postID int) (int, error) {
+	var uid int
+	err := r.db.QueryRow("SELECT user_id FROM posts WHERE id=$1 AND deleted_at IS NULL", postID).Scan(&uid)
+	return uid, err
+}
+
+func (r *Repository) Attach(postID, uploadID, position int) error {
+	_, err := r.db.Exec(`INSERT INTO attachments (post_id, upload_id, position) VALUES ($1,$2,$3)
+                         ON CONFLICT (post_id, upload_id) DO UPDATE SET position = EXCLUDED.position`, postID, uploadID, position)
+	return err
+}
+
+func (r *Repository) Detach(postID, uploadID int) (int64, error) {
+	res, err := r.db.Exec("DELETE FROM attachments WHERE post_id=$1 AND upload_id=$2", postID, uploadID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// readablePost joins the post of attachment a while viewerID ($2) may read
+// it: it is not deleted, and not hidden unless viewerID wrote it.
+const readablePost = "JOIN posts p ON p.id = a.post_id AND p.deleted_at IS NULL AND (p.hidden_at IS NULL OR p.user_id = $2)"
+
+// PostReadable reports whether viewerID may read postID, and so list its
+// attachments.
+func (r *Repository) PostReadable(postID, viewerID int) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1 AND deleted_at IS NULL
+                          AND (hidden_at IS NULL OR user_id = $2))`, postID, viewerID).Scan(&ok)
+	return ok, err
+}
+
+func (r *Repository) ListByPost(postID, viewerID int) (*sql.Rows, error) {
+	const q = `SELECT a.post_id, a.position, u.id, u.user_id, u.storage_key, u.content_type, u.size_bytes, u.original_name, u.status, u.created_at
+               FROM attachments a JOIN uploads u ON u.id = a.upload_id ` + readablePost + `
+               WHERE a.post_id = $1 AND a.deleted_at IS NULL
+               ORDER BY a.position ASC, a.created_at ASC`
+	return r.db.Query(q, postID, viewerID)
+}
+
+func (r *Repository) VariantsByUploadIDs(ids []int) (*sql.Rows, error) {
+	const q = `SELECT upload_id, name, storage_key, content_type, width, height, size_bytes
+               FROM upload_variants WHERE upload_id = ANY($1)`
+	return r.db.Query(q, pq.Array(ids))
+}
+
+// ClaimForProcessing marks a pending (or stale processing) upload as being
+// processed and returns its storage key and type. sql.ErrNoRows means some
+// other worker has it or it is already done.
+func (r *Repository) ClaimForProcessing(id int) (string, string, error) {
+	const q = `UPDATE uploads SET status = 'processing', processing_started_at = CURRENT_TIMESTAMP
+               WHERE id = $1 AND (status = 'pending'
+                   OR (status = 'processing' AND processing_started_at < CURRENT_TIMESTAMP - INTERVAL '` + staleAfter + `'))
+               RETURNING storage_key, content_type`
+	var key, contentType string
+	err := r.db.QueryRow(q, id).Scan(&key, &contentType)
+	return key, contentType, err
+}
+
+// ProcessableUploadIDs lists uploads waiting for a worker, oldest first.
+func (r *Repository) ProcessableUploadIDs(limit int) ([]int, error) {
+	const q = `SELECT id FROM uploads
+               WHERE status = 'pending'
+                  OR (status = 'processing' AND processing_started_at < CURRENT_TIMESTAMP - INTERVAL '` + staleAfter + `')
+               ORDER BY id LIMIT $1`
+	rows, err := r.db.Query(q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *Repository) SetUploadSize(id int, size int64) error {
+	_, err := r.db.Exec("UPDATE uploads SET size_bytes=$2 WHERE id=$1", id, size)
+	return err
+}
+
+func (r *Repository) UpsertVariant(uploadID int, name string, v Variant) error {
+	_, err := r.db.Exec(`INSERT INTO upload_variants (upload_id, name, storage_key, content_type, width, height, size_bytes)
+                         VALUES ($1,$2,$3,$4,$5,$6,$7)
+                         ON CONFLICT (upload_id, name) DO UPDATE SET storage_key = EXCLUDED.storage_key,
+                             content_type = EXCLUDED.content_type, width = EXCLUDED.width,
+                             height = EXCLUDED.height, size_bytes = EXCLUDED.size_bytes`,
+		uploadID, name, v.StorageKey, v.ContentType, v.Width, v.Height, v.Size)
+	return err
+}
+
+func (r *Repository) FinishProcessing(id int, status, errMsg string) error {
+	_, err := r.db.Exec("UPDATE uploads SET status=$2, processing_error=$3 WHERE id=$1", id, status, errMsg)
+	return err
+}
@@ -0,0 +1,258 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/storage"
+)
+
+// sniffLen is how much of an upload http.DetectContentType looks at.
+const sniffLen = 512
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type Usecase struct {
+	repo     *Repository
+	store    storage.Storage
+	proc     *Processor
+	maxBytes int64
+	allowed  map[string]bool
+	urlTTL   time.Duration
+	now      func() time.Time
+}
+
+// NewUsecase wires uploads to store. proc may be nil, in which case images
+// are stored without variants.
+func NewUsecase(repo *Repository, store storage.Storage, proc *Processor, cfg config.Config) *Usecase {
+	allowed := make(map[string]bool, len(cfg.UploadAllowedTypes))
+	for _, t := range cfg.UploadAllowedTypes {
+		allowed[t] = true
+	}
+	return &Usecase{repo: repo, store: store, proc: proc, maxBytes: cfg.UploadMaxBytes, allowed: allowed, urlTTL: cfg.DownloadURLTTL, now: time.Now}
+}
+
+// MaxBytes is the largest file Upload accepts.
+func (u *Usecase) MaxBytes() int64 { return u.maxBytes }
+
+// Upload stores r after checking its size and sniffing its real content
+// type; the client-supplied name and Content-Type are not trusted.
+func (u *Usecase) Upload(ctx context.Context, userID int, name string, size int64, r io.Reader) (Upload, error) {
+	if size > u.maxBytes {
+		return Upload{}, ErrTooLarge
+	}
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return Upload{}, ErrEmpty
+		}
+		return Upload{}, err
+	}
+	head = head[:n]
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !u.allowed[contentType] {
+		return Upload{}, ErrUnsupportedType
+	}
+	key, err := u.newKey(contentType)
+	if err != nil {
+		return Upload{}, err
+	}
+	body := io.MultiReader(bytes.NewReader(head), r)
+	if err := u.store.Put(ctx, key, body, size, contentType); err != nil {
+		return Upload{}, err
+	}
+	status := "ready"
+	if u.proc != nil && processable[contentType] {
+		status = "pending"
+	}
+	id, err := u.repo.CreateUpload(userID, key, contentType, size, filepath.Base(name), status)
+	if err != nil {
+		_ = u.store.Delete(ctx, key)
+		return Upload{}, err
+	}
+	if status == "pending" {
+		u.proc.Enqueue(id)
+	}
+	return u.Get(userID, id)
+}
+
+func (u *Usecase) newKey(contentType string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	ext := extensions[contentType]
+	if ext == "" {
+		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return u.now().UTC().Format("2006/01/") + hex.EncodeToString(b) + ext, nil
+}
+
+// Get returns one of the user's own uploads with a fresh download URL.
+func (u *Usecase) Get(userID, id int) (Upload, error) {
+	row, _ := u.repo.GetUpload(id)
+	var up Upload
+	if err := row.Scan(&up.ID, &up.UserID, &up.StorageKey, &up.ContentType, &up.Size, &up.OriginalName, &up.Status, &up.CreatedAt); err != nil {
+		return Upload{}, err
+	}
+	if up.UserID != userID {
+		return Upload{}, ErrForbidden
+	}
+	if err := u.loadVariants([]*Upload{&up}); err != nil {
+		return Upload{}, err
+	}
+	return up, u.sign(&up)
+}
+
+// loadVariants fills Variants on every upload with a single query.
+func (u *Usecase) loadVariants(uploads []*Upload) error {
+	if len(uploads) == 0 {
+		return nil
+	}
+	ids := make([]int, len(uploads))
+	index := make(map[int]*Upload, len(uploads))
+	for i, up := range uploads {
+		ids[i] = up.ID
+		index[up.ID] = up
+	}
+	rows, err := u.repo.VariantsByUploadIDs(ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var uploadID int
+		var name string
+		var v Variant
+		if err := rows.Scan(&uploadID, &name, &v.StorageKey, &v.ContentType, &v.Width, &v.Height, &v.Size); err != nil {
+			return err
+		}
+		if up, ok := index[uploadID]; ok {
+			if up.Variants == nil {
+				up.Variants = map[string]Variant{}
+			}
+			up.Variants[name] = v
+		}
+	}
+	return rows.Err()
+}
+
+// sign sets fresh signed URLs on the upload and its variants.
+func (u *Usecase) sign(up *Upload) error {
+	up.URLExpiresAt = u.now().Add(u.urlTTL).UTC()
+	url, err := u.store.SignedURL(up.StorageKey, up.URLExpiresAt)
+	if err != nil {
+		return err
+	}
+	up.URL = url
+	for name, v := range up.Variants {
+		if v.URL, err = u.store.SignedURL(v.StorageKey, up.URLExpiresAt); err != nil {
+			return err
+		}
+		up.Variants[name] = v
+	}
+	return nil
+}
+
+// Attach links one of the user's uploads to one of their posts.
+func (u *Usecase) Attach(userID, postID int, req AttachRequest) error {
+	ownerID, err := u.repo.PostOwnerID(postID)
+	if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return ErrForbidden
+	}
+	if _, err := u.Get(userID, req.UploadID); err != nil {
+		return err
+	}
+	return u.repo.Attach(postID, req.UploadID, req.Position)
+}
+
+func (u *Usecase) Detach(userID, postID, uploadID int) error {
+	ownerID, err := u.repo.PostOwnerID(postID)
+	if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return ErrForbidden
+	}
+	n, err := u.repo.Detach(postID, uploadID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListByPost returns the attachments of a post viewerID may read, with
+// signed URLs, or ErrNotFound when the post is deleted or hidden from them.
+func (u *Usecase) ListByPost(viewerID, postID int) ([]Attachment, error) {
+	readable, err := u.repo.PostReadable(postID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !readable {
+		return nil, ErrNotFound
+	}
+	rows, err := u.repo.ListByPost(postID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Attachment{}
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.PostID, &a.Position, &a.ID, &a.UserID, &a.StorageKey, &a.ContentType, &a.Size, &a.OriginalName, &a.Status, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	uploads := make([]*Upload, len(out))
+	for i := range out {
+		uploads[i] = &out[i].Upload
+	}
+	if err := u.loadVariants(uploads); err != nil {
+		return nil, err
+	}
+	for _, up := range uploads {
+		if err := u.sign(up); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+var (
+	ErrForbidden       = errString("forbidden")
+	ErrNotFound        = errString("not_found")
+	ErrEmpty           = errString("empty_upload")
+	ErrTooLarge        = errString("upload_too_large")
+	ErrUnsupportedType = errString("unsupported_media_type")
+	ErrImageTooLarge   = errString("image_dimensions_too_large")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
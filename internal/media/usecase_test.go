@@ -0,0 +1,147 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"majoo-case1-rest-api/config"
+	"majoo-case1-rest-api/internal/storage"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func newTestUsecase(t *testing.T) (*Usecase, sqlmock.Sqlmock, func()) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	store, err := storage.NewLocal(t.TempDir(), "http://example.test/files", []byte("secret"))
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	cfg := config.Config{
+		UploadMaxBytes:     1 << 20,
+		UploadAllowedTypes: []string{"image/png", "image/jpeg"},
+		DownloadURLTTL:     time.Minute,
+	}
+	return NewUsecase(NewRepository(db), store, nil, cfg), mock, func() { db.Close() }
+}
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestUsecase_Upload_Success(t *testing.T) {
+	uc, mock, done := newTestUsecase(t)
+	defer done()
+
+	data := pngBytes(t)
+	mock.ExpectQuery("INSERT INTO uploads").
+		WithArgs(1, sqlmock.AnyArg(), "image/png", int64(len(data)), "photo.png", "ready").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("SELECT id, user_id, storage_key").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "storage_key", "content_type", "size_bytes", "original_name", "status", "created_at"}).
+			AddRow(3, 1, "2024/01/abc.png", "image/png", len(data), "photo.png", "ready", time.Now()))
+	mock.ExpectQuery("SELECT upload_id, name, storage_key").
+		WillReturnRows(sqlmock.NewRows([]string{"upload_id", "name", "storage_key", "content_type", "width", "height", "size_bytes"}))
+
+	up, err := uc.Upload(context.Background(), 1, "../../photo.png", int64(len(data)), bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Upload error: %v", err)
+	}
+	if !strings.HasPrefix(up.URL, "http://example.test/files/2024/01/abc.png?") {
+		t.Errorf("expected signed URL, got %q", up.URL)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Upload_RejectsSpoofedType(t *testing.T) {
+	uc, mock, done := newTestUsecase(t)
+	defer done()
+
+	// An HTML file named like an image is still HTML.
+	data := []byte("<html><script>alert(1)</script></html>")
+	_, err := uc.Upload(context.Background(), 1, "cat.png", int64(len(data)), bytes.NewReader(data))
+	if err != ErrUnsupportedType {
+		t.Errorf("expected ErrUnsupportedType, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Upload_TooLarge(t *testing.T) {
+	uc, _, done := newTestUsecase(t)
+	defer done()
+
+	if _, err := uc.Upload(context.Background(), 1, "big.png", 2<<20, bytes.NewReader(nil)); err != ErrTooLarge {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+}
+
+func TestUsecase_Attach_Forbidden(t *testing.T) {
+	uc, mock, done := newTestUsecase(t)
+	defer done()
+
+	mock.ExpectQuery("SELECT user_id FROM posts").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(999))
+
+	if err := uc.Attach(1, 5, AttachRequest{UploadID: 3}); err != ErrForbidden {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Attach_OthersUpload(t *testing.T) {
+	uc, mock, done := newTestUsecase(t)
+	defer done()
+
+	mock.ExpectQuery("SELECT user_id FROM posts").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectQuery("SELECT id, user_id, storage_key").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "storage_key", "content_type", "size_bytes", "original_name", "status", "created_at"}).
+			AddRow(3, 2, "k.png", "image/png", 10, "k.png", "ready", time.Now()))
+
+	if err := uc.Attach(1, 5, AttachRequest{UploadID: 3}); err != ErrForbidden {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_ListByPost_UnreadablePost(t *testing.T) {
+	uc, mock, done := newTestUsecase(t)
+	defer done()
+
+	// deleted, or hidden by reports or the filter from someone else
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM posts").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	if _, err := uc.ListByPost(1, 5); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
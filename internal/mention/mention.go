@@ -0,0 +1,56 @@
+// Package mention finds @username references in post and comment content
+// and stores the ones that name existing users.
+package mention
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// pattern matches an @ that does not follow a word character (so e-mail
+// addresses don't count) and the username after it.
+var pattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_][A-Za-z0-9_.-]*)`)
+
+// Span is one @username occurrence. Offset and Length count Unicode code
+// points and cover the @ as well as the name.
+type Span struct {
+	Username string
+	Offset   int
+	Length   int
+}
+
+// Parse returns every mention in s in order. Trailing dots and dashes are
+// treated as punctuation.
+func Parse(s string) []Span {
+	var out []Span
+	for _, m := range pattern.FindAllStringSubmatchIndex(s, -1) {
+		name := strings.TrimRight(s[m[2]:m[3]], ".-")
+		if name == "" {
+			continue
+		}
+		at := m[2] - 1
+		out = append(out, Span{
+			Username: name,
+			Offset:   utf8.RuneCountInString(s[:at]),
+			Length:   1 + utf8.RuneCountInString(name),
+		})
+	}
+	return out
+}
+
+// Usernames returns the distinct usernames mentioned in s, in order of first
+// appearance.
+func Usernames(s string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, sp := range Parse(s) {
+		key := strings.ToLower(sp.Username)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, sp.Username)
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+package mention
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUsernames(t *testing.T) {
+	cases := map[string][]string{
+		"hi @alice and @bob.":        {"alice", "bob"},
+		"@alice @Alice @alice_2":     {"alice", "alice_2"},
+		"mail me at bob@example.com": nil,
+		"(@carol) @@dave @":          {"carol"},
+		"thanks @j.doe-":             {"j.doe"},
+	}
+	for in, want := range cases {
+		if got := Usernames(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("Usernames(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestParse_OffsetsCountCodePoints(t *testing.T) {
+	got := Parse("héllo @zoe, @bob. @bob")
+	want := []Span{
+		{Username: "zoe", Offset: 6, Length: 4},
+		{Username: "bob", Offset: 12, Length: 4},
+		{Username: "bob", Offset: 18, Length: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %+v, want %+v", got, want)
+	}
+}
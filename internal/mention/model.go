@@ -0,0 +1,18 @@
+package mention
+
+// Target is the kind of content a mention appears in.
+type Target string
+
+const (
+	Post    Target = "post"
+	Comment Target = "comment"
+)
+
+// Entity is a resolved mention as returned to clients. Offset and Length
+// locate the "@name" text in the content, counted in Unicode code points.
+type Entity struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
@@ -0,0 +1,87 @@
+package mention
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// tables maps a target to its mentions table and id column.
+var tables = map[Target][2]string{
+	Post:    {"post_mentions", "post_id"},
+	Comment: {"comment_mentions", "comment_id"},
+}
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+// SaveTx stores the mentions in content for a newly written row. Names that
+// don't match a user, case-insensitively, are ignored.
+func (r *Repository) SaveTx(tx *sql.Tx, t Target, id int, content string) error {
+	spans := Parse(content)
+	if len(spans) == 0 {
+		return nil
+	}
+	names := make([]string, len(spans))
+	offsets := make([]int64, len(spans))
+	lengths := make([]int64, len(spans))
+	for i, sp := range spans {
+		names[i] = strings.ToLower(sp.Username)
+		offsets[i] = int64(sp.Offset)
+		lengths[i] = int64(sp.Length)
+	}
+	tbl := tables[t]
+	q := fmt.Sprintf(`INSERT INTO %s (%s, user_id, start_offset, length)
+          SELECT $1, u.id, m.start_offset, m.length
+          FROM unnest($2::text[], $3::int[], $4::int[]) AS m(username, start_offset, length)
+          JOIN users u ON LOWER(u.username) = m.username`, tbl[0], tbl[1])
+	_, err := tx.Exec(q, id, pq.Array(names), pq.Array(offsets), pq.Array(lengths))
+	return err
+}
+
+// UserIDsTx returns the distinct users mentioned in the target.
+func (r *Repository) UserIDsTx(tx *sql.Tx, t Target, id int) ([]int, error) {
+	tbl := tables[t]
+	q := fmt.Sprintf("SELECT DISTINCT user_id FROM %s WHERE %s = $1 ORDER BY user_id", tbl[0], tbl[1])
+	rows, err := tx.Query(q, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var uid int
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		ids = append(ids, uid)
+	}
+	return ids, rows.Err()
+}
+
+// Entities loads the mentions of the given targets in one query, keyed by
+// target ID and ordered by offset.
+func (r *Repository) Entities(t Target, ids []int) (map[int][]Entity, error) {
+	tbl := tables[t]
+	q := fmt.Sprintf(`SELECT m.%[2]s, m.user_id, u.username, m.start_offset, m.length
+          FROM %[1]s m JOIN users u ON u.id = m.user_id
+          WHERE m.%[2]s = ANY($1) ORDER BY m.%[2]s, m.start_offset`, tbl[0], tbl[1])
+	rows, err := r.db.Query(q, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int][]Entity{}
+	for rows.Next() {
+		var id int
+		var e Entity
+		if err := rows.Scan(&id, &e.UserID, &e.Username, &e.Offset, &e.Length); err != nil {
+			return nil, err
+		}
+		out[id] = append(out[id], e)
+	}
+	return out, rows.Err()
+}
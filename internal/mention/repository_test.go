@@ -0,0 +1,35 @@
+package mention
+
+import (
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRepository_SaveTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO comment_mentions \\(comment_id, user_id, start_offset, length\\)").
+		WithArgs(7, `{"alice","bob"}`, "{0,11}", "{6,4}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	tx, _ := db.Begin()
+	if err := repo.SaveTx(tx, Comment, 7, "@Alice and @bob"); err != nil {
+		t.Fatalf("SaveTx: %v", err)
+	}
+	// no mentions, no query
+	if err := repo.SaveTx(tx, Comment, 8, "nobody here"); err != nil {
+		t.Fatalf("SaveTx: %v", err)
+	}
+	tx.Commit()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
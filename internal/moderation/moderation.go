@@ -0,0 +1,58 @@
+// Package moderation holds the vocabulary of comment moderation shared by
+// the comment and post packages.
+package moderation
+
+import "errors"
+
+// Mode decides which new comments wait for a moderator.
+type Mode string
+
+const (
+	// ModeOpen publishes every comment immediately.
+	ModeOpen Mode = "open"
+	// ModeFirstTime holds comments from users without an approved comment.
+	ModeFirstTime Mode = "first_time"
+	// ModeAll holds every comment.
+	ModeAll Mode = "all"
+)
+
+// Modes lists every mode, least strict first.
+var Modes = []Mode{ModeOpen, ModeFirstTime, ModeAll}
+
+// Status is where a comment stands in moderation. Only approved comments
+// are shown to everyone.
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+)
+
+var ErrUnknownMode = errors.New("unknown moderation mode")
+
+// ParseMode validates a mode; empty means open.
+func ParseMode(s string) (Mode, error) {
+	if s == "" {
+		return ModeOpen, nil
+	}
+	for _, m := range Modes {
+		if Mode(s) == m {
+			return m, nil
+		}
+	}
+	return "", ErrUnknownMode
+}
+
+// Decide returns the status a new comment starts with under mode. trusted
+// authors, such as moderators, skip the queue; hasApproved reports whether
+// the author already has an approved comment.
+func Decide(mode Mode, trusted, hasApproved bool) Status {
+	switch {
+	case trusted, mode == ModeOpen:
+		return StatusApproved
+	case mode == ModeFirstTime && hasApproved:
+		return StatusApproved
+	}
+	return StatusPending
+}
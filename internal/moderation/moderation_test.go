@@ -0,0 +1,34 @@
+package moderation
+
+import "testing"
+
+func TestDecide(t *testing.T) {
+	cases := []struct {
+		mode                 Mode
+		trusted, hasApproved bool
+		want                 Status
+	}{
+		{ModeOpen, false, false, StatusApproved},
+		{ModeFirstTime, false, false, StatusPending},
+		{ModeFirstTime, false, true, StatusApproved},
+		{ModeAll, false, true, StatusPending},
+		{ModeAll, true, false, StatusApproved},
+	}
+	for _, c := range cases {
+		if got := Decide(c.mode, c.trusted, c.hasApproved); got != c.want {
+			t.Errorf("Decide(%s, %v, %v) = %s, want %s", c.mode, c.trusted, c.hasApproved, got, c.want)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if m, err := ParseMode(""); err != nil || m != ModeOpen {
+		t.Errorf("ParseMode(\"\") = %q, %v", m, err)
+	}
+	if m, err := ParseMode("first_time"); err != nil || m != ModeFirstTime {
+		t.Errorf("ParseMode(first_time) = %q, %v", m, err)
+	}
+	if _, err := ParseMode("strict"); err != ErrUnknownMode {
+		t.Errorf("expected ErrUnknownMode, got %v", err)
+	}
+}
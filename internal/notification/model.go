@@ -0,0 +1,39 @@
+package notification
+
+import "time"
+
+type Type string
+
+const (
+	CommentOnPost  Type = "comment_on_post"
+	ReplyToComment Type = "reply_to_comment"
+	Mention        Type = "mention"
+	NewFollower    Type = "new_follower"
+)
+
+// Types lists every notification type, in the order preferences are shown.
+var Types = []Type{CommentOnPost, ReplyToComment, Mention, NewFollower}
+
+type Actor struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+type Notification struct {
+	ID        int64      `json:"id"`
+	Type      Type       `json:"type"`
+	Actor     *Actor     `json:"actor,omitempty"`
+	PostID    *int       `json:"post_id,omitempty"`
+	CommentID *int       `json:"comment_id,omitempty"`
+	Read      bool       `json:"read"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+// Page is one page of a user's notifications, newest first. NextCursor is
+// empty on the last page.
+type Page struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unread_count"`
+	NextCursor    string         `json:"next_cursor"`
+}
@@ -0,0 +1,106 @@
+package notification
+
+import (
+	"database/sql"
+)
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+// InsertTx notifies userID unless they switched notifications of type t off,
+// and returns the new notification's ID or 0 if none was created. postID and
+// commentID are stored as NULL when zero.
+func (r *Repository) InsertTx(tx *sql.Tx, userID int, t Type, actorID, postID, commentID int) (int64, error) {
+	const q = `INSERT INTO notifications (user_id, type, actor_id, post_id, comment_id)
+               SELECT $1, $2, $3, $4, $5
+               WHERE NOT EXISTS (SELECT 1 FROM notification_preferences WHERE user_id = $1 AND type = $2 AND NOT enabled)
+               RETURNING id`
+	var id int64
+	err := tx.QueryRow(q, userID, string(t), nullID(actorID), nullID(postID), nullID(commentID)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+func nullID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func (r *Repository) PostOwnerTx(tx *sql.Tx, postID int) (int, error) {
+	var id int
+	err := tx.QueryRow("SELECT user_id FROM posts WHERE id=$1", postID).Scan(&id)
+	return id, err
+}
+
+func (r *Repository) CommentAuthorTx(tx *sql.Tx, commentID int) (int, error) {
+	var id int
+	err := tx.QueryRow("SELECT user_id FROM comments WHERE id=$1", commentID).Scan(&id)
+	return id, err
+}
+
+// NotifiedTx reports whether userID already has a notification about the
+// comment, or, when commentID is 0, a mention notification about the post
+// itself.
+func (r *Repository) NotifiedTx(tx *sql.Tx, userID, postID, commentID int) (bool, error) {
+	q := "SELECT EXISTS(SELECT 1 FROM notifications WHERE user_id=$1 AND comment_id=$2)"
+	args := []interface{}{userID, commentID}
+	if commentID == 0 {
+		q = "SELECT EXISTS(SELECT 1 FROM notifications WHERE user_id=$1 AND post_id=$2 AND comment_id IS NULL AND type=$3)"
+		args = []interface{}{userID, postID, string(Mention)}
+	}
+	var ok bool
+	err := tx.QueryRow(q, args...).Scan(&ok)
+	return ok, err
+}
+
+// List returns the user's notifications with an id below before (0 for the
+// first page), newest first, along with the actor's username.
+func (r *Repository) List(userID int, before int64, limit int, unreadOnly bool) (*sql.Rows, error) {
+	const q = `SELECT n.id, n.type, n.actor_id, COALESCE(u.username, ''), n.post_id, n.comment_id, n.created_at, n.read_at
+               FROM notifications n LEFT JOIN users u ON u.id = n.actor_id
+               WHERE n.user_id = $1 AND ($2 = 0 OR n.id < $2) AND (NOT $3 OR n.read_at IS NULL)
+               ORDER BY n.id DESC LIMIT $4`
+	return r.db.Query(q, userID, before, unreadOnly, limit)
+}
+
+func (r *Repository) UnreadCount(userID int) (int, error) {
+	var n int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id=$1 AND read_at IS NULL", userID).Scan(&n)
+	return n, err
+}
+
+// MarkRead marks one of the user's notifications read and reports whether it exists.
+func (r *Repository) MarkRead(userID int, id int64) (bool, error) {
+	res, err := r.db.Exec(`UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+                           WHERE id=$1 AND user_id=$2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *Repository) MarkAllRead(userID int) error {
+	_, err := r.db.Exec("UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id=$1 AND read_at IS NULL", userID)
+	return err
+}
+
+func (r *Repository) Preferences(userID int) (*sql.Rows, error) {
+	return r.db.Query("SELECT type, enabled FROM notification_preferences WHERE user_id=$1", userID)
+}
+
+func (r *Repository) SetPreferencesTx(tx *sql.Tx, userID int, prefs map[Type]bool) error {
+	for t, enabled := range prefs {
+		_, err := tx.Exec(`INSERT INTO notification_preferences (user_id, type, enabled) VALUES ($1,$2,$3)
+                           ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled`, userID, string(t), enabled)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
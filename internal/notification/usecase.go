@@ -0,0 +1,302 @@
+package notification
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"strconv"
+
+	"majoo-case1-rest-api/internal/event"
+	"majoo-case1-rest-api/internal/mention"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type Usecase struct {
+	repo     *Repository
+	mentions *mention.Repository
+	events   *event.Bus
+	db       *sql.DB
+}
+
+func NewUsecase(db *sql.DB, repo *Repository) *Usecase {
+	return &Usecase{db: db, repo: repo, mentions: mention.NewRepository(db)}
+}
+
+// Subscribe registers the producers of notifications on bus, which is also
+// where notification.created is published for each notification stored.
+func (u *Usecase) Subscribe(bus *event.Bus) {
+	u.events = bus
+	bus.Subscribe(event.CommentCreated, u.onCommentCreated)
+	bus.Subscribe(event.CommentUpdated, u.onCommentUpdated)
+	bus.Subscribe(event.PostCreated, u.onPostMentions)
+	bus.Subscribe(event.PostUpdated, u.onPostMentions)
+	bus.Subscribe(event.UserFollowed, u.onUserFollowed)
+}
+
+func (u *Usecase) insertTx(tx *sql.Tx, userID int, t Type, actorID, postID, commentID int) error {
+	id, err := u.repo.InsertTx(tx, userID, t, actorID, postID, commentID)
+	if err != nil || id == 0 {
+		return err
+	}
+	return u.events.PublishTx(tx, event.Event{Type: event.NotificationCreated, ActorID: actorID, UserID: userID, NotificationID: id})
+}
+
+// onCommentCreated notifies the author of the comment replied to, the post's
+// author and the users mentioned, each at most once and never the commenter
+// themselves. A reply notification wins over the others when they overlap.
+// A comment restored from the trash notified them when it was first posted.
+func (u *Usecase) onCommentCreated(tx *sql.Tx, e event.Event) error {
+	if e.Restored {
+		return nil
+	}
+	notified := map[int]bool{e.ActorID: true}
+	notify := func(userID int, t Type) error {
+		if notified[userID] {
+			return nil
+		}
+		notified[userID] = true
+		return u.insertTx(tx, userID, t, e.ActorID, e.PostID, e.CommentID)
+	}
+	if e.ParentID != 0 {
+		author, err := u.repo.CommentAuthorTx(tx, e.ParentID)
+		if err != nil {
+			return err
+		}
+		if err := notify(author, ReplyToComment); err != nil {
+			return err
+		}
+	}
+	owner, err := u.repo.PostOwnerTx(tx, e.PostID)
+	if err != nil {
+		return err
+	}
+	if err := notify(owner, CommentOnPost); err != nil {
+		return err
+	}
+	mentioned, err := u.mentions.UserIDsTx(tx, mention.Comment, e.CommentID)
+	if err != nil {
+		return err
+	}
+	for _, id := range mentioned {
+		if err := notify(id, Mention); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// onCommentUpdated notifies users mentioned by an edit who haven't been
+// notified about the comment before, so re-saving a comment or editing its
+// wording doesn't notify anyone twice. Notifications follow the comment to
+// its new ID, which is what makes the earlier ones visible here.
+func (u *Usecase) onCommentUpdated(tx *sql.Tx, e event.Event) error {
+	mentioned, err := u.mentions.UserIDsTx(tx, mention.Comment, e.CommentID)
+	if err != nil {
+		return err
+	}
+	for _, id := range mentioned {
+		if id == e.ActorID {
+			continue
+		}
+		done, err := u.repo.NotifiedTx(tx, id, 0, e.CommentID)
+		if err != nil {
+			return err
+		}
+		if !done {
+			if err := u.insertTx(tx, id, Mention, e.ActorID, e.PostID, e.CommentID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// onPostMentions notifies users mentioned in a new or edited post, once per
+// post across edits.
+func (u *Usecase) onPostMentions(tx *sql.Tx, e event.Event) error {
+	mentioned, err := u.mentions.UserIDsTx(tx, mention.Post, e.PostID)
+	if err != nil {
+		return err
+	}
+	for _, id := range mentioned {
+		if id == e.ActorID {
+			continue
+		}
+		done, err := u.repo.NotifiedTx(tx, id, e.PostID, 0)
+		if err != nil {
+			return err
+		}
+		if !done {
+			if err := u.insertTx(tx, id, Mention, e.ActorID, e.PostID, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (u *Usecase) onUserFollowed(tx *sql.Tx, e event.Event) error {
+	return u.insertTx(tx, e.UserID, NewFollower, e.ActorID, 0, 0)
+}
+
+// List returns a page of the user's notifications following cursor, which is
+// empty for the first page, together with their total unread count.
+func (u *Usecase) List(userID int, cursor string, limit int, unreadOnly bool) (Page, error) {
+	if limit < 1 || limit > maxLimit {
+		limit = defaultLimit
+	}
+	before, err := decodeCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+	rows, err := u.repo.List(userID, before, limit+1, unreadOnly)
+	if err != nil {
+		return Page{}, err
+	}
+	defer rows.Close()
+	page := Page{Notifications: []Notification{}}
+	for rows.Next() {
+		var n Notification
+		var actorID, postID, commentID sql.NullInt64
+		var actorName string
+		var readAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.Type, &actorID, &actorName, &postID, &commentID, &n.CreatedAt, &readAt); err != nil {
+			return Page{}, err
+		}
+		if actorID.Valid {
+			n.Actor = &Actor{ID: int(actorID.Int64), Username: actorName}
+		}
+		n.PostID = intPtr(postID)
+		n.CommentID = intPtr(commentID)
+		if readAt.Valid {
+			n.Read = true
+			n.ReadAt = &readAt.Time
+		}
+		page.Notifications = append(page.Notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return Page{}, err
+	}
+	if len(page.Notifications) > limit {
+		page.Notifications = page.Notifications[:limit]
+		page.NextCursor = encodeCursor(page.Notifications[limit-1].ID)
+	}
+	if page.UnreadCount, err = u.repo.UnreadCount(userID); err != nil {
+		return Page{}, err
+	}
+	return page, nil
+}
+
+func intPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+func (u *Usecase) UnreadCount(userID int) (int, error) {
+	return u.repo.UnreadCount(userID)
+}
+
+func (u *Usecase) MarkRead(userID int, id int64) error {
+	found, err := u.repo.MarkRead(userID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (u *Usecase) MarkAllRead(userID int) error {
+	return u.repo.MarkAllRead(userID)
+}
+
+// Preferences reports for every notification type whether the user receives it.
+func (u *Usecase) Preferences(userID int) (map[Type]bool, error) {
+	out := make(map[Type]bool, len(Types))
+	for _, t := range Types {
+		out[t] = true
+	}
+	rows, err := u.repo.Preferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t Type
+		var enabled bool
+		if err := rows.Scan(&t, &enabled); err != nil {
+			return nil, err
+		}
+		if _, ok := out[t]; ok {
+			out[t] = enabled
+		}
+	}
+	return out, rows.Err()
+}
+
+// SetPreferences switches the given types on or off; types not mentioned keep
+// their setting. It returns the resulting preferences.
+func (u *Usecase) SetPreferences(userID int, prefs map[Type]bool) (map[Type]bool, error) {
+	for t := range prefs {
+		if !known(t) {
+			return nil, ErrUnknownType
+		}
+	}
+	tx, err := u.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err := u.repo.SetPreferencesTx(tx, userID, prefs); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return u.Preferences(userID)
+}
+
+func known(t Type) bool {
+	for _, k := range Types {
+		if k == t {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+var (
+	ErrNotFound      = errString("notification not found")
+	ErrUnknownType   = errString("unknown notification type")
+	ErrInvalidCursor = errString("invalid cursor")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -0,0 +1,162 @@
+package notification
+
+import (
+	"testing"
+
+	"majoo-case1-rest-api/internal/event"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUsecase_OnCommentCreated_NotifiesEachUserOnce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	bus := event.NewBus()
+	uc.Subscribe(bus)
+
+	mock.ExpectBegin()
+	// user 2 wrote the parent comment and the post: only the reply counts
+	mock.ExpectQuery("SELECT user_id FROM comments").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2))
+	mock.ExpectQuery("INSERT INTO notifications").
+		WithArgs(2, "reply_to_comment", 1, 10, 11).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT user_id FROM posts").
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2))
+	// @alice is user 3 and @carol the commenter; @nobody was never stored
+	mock.ExpectQuery("SELECT DISTINCT user_id FROM comment_mentions").
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1).AddRow(3))
+	mock.ExpectQuery("INSERT INTO notifications").
+		WithArgs(3, "mention", 1, 10, 11).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	e := event.Event{Type: event.CommentCreated, ActorID: 1, PostID: 10, CommentID: 11, ParentID: 5, Content: "@Alice @carol @nobody"}
+	if err := bus.PublishTx(tx, e); err != nil {
+		t.Fatalf("PublishTx error: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_OnCommentCreated_SkipsRestoredComments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	bus := event.NewBus()
+	uc.Subscribe(bus)
+
+	// everyone was notified when the comment was first posted
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	e := event.Event{Type: event.CommentCreated, ActorID: 1, PostID: 10, CommentID: 11, ParentID: 5, Content: "@alice", Restored: true}
+	if err := bus.PublishTx(tx, e); err != nil {
+		t.Fatalf("PublishTx error: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_OnCommentUpdated_NotifiesNewMentionsOnly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	bus := event.NewBus()
+	uc.Subscribe(bus)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT DISTINCT user_id FROM comment_mentions").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(3).AddRow(4))
+	// user 3 was notified when the comment was created
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(3, 12).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(4, 12).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("INSERT INTO notifications").
+		WithArgs(4, "mention", 1, 10, 12).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	e := event.Event{Type: event.CommentUpdated, ActorID: 1, PostID: 10, CommentID: 12, PreviousID: 11}
+	if err := bus.PublishTx(tx, e); err != nil {
+		t.Fatalf("PublishTx error: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_SetPreferences_UnknownType(t *testing.T) {
+	uc := NewUsecase(nil, nil)
+	if _, err := uc.SetPreferences(1, map[Type]bool{"likes": false}); err != ErrUnknownType {
+		t.Errorf("expected ErrUnknownType, got %v", err)
+	}
+}
+
+func TestUsecase_Preferences_DefaultsToEnabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	mock.ExpectQuery("SELECT type, enabled FROM notification_preferences").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "enabled"}).AddRow("mention", false))
+
+	prefs, err := uc.Preferences(1)
+	if err != nil {
+		t.Fatalf("Preferences error: %v", err)
+	}
+	if prefs[Mention] || !prefs[CommentOnPost] || !prefs[NewFollower] || len(prefs) != len(Types) {
+		t.Errorf("unexpected preferences %v", prefs)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -1,13 +1,41 @@
 package post
 
 type CreatePostRequest struct {
-    Title   string `json:"title" binding:"required,min=1,max=255"`
-    Content string `json:"content" binding:"required,min=1"`
+    Title         string   `json:"title" binding:"required,min=1,max=255"`
+    Content       string   `json:"content" binding:"required,min=1"`
+    ContentFormat string   `json:"content_format" binding:"omitempty,oneof=plain markdown"`
+    Tags          []string `json:"tags" binding:"omitempty,max=10,dive,min=1,max=50"`
 }
 
 type UpdatePostRequest struct {
-    Title   *string `json:"title" binding:"omitempty,min=1,max=255"`
-    Content *string `json:"content" binding:"omitempty,min=1"`
+    Title         *string   `json:"title" binding:"omitempty,min=1,max=255"`
+    Content       *string   `json:"content" binding:"omitempty,min=1"`
+    ContentFormat *string   `json:"content_format" binding:"omitempty,oneof=plain markdown"`
+    Tags          *[]string `json:"tags" binding:"omitempty,max=10,dive,min=1,max=50"`
 }
 
+// ListFilter narrows List to posts carrying the given tag slugs. With MatchAll
+// a post must carry every tag, otherwise any one of them is enough. A non-zero
+// AuthorID keeps only that user's posts, and a non-zero MutedBy drops the
+// posts of users that user muted.
+type ListFilter struct {
+    Tags     []string
+    MatchAll bool
+    AuthorID int
+    MutedBy  int
+}
+
+
 
+// Page is a keyset-paginated list of posts. NextCursor is empty on the last page.
+type Page struct {
+    Posts      []Post `json:"posts"`
+    NextCursor string `json:"next_cursor"`
+}
+
+type UpdateSettingsRequest struct {
+    CommentsEnabled *bool `json:"comments_enabled"`
+    CommentsLocked  *bool `json:"comments_locked"`
+    MembersOnly     *bool `json:"members_only"`
+    PreModerated    *bool `json:"pre_moderated"`
+}
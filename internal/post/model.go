@@ -1,15 +1,39 @@
 package post
 
-import "time"
+import (
+    "time"
+
+    "majoo-case1-rest-api/internal/mention"
+    "majoo-case1-rest-api/internal/reaction"
+)
 
 type Post struct {
-    ID        int       `json:"id"`
-    UserID    int       `json:"user_id"`
-    Title     string    `json:"title"`
-    Content   string    `json:"content"`
-    CreatedAt time.Time `json:"created_at"`
-    UpdatedAt time.Time `json:"updated_at"`
-    Author    string    `json:"author,omitempty"`
-}
+    ID            int       `json:"id"`
+    UserID        int       `json:"user_id"`
+    Slug          string    `json:"slug"`
+    Title         string    `json:"title"`
+    Content       string    `json:"content"`
+    ContentFormat string    `json:"content_format"`
+    ContentHTML   string    `json:"content_html"`
+    Tags          []string  `json:"tags"`
+    CreatedAt     time.Time `json:"created_at"`
+    UpdatedAt     time.Time `json:"updated_at"`
+    Author        string    `json:"author,omitempty"`
+    // Version goes up with every edit; If-Match is checked against it.
+    Version int `json:"version"`
 
+    // Mentions locates the @usernames in Content that name existing users.
+    Mentions []mention.Entity `json:"mentions"`
+    reaction.Summary
+}
 
+// Settings is how the author lets readers discuss a post. With comments
+// disabled or locked no new comments are accepted; existing ones stay
+// listed. MembersOnly accepts comments only from the author's followers,
+// and PreModerated holds every comment for a moderator.
+type Settings struct {
+    CommentsEnabled bool `json:"comments_enabled"`
+    CommentsLocked  bool `json:"comments_locked"`
+    MembersOnly     bool `json:"members_only"`
+    PreModerated    bool `json:"pre_moderated"`
+}
@@ -1,23 +1,136 @@
 package post
 
-import "database/sql"
+import (
+    "database/sql"
+    "fmt"
+    "time"
+
+    "majoo-case1-rest-api/internal/moderation"
+    "majoo-case1-rest-api/internal/tag"
+
+    "github.com/lib/pq"
+)
 
 type Repository struct{ db *sql.DB }
 
 func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
 
-func (r *Repository) List(limit, offset int) (*sql.Rows, error) {
-    const q = `SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at, u.username as author
+func (r *Repository) List(f ListFilter, limit, offset int) (*sql.Rows, error) {
+    q := `SELECT p.id, p.user_id, COALESCE(p.slug, ''), p.title, p.content, p.content_format, p.content_html, p.created_at, p.updated_at, p.version, u.username as author
+          FROM posts p JOIN users u ON p.user_id = u.id
+          WHERE p.deleted_at IS NULL AND p.hidden_at IS NULL`
+    args := []interface{}{limit, offset}
+    if f.AuthorID != 0 {
+        args = append(args, f.AuthorID)
+        q += fmt.Sprintf(" AND p.user_id = $%d", len(args))
+    }
+    if f.MutedBy != 0 {
+        args = append(args, f.MutedBy)
+        q += fmt.Sprintf(" AND NOT EXISTS (SELECT 1 FROM user_mutes m WHERE m.muter_id = $%d AND m.muted_id = p.user_id)", len(args))
+    }
+    if len(f.Tags) > 0 {
+        args = append(args, pq.Array(f.Tags))
+        if f.MatchAll {
+            args = append(args, len(f.Tags))
+            q += fmt.Sprintf(` AND (SELECT COUNT(*) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+                               WHERE pt.post_id = p.id AND t.slug = ANY($%d)) = $%d`, len(args)-1, len(args))
+        } else {
+            q += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+                               WHERE pt.post_id = p.id AND t.slug = ANY($%d))`, len(args))
+        }
+    }
+    q += ` ORDER BY p.created_at DESC LIMIT $1 OFFSET $2`
+    return r.db.Query(q, args...)
+}
+
+// ListFollowed returns live posts by authors the follower follows, newest
+// first, starting after the (createdAt, id) keyset position when createdAt is
+// non-nil. Each followed author's posts come off idx_posts_user_created_live
+// in order, so the cost tracks limit rather than the authors' post counts.
+func (r *Repository) ListFollowed(followerID int, createdAt *time.Time, id, limit int) (*sql.Rows, error) {
+    const q = `SELECT p.id, p.user_id, COALESCE(p.slug, ''), p.title, p.content, p.content_format, p.content_html, p.created_at, p.updated_at, p.version, u.username as author
                FROM posts p JOIN users u ON p.user_id = u.id
-               WHERE p.deleted_at IS NULL
-               ORDER BY p.created_at DESC LIMIT $1 OFFSET $2`
-    return r.db.Query(q, limit, offset)
+               WHERE p.deleted_at IS NULL AND p.hidden_at IS NULL
+                 AND p.user_id IN (SELECT followee_id FROM follows WHERE follower_id = $1)
+                 AND ($2::timestamp IS NULL OR (p.created_at, p.id) < ($2::timestamp, $3))
+               ORDER BY p.created_at DESC, p.id DESC LIMIT $4`
+    return r.db.Query(q, followerID, createdAt, id, limit)
 }
 
-func (r *Repository) GetByID(id int) (*sql.Row, error) {
-    const q = `SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at, u.username as author
-               FROM posts p JOIN users u ON p.user_id = u.id WHERE p.id = $1 AND p.deleted_at IS NULL`
-    return r.db.QueryRow(q, id), nil
+// ListByIDs returns the live posts among ids, in no particular order.
+func (r *Repository) ListByIDs(ids []int) (*sql.Rows, error) {
+    const q = `SELECT p.id, p.user_id, COALESCE(p.slug, ''), p.title, p.content, p.content_format, p.content_html, p.created_at, p.updated_at, p.version, u.username as author
+               FROM posts p JOIN users u ON p.user_id = u.id WHERE p.id = ANY($1) AND p.deleted_at IS NULL AND p.hidden_at IS NULL`
+    return r.db.Query(q, pq.Array(ids))
+}
+
+// GetByID returns a live post; hidden posts are only returned to their
+// author, viewerID.
+func (r *Repository) GetByID(id, viewerID int) (*sql.Row, error) {
+    const q = `SELECT p.id, p.user_id, COALESCE(p.slug, ''), p.title, p.content, p.content_format, p.content_html, p.created_at, p.updated_at, p.version, u.username as author
+               FROM posts p JOIN users u ON p.user_id = u.id WHERE p.id = $1 AND p.deleted_at IS NULL AND (p.hidden_at IS NULL OR p.user_id = $2)`
+    return r.db.QueryRow(q, id, viewerID), nil
+}
+
+// TitleTx reads a post's title inside tx.
+func (r *Repository) TitleTx(tx *sql.Tx, id int) (string, error) {
+    var title string
+    err := tx.QueryRow("SELECT title FROM posts WHERE id=$1", id).Scan(&title)
+    return title, err
+}
+
+// HiddenTx reports whether a post is hidden from readers, by reports or by a
+// filter holding it.
+func (r *Repository) HiddenTx(tx *sql.Tx, id int) (bool, error) {
+    var hidden bool
+    err := tx.QueryRow("SELECT hidden_at IS NOT NULL FROM posts WHERE id=$1", id).Scan(&hidden)
+    return hidden, err
+}
+
+// ResolveSlug finds the live post a current or historical slug points at,
+// along with that post's current slug.
+func (r *Repository) ResolveSlug(s string) (int, string, error) {
+    const q = `SELECT p.id, COALESCE(p.slug, '') FROM post_slugs ps
+               JOIN posts p ON p.id = ps.post_id AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+               WHERE ps.slug = $1`
+    var id int
+    var current string
+    err := r.db.QueryRow(q, s).Scan(&id, &current)
+    return id, current, err
+}
+
+// TakenSlugsTx returns the slugs equal to base or of the form base-N together
+// with the post owning each. It takes a transaction-scoped advisory lock on
+// base so concurrent posts with the same title don't race for a suffix.
+func (r *Repository) TakenSlugsTx(tx *sql.Tx, base string) (map[string]int, error) {
+    if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", base); err != nil {
+        return nil, err
+    }
+    rows, err := tx.Query("SELECT slug, post_id FROM post_slugs WHERE slug = $1 OR slug LIKE $2", base, base+"-%")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    taken := map[string]int{}
+    for rows.Next() {
+        var s string
+        var postID int
+        if err := rows.Scan(&s, &postID); err != nil {
+            return nil, err
+        }
+        taken[s] = postID
+    }
+    return taken, rows.Err()
+}
+
+// SetSlugTx makes s the current slug of the post and records it in the history.
+func (r *Repository) SetSlugTx(tx *sql.Tx, id int, s string) error {
+    if _, err := tx.Exec("UPDATE posts SET slug=$2 WHERE id=$1", id, s); err != nil {
+        return err
+    }
+    _, err := tx.Exec(`INSERT INTO post_slugs (slug, post_id) VALUES ($1,$2)
+                       ON CONFLICT (slug) DO UPDATE SET post_id = EXCLUDED.post_id, created_at = CURRENT_TIMESTAMP`, s, id)
+    return err
 }
 
 func (r *Repository) GetOwnerID(id int) (int, error) {
@@ -26,9 +139,17 @@ func (r *Repository) GetOwnerID(id int) (int, error) {
     return userID, err
 }
 
-func (r *Repository) CreateTx(tx *sql.Tx, userID int, title, content string) (int, error) {
+// VersionTx locks the live post and returns its version.
+func (r *Repository) VersionTx(tx *sql.Tx, id int) (int, error) {
+    var v int
+    err := tx.QueryRow("SELECT version FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&v)
+    return v, err
+}
+
+func (r *Repository) CreateTx(tx *sql.Tx, userID int, title, content, format, html string) (int, error) {
     var id int
-    err := tx.QueryRow("INSERT INTO posts (user_id, title, content) VALUES ($1,$2,$3) RETURNING id", userID, title, content).Scan(&id)
+    err := tx.QueryRow("INSERT INTO posts (user_id, title, content, content_format, content_html) VALUES ($1,$2,$3,$4,$5) RETURNING id",
+        userID, title, content, format, html).Scan(&id)
     return id, err
 }
 
@@ -51,9 +172,151 @@ func (r *Repository) UpdateTx(tx *sql.Tx, id int, title *string, content *string
     return newID, nil
 }
 
-func (r *Repository) DeleteTx(tx *sql.Tx, id int) error {
-    _, err := tx.Exec("UPDATE posts SET deleted_at=CURRENT_TIMESTAMP WHERE id=$1 AND deleted_at IS NULL", id)
+// TagsByPostIDs returns (post_id, slug) pairs for the given posts in one query.
+func (r *Repository) TagsByPostIDs(ids []int) (*sql.Rows, error) {
+    const q = `SELECT pt.post_id, t.slug FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+               WHERE pt.post_id = ANY($1) ORDER BY t.slug`
+    return r.db.Query(q, pq.Array(ids))
+}
+
+// ReplaceTagsTx makes tags the exact tag set of the post, creating missing tags.
+func (r *Repository) ReplaceTagsTx(tx *sql.Tx, postID int, tags []tag.Tag) error {
+    if _, err := tx.Exec("DELETE FROM post_tags WHERE post_id=$1", postID); err != nil {
+        return err
+    }
+    for _, t := range tags {
+        var tagID int
+        // DO UPDATE (rather than DO NOTHING) so RETURNING yields the existing row
+        err := tx.QueryRow(`INSERT INTO tags (slug, name) VALUES ($1,$2)
+                            ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug RETURNING id`, t.Slug, t.Name).Scan(&tagID)
+        if err != nil {
+            return err
+        }
+        if _, err := tx.Exec("INSERT INTO post_tags (post_id, tag_id) VALUES ($1,$2)", postID, tagID); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// RelinkTx moves everything hanging off a post from the row superseded by
+// UpdateTx to its replacement so the edit does not drop it.
+func (r *Repository) RelinkTx(tx *sql.Tx, oldID, newID int) error {
+    stmts := []string{
+        // keep the original publication time; updated_at records the edit
+        `UPDATE posts n SET slug = o.slug, content_format = o.content_format, content_html = o.content_html, created_at = o.created_at,
+             version = o.version + 1, comment_moderation = o.comment_moderation, hidden_at = o.hidden_at,
+             comments_enabled = o.comments_enabled, comments_locked = o.comments_locked,
+             comments_members_only = o.comments_members_only, comments_premoderated = o.comments_premoderated
+         FROM posts o WHERE o.id = $1 AND n.id = $2`,
+        "UPDATE posts SET superseded_by=$2 WHERE id=$1",
+        "UPDATE post_slugs SET post_id=$2 WHERE post_id=$1",
+        "UPDATE post_tags SET post_id=$2 WHERE post_id=$1",
+        "UPDATE attachments SET post_id=$2 WHERE post_id=$1",
+        "UPDATE post_reactions SET post_id=$2 WHERE post_id=$1",
+        "UPDATE bookmarks SET post_id=$2 WHERE post_id=$1",
+        "UPDATE notifications SET post_id=$2 WHERE post_id=$1",
+        "UPDATE live_presence SET post_id=$2 WHERE post_id=$1",
+        "UPDATE reports SET target_id=$2 WHERE target='post' AND target_id=$1",
+        "UPDATE comments SET post_id=$2 WHERE post_id=$1",
+    }
+    for _, q := range stmts {
+        if _, err := tx.Exec(q, oldID, newID); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// ContentTx reads the source content and format of a post inside tx.
+func (r *Repository) ContentTx(tx *sql.Tx, id int) (string, string, error) {
+    var content, format string
+    err := tx.QueryRow("SELECT content, content_format FROM posts WHERE id=$1", id).Scan(&content, &format)
+    return content, format, err
+}
+
+func (r *Repository) SetRenderedTx(tx *sql.Tx, id int, format, html string) error {
+    _, err := tx.Exec("UPDATE posts SET content_format=$2, content_html=$3 WHERE id=$1", id, format, html)
     return err
 }
 
+// SetCommentModeration sets the moderation mode of a live post's comments;
+// nil follows the global mode. It reports whether the post exists.
+func (r *Repository) SetCommentModeration(id int, mode *moderation.Mode) (bool, error) {
+    res, err := r.db.Exec("UPDATE posts SET comment_moderation=$2 WHERE id=$1 AND deleted_at IS NULL", id, mode)
+    if err != nil { return false, err }
+    n, err := res.RowsAffected()
+    return n > 0, err
+}
+
+const settingsColumns = "comments_enabled, comments_locked, comments_members_only, comments_premoderated"
+
+// Settings returns the settings of a visible post.
+func (r *Repository) Settings(id int) (Settings, error) {
+    var s Settings
+    err := r.db.QueryRow("SELECT "+settingsColumns+" FROM posts WHERE id=$1 AND deleted_at IS NULL AND hidden_at IS NULL", id).
+        Scan(&s.CommentsEnabled, &s.CommentsLocked, &s.MembersOnly, &s.PreModerated)
+    return s, err
+}
+
+// UpdateSettings changes the settings given in req, leaving nil ones alone.
+func (r *Repository) UpdateSettings(id int, req UpdateSettingsRequest) (Settings, error) {
+    const q = `UPDATE posts SET comments_enabled = COALESCE($2, comments_enabled), comments_locked = COALESCE($3, comments_locked),
+                   comments_members_only = COALESCE($4, comments_members_only), comments_premoderated = COALESCE($5, comments_premoderated)
+               WHERE id = $1 AND deleted_at IS NULL
+               RETURNING ` + settingsColumns
+    var s Settings
+    err := r.db.QueryRow(q, id, req.CommentsEnabled, req.CommentsLocked, req.MembersOnly, req.PreModerated).
+        Scan(&s.CommentsEnabled, &s.CommentsLocked, &s.MembersOnly, &s.PreModerated)
+    return s, err
+}
+
+// DeleteTx soft-deletes a post together with its live comments, which are
+// marked to come back if the post is restored, and the reactions and
+// attachments on both.
+func (r *Repository) DeleteTx(tx *sql.Tx, id int) error {
+    stmts := []string{
+        "UPDATE posts SET deleted_at=CURRENT_TIMESTAMP WHERE id=$1 AND deleted_at IS NULL",
+        // before the comments, while the live ones can still be told apart
+        `UPDATE comment_reactions SET deleted_at=CURRENT_TIMESTAMP
+         WHERE deleted_at IS NULL AND comment_id IN (SELECT id FROM comments WHERE post_id=$1 AND deleted_at IS NULL)`,
+        "UPDATE comments SET deleted_at=CURRENT_TIMESTAMP, deleted_with_post=TRUE WHERE post_id=$1 AND deleted_at IS NULL",
+        "UPDATE post_reactions SET deleted_at=CURRENT_TIMESTAMP WHERE post_id=$1 AND deleted_at IS NULL",
+        "UPDATE attachments SET deleted_at=CURRENT_TIMESTAMP WHERE post_id=$1 AND deleted_at IS NULL",
+    }
+    for _, q := range stmts {
+        if _, err := tx.Exec(q, id); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Trashed returns the author of a deleted post, and whether it was deleted
+// longer than retention ago. Old versions left by edits are not trashed.
+func (r *Repository) Trashed(id int, retention time.Duration) (userID int, expired bool, err error) {
+    const q = `SELECT user_id, deleted_at <= CURRENT_TIMESTAMP - $2 * INTERVAL '1 second'
+               FROM posts WHERE id=$1 AND deleted_at IS NOT NULL AND superseded_by IS NULL AND NOT legacy_deleted`
+    err = r.db.QueryRow(q, id, retention.Seconds()).Scan(&userID, &expired)
+    return userID, expired, err
+}
+
+// RestoreTx undeletes a post and everything DeleteTx deleted along with it.
+func (r *Repository) RestoreTx(tx *sql.Tx, id int) error {
+    stmts := []string{
+        "UPDATE posts SET deleted_at=NULL WHERE id=$1",
+        `UPDATE comment_reactions SET deleted_at=NULL
+         WHERE comment_id IN (SELECT id FROM comments WHERE post_id=$1 AND deleted_with_post)`,
+        "UPDATE comments SET deleted_at=NULL, deleted_with_post=FALSE WHERE post_id=$1 AND deleted_with_post",
+        "UPDATE post_reactions SET deleted_at=NULL WHERE post_id=$1",
+        "UPDATE attachments SET deleted_at=NULL WHERE post_id=$1",
+    }
+    for _, q := range stmts {
+        if _, err := tx.Exec(q, id); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
 
@@ -17,6 +17,14 @@ func TestRepository_DeleteTx_SoftDelete(t *testing.T) {
     mock.ExpectBegin()
     mock.ExpectExec(regexp.QuoteMeta("UPDATE posts SET deleted_at=CURRENT_TIMESTAMP WHERE id=$1 AND deleted_at IS NULL")).
         WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec("UPDATE comment_reactions SET deleted_at=CURRENT_TIMESTAMP").
+        WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 3))
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE comments SET deleted_at=CURRENT_TIMESTAMP, deleted_with_post=TRUE WHERE post_id=$1 AND deleted_at IS NULL")).
+        WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE post_reactions SET deleted_at=CURRENT_TIMESTAMP WHERE post_id=$1 AND deleted_at IS NULL")).
+        WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 4))
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE attachments SET deleted_at=CURRENT_TIMESTAMP WHERE post_id=$1 AND deleted_at IS NULL")).
+        WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
     mock.ExpectCommit()
 
     tx, _ := db.Begin()
@@ -49,3 +57,46 @@ func TestRepository_UpdateTx_SoftUpdate(t *testing.T) {
 }
 
 
+
+func TestRepository_List_MatchAllTags(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock.New: %v", err) }
+    defer db.Close()
+
+    repo := NewRepository(db)
+
+    mock.ExpectQuery(regexp.QuoteMeta("WHERE pt.post_id = p.id AND t.slug = ANY($3)) = $4")).
+        WithArgs(10, 0, sqlmock.AnyArg(), 2).
+        WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+    rows, err := repo.List(ListFilter{Tags: []string{"go", "sql"}, MatchAll: true}, 10, 0)
+    if err != nil { t.Fatalf("List error: %v", err) }
+    rows.Close()
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet: %v", err) }
+}
+
+func TestRepository_RestoreTx_BringsBackCascadedRows(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock.New: %v", err) }
+    defer db.Close()
+
+    repo := NewRepository(db)
+
+    mock.ExpectBegin()
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE posts SET deleted_at=NULL WHERE id=$1")).
+        WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec("UPDATE comment_reactions SET deleted_at=NULL").
+        WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 3))
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE comments SET deleted_at=NULL, deleted_with_post=FALSE WHERE post_id=$1 AND deleted_with_post")).
+        WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE post_reactions SET deleted_at=NULL WHERE post_id=$1")).
+        WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 4))
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE attachments SET deleted_at=NULL WHERE post_id=$1")).
+        WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectCommit()
+
+    tx, _ := db.Begin()
+    if err := repo.RestoreTx(tx, 1); err != nil { t.Fatalf("RestoreTx error: %v", err) }
+    if err := tx.Commit(); err != nil { t.Fatalf("commit: %v", err) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet: %v", err) }
+}
@@ -1,15 +1,53 @@
 package post
 
-import "database/sql"
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"majoo-case1-rest-api/internal/content"
+	"majoo-case1-rest-api/internal/event"
+	"majoo-case1-rest-api/internal/filter"
+	"majoo-case1-rest-api/internal/mention"
+	"majoo-case1-rest-api/internal/moderation"
+	"majoo-case1-rest-api/internal/reaction"
+	"majoo-case1-rest-api/internal/report"
+	"majoo-case1-rest-api/internal/slug"
+	"majoo-case1-rest-api/internal/tag"
+)
+
+// maxSlugLength leaves room in posts.slug for a deduplicating "-N" suffix.
+const maxSlugLength = 80
 
 type Usecase struct {
-	repo *Repository
-	db   *sql.DB
+	repo      *Repository
+	reactions *reaction.Repository
+	mentions  *mention.Repository
+	reports   *report.Repository
+	events    *event.Bus
+	filter    *filter.Pipeline
+	retention time.Duration
+	db        *sql.DB
+}
+
+func NewUsecase(db *sql.DB, repo *Repository) *Usecase {
+	return &Usecase{db: db, repo: repo, reactions: reaction.NewRepository(db), mentions: mention.NewRepository(db), reports: report.NewRepository(db)}
 }
 
-func NewUsecase(db *sql.DB, repo *Repository) *Usecase { return &Usecase{db: db, repo: repo} }
+// SetEventBus makes the usecase publish post events on bus inside its write
+// transactions.
+func (u *Usecase) SetEventBus(bus *event.Bus) { u.events = bus }
+
+// SetFilter makes the usecase screen new and edited posts with p: rejected
+// posts fail with a *filter.RejectedError and flagged ones are hidden, and
+// reported, until a moderator resolves the report.
+func (u *Usecase) SetFilter(p *filter.Pipeline) { u.filter = p }
 
-func (u *Usecase) List(page, limit int) ([]Post, error) {
+// List returns a page of posts. viewerID identifies the reader for
+// per-user fields such as my_reactions and leaves out the posts of users
+// they muted; 0 means anonymous.
+func (u *Usecase) List(viewerID, page, limit int, f ListFilter) ([]Post, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -17,72 +55,464 @@ func (u *Usecase) List(page, limit int) ([]Post, error) {
 		limit = 10
 	}
 	offset := (page - 1) * limit
-	rows, err := u.repo.List(limit, offset)
+	f.MutedBy = viewerID
+	rows, err := u.repo.List(f, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var out []Post
 	for rows.Next() {
-		var p Post
-		if err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Content, &p.CreatedAt, &p.UpdatedAt, &p.Author); err != nil {
+		p, err := scanPost(rows)
+		if err != nil {
 			return nil, err
 		}
 		out = append(out, p)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := u.load(viewerID, out); err != nil {
+		return nil, err
+	}
 	return out, nil
 }
 
-func (u *Usecase) Get(id int) (Post, error) {
-	row, _ := u.repo.GetByID(id)
-	var p Post
-	if err := row.Scan(&p.ID, &p.UserID, &p.Title, &p.Content, &p.CreatedAt, &p.UpdatedAt, &p.Author); err != nil {
+// HomeFeed returns a page of posts by the authors viewerID follows, newest
+// first. cursor is empty for the first page and NextCursor afterwards.
+func (u *Usecase) HomeFeed(viewerID int, cursor string, limit int) (Page, error) {
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	at, id, err := decodeCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+	rows, err := u.repo.ListFollowed(viewerID, at, id, limit+1)
+	if err != nil {
+		return Page{}, err
+	}
+	defer rows.Close()
+	out := []Post{}
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return Page{}, err
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return Page{}, err
+	}
+	page := Page{}
+	if len(out) > limit {
+		out = out[:limit]
+		last := out[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	if err := u.load(viewerID, out); err != nil {
+		return Page{}, err
+	}
+	page.Posts = out
+	return page, nil
+}
+
+// encodeCursor packs a (created_at, id) keyset position into an opaque token.
+func encodeCursor(at time.Time, id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", at.UnixNano(), id)))
+}
+
+func decodeCursor(s string) (*time.Time, int, error) {
+	if s == "" {
+		return nil, 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	var nanos int64
+	var id int
+	if _, err := fmt.Sscanf(string(b), "%d:%d", &nanos, &id); err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	at := time.Unix(0, nanos).UTC()
+	return &at, id, nil
+}
+
+// ListByIDs returns the live posts among ids, in no particular order.
+func (u *Usecase) ListByIDs(viewerID int, ids []int) ([]Post, error) {
+	if len(ids) == 0 {
+		return []Post{}, nil
+	}
+	rows, err := u.repo.ListByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Post{}
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := u.load(viewerID, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (u *Usecase) Get(viewerID, id int) (Post, error) {
+	row, _ := u.repo.GetByID(id, viewerID)
+	p, err := scanPost(row)
+	if err != nil {
 		return Post{}, err
 	}
-	return p, nil
+	posts := []Post{p}
+	if err := u.load(viewerID, posts); err != nil {
+		return Post{}, err
+	}
+	return posts[0], nil
+}
+
+// GetBySlug returns the post s resolves to together with the post's current
+// slug, which differs from s when s is a slug the post had before a rename.
+func (u *Usecase) GetBySlug(viewerID int, s string) (Post, string, error) {
+	id, current, err := u.repo.ResolveSlug(s)
+	if err != nil {
+		return Post{}, "", err
+	}
+	if current != s {
+		return Post{}, current, nil
+	}
+	p, err := u.Get(viewerID, id)
+	return p, current, err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPost(s scanner) (Post, error) {
+	var p Post
+	err := s.Scan(&p.ID, &p.UserID, &p.Slug, &p.Title, &p.Content, &p.ContentFormat, &p.ContentHTML, &p.CreatedAt, &p.UpdatedAt, &p.Version, &p.Author)
+	if err == nil && p.ContentHTML == "" {
+		// rows written before content rendering existed
+		p.ContentHTML, _ = content.Render(content.Format(p.ContentFormat), p.Content)
+	}
+	return p, err
+}
+
+// load fills the fields kept outside the posts table, one query per field
+// for the whole batch.
+func (u *Usecase) load(viewerID int, posts []Post) error {
+	if err := u.loadTags(posts); err != nil {
+		return err
+	}
+	if err := u.loadMentions(posts); err != nil {
+		return err
+	}
+	return u.loadReactions(viewerID, posts)
+}
+
+// loadMentions fills Mentions on every post with a single query.
+func (u *Usecase) loadMentions(posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+	entities, err := u.mentions.Entities(mention.Post, ids)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Mentions = entities[posts[i].ID]
+		if posts[i].Mentions == nil {
+			posts[i].Mentions = []mention.Entity{}
+		}
+	}
+	return nil
+}
+
+func (u *Usecase) loadReactions(viewerID int, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+	summaries, err := u.reactions.Summaries(reaction.Post, ids, viewerID)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		if s, ok := summaries[posts[i].ID]; ok {
+			posts[i].Summary = s
+		} else {
+			posts[i].Summary = reaction.Empty()
+		}
+	}
+	return nil
+}
+
+// loadTags fills Tags on every post with a single query.
+func (u *Usecase) loadTags(posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int, len(posts))
+	index := make(map[int]int, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+		index[posts[i].ID] = i
+		posts[i].Tags = []string{}
+	}
+	rows, err := u.repo.TagsByPostIDs(ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postID int
+		var slug string
+		if err := rows.Scan(&postID, &slug); err != nil {
+			return err
+		}
+		if i, ok := index[postID]; ok {
+			posts[i].Tags = append(posts[i].Tags, slug)
+		}
+	}
+	return rows.Err()
 }
 
 func (u *Usecase) Create(userID int, req CreatePostRequest) (Post, error) {
+	tags, err := tag.Normalize(req.Tags)
+	if err != nil {
+		return Post{}, err
+	}
+	format, err := content.ParseFormat(req.ContentFormat)
+	if err != nil {
+		return Post{}, err
+	}
+	html, err := content.Render(format, req.Content)
+	if err != nil {
+		return Post{}, err
+	}
+	res, err := u.filter.Run(filter.Input{UserID: userID, Target: "post", Text: filterText(req.Title, req.Content)})
+	if err != nil {
+		return Post{}, err
+	}
+	if err := res.Err(); err != nil {
+		return Post{}, err
+	}
 	tx, err := u.db.Begin()
 	if err != nil {
 		return Post{}, err
 	}
 	defer tx.Rollback()
-	id, err := u.repo.CreateTx(tx, userID, req.Title, req.Content)
+	id, err := u.repo.CreateTx(tx, userID, req.Title, req.Content, string(format), html)
 	if err != nil {
 		return Post{}, err
 	}
+	if err := u.assignSlugTx(tx, id, req.Title); err != nil {
+		return Post{}, err
+	}
+	if len(tags) > 0 {
+		if err := u.repo.ReplaceTagsTx(tx, id, tags); err != nil {
+			return Post{}, err
+		}
+	}
+	if err := u.mentions.SaveTx(tx, mention.Post, id, req.Content); err != nil {
+		return Post{}, err
+	}
+	if res.Verdict == filter.Flag {
+		// nobody can see the post yet, so there is nothing to announce
+		if err := u.reports.FlagTx(tx, report.Post, id, flagDetails(res)); err != nil {
+			return Post{}, err
+		}
+	} else {
+		e := event.Event{Type: event.PostCreated, ActorID: userID, PostID: id, Content: req.Content}
+		if err := u.events.PublishTx(tx, e); err != nil {
+			return Post{}, err
+		}
+	}
 	if err := tx.Commit(); err != nil {
 		return Post{}, err
 	}
-	return u.Get(id)
+	return u.Get(userID, id)
 }
 
-func (u *Usecase) Update(userID, id int, req UpdatePostRequest) (Post, error) {
+// Update edits the post. A non-zero version is the version the caller last
+// saw; the edit fails with ErrVersionMismatch if the post has moved on.
+func (u *Usecase) Update(userID, id int, req UpdatePostRequest, version int) (Post, error) {
 	ownerID, err := u.repo.GetOwnerID(id)
+	if err == sql.ErrNoRows && version != 0 {
+		// edited or deleted since the caller read it
+		return Post{}, ErrVersionMismatch
+	}
 	if err != nil {
 		return Post{}, err
 	}
 	if ownerID != userID {
 		return Post{}, ErrForbidden
 	}
+	var tags []tag.Tag
+	if req.Tags != nil {
+		if tags, err = tag.Normalize(*req.Tags); err != nil {
+			return Post{}, err
+		}
+	}
+	if req.ContentFormat != nil {
+		if _, err := content.ParseFormat(*req.ContentFormat); err != nil {
+			return Post{}, err
+		}
+	}
 	tx, err := u.db.Begin()
 	if err != nil {
 		return Post{}, err
 	}
 	defer tx.Rollback()
+	if err := u.checkVersionTx(tx, id, version); err != nil {
+		return Post{}, err
+	}
 	newID, err := u.repo.UpdateTx(tx, id, req.Title, req.Content)
 	if err != nil {
 		return Post{}, err
 	}
+	if err := u.repo.RelinkTx(tx, id, newID); err != nil {
+		return Post{}, err
+	}
+	if req.Title != nil {
+		if err := u.assignSlugTx(tx, newID, *req.Title); err != nil {
+			return Post{}, err
+		}
+	}
+	if req.Content != nil || req.ContentFormat != nil {
+		if err := u.rerenderTx(tx, newID, req.ContentFormat); err != nil {
+			return Post{}, err
+		}
+	}
+	if req.Tags != nil {
+		if err := u.repo.ReplaceTagsTx(tx, newID, tags); err != nil {
+			return Post{}, err
+		}
+	}
+	src, _, err := u.repo.ContentTx(tx, newID)
+	if err != nil {
+		return Post{}, err
+	}
+	if err := u.mentions.SaveTx(tx, mention.Post, newID, src); err != nil {
+		return Post{}, err
+	}
+	var res filter.Result
+	if req.Title != nil || req.Content != nil {
+		title, err := u.repo.TitleTx(tx, newID)
+		if err != nil {
+			return Post{}, err
+		}
+		// the superseded row still looks live outside tx
+		res, err = u.filter.Run(filter.Input{UserID: userID, Target: "post", ID: id, Text: filterText(title, src)})
+		if err != nil {
+			return Post{}, err
+		}
+		if err := res.Err(); err != nil {
+			return Post{}, err
+		}
+	}
+	// a post already hidden keeps its content back, as Create does
+	hidden, err := u.repo.HiddenTx(tx, newID)
+	if err != nil {
+		return Post{}, err
+	}
+	var e event.Event
+	switch {
+	case res.Verdict == filter.Flag:
+		if err := u.reports.FlagTx(tx, report.Post, newID, flagDetails(res)); err != nil {
+			return Post{}, err
+		}
+		if !hidden {
+			// readers saw the post before the edit; it leaves their view until released
+			e = event.Event{Type: event.PostDeleted, ActorID: userID, PostID: id}
+		}
+	case !hidden:
+		e = event.Event{Type: event.PostUpdated, ActorID: userID, PostID: newID, PreviousID: id, Content: src}
+	}
+	if e.Type != "" {
+		if err := u.events.PublishTx(tx, e); err != nil {
+			return Post{}, err
+		}
+	}
 	if err := tx.Commit(); err != nil {
 		return Post{}, err
 	}
-	return u.Get(newID)
+	return u.Get(userID, newID)
+}
+
+// filterText is what filters see of a post; it matches the duplicate
+// filter's SQL.
+func filterText(title, content string) string { return title + "\n\n" + content }
+
+// flagDetails tells moderators why a filter held a post.
+func flagDetails(res filter.Result) string { return "flagged by " + res.Filter + ": " + res.Reason }
+
+// assignSlugTx gives the post a unique slug derived from title, suffixing
+// "-2", "-3", ... on collisions. Slugs the post already owns are reused, so
+// saving an unchanged title keeps the current slug and renaming back to an
+// earlier title revives the earlier slug.
+func (u *Usecase) assignSlugTx(tx *sql.Tx, id int, title string) error {
+	base := slug.Make(title, maxSlugLength)
+	if base == "" {
+		base = "post"
+	}
+	taken, err := u.repo.TakenSlugsTx(tx, base)
+	if err != nil {
+		return err
+	}
+	s := base
+	for n := 2; ; n++ {
+		if owner, ok := taken[s]; !ok || owner == id {
+			break
+		}
+		s = fmt.Sprintf("%s-%d", base, n)
+	}
+	return u.repo.SetSlugTx(tx, id, s)
+}
+
+// rerenderTx refreshes content_html from the post's merged content, switching
+// to format first when one is given.
+func (u *Usecase) rerenderTx(tx *sql.Tx, id int, format *string) error {
+	src, current, err := u.repo.ContentTx(tx, id)
+	if err != nil {
+		return err
+	}
+	if format != nil {
+		current = *format
+	}
+	f, err := content.ParseFormat(current)
+	if err != nil {
+		return err
+	}
+	html, err := content.Render(f, src)
+	if err != nil {
+		return err
+	}
+	return u.repo.SetRenderedTx(tx, id, string(f), html)
 }
 
-func (u *Usecase) Delete(userID, id int) error {
+// Delete moves the post to the trash; version works as in Update.
+func (u *Usecase) Delete(userID, id, version int) error {
 	ownerID, err := u.repo.GetOwnerID(id)
+	if err == sql.ErrNoRows && version != 0 {
+		return ErrVersionMismatch
+	}
 	if err != nil {
 		return err
 	}
@@ -94,14 +524,137 @@ func (u *Usecase) Delete(userID, id int) error {
 		return err
 	}
 	defer tx.Rollback()
+	if err := u.checkVersionTx(tx, id, version); err != nil {
+		return err
+	}
 	if err := u.repo.DeleteTx(tx, id); err != nil {
 		return err
 	}
+	if err := u.events.PublishTx(tx, event.Event{Type: event.PostDeleted, ActorID: userID, PostID: id}); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
+// checkVersionTx locks the post for the rest of tx and fails unless it is
+// still at version. A post edited or deleted since the caller read it no
+// longer has a live row under id, which is a mismatch too. Zero skips the
+// check.
+func (u *Usecase) checkVersionTx(tx *sql.Tx, id, version int) error {
+	if version == 0 {
+		return nil
+	}
+	current, err := u.repo.VersionTx(tx, id)
+	if err == sql.ErrNoRows {
+		return ErrVersionMismatch
+	}
+	if err != nil {
+		return err
+	}
+	if current != version {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// SetTrashRetention sets how long a deleted post can be restored.
+func (u *Usecase) SetTrashRetention(d time.Duration) { u.retention = d }
+
+// Restore brings back a post its author deleted within the trash retention,
+// along with the comments that went with it, and announces it as created.
+func (u *Usecase) Restore(userID, id int) error {
+	ownerID, expired, err := u.repo.Trashed(id, u.retention)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return ErrForbidden
+	}
+	if expired {
+		return ErrExpired
+	}
+	tx, err := u.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := u.repo.RestoreTx(tx, id); err != nil {
+		return err
+	}
+	// a post hidden before it was deleted comes back hidden, unannounced
+	hidden, err := u.repo.HiddenTx(tx, id)
+	if err != nil {
+		return err
+	}
+	if !hidden {
+		src, _, err := u.repo.ContentTx(tx, id)
+		if err != nil {
+			return err
+		}
+		e := event.Event{Type: event.PostCreated, ActorID: userID, PostID: id, Content: src, Restored: true}
+		if err := u.events.PublishTx(tx, e); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Settings returns a post's discussion settings.
+func (u *Usecase) Settings(id int) (Settings, error) {
+	s, err := u.repo.Settings(id)
+	if err == sql.ErrNoRows {
+		return Settings{}, ErrNotFound
+	}
+	return s, err
+}
+
+// UpdateSettings lets the author of a post change its discussion settings.
+func (u *Usecase) UpdateSettings(userID, id int, req UpdateSettingsRequest) (Settings, error) {
+	ownerID, err := u.repo.GetOwnerID(id)
+	if err == sql.ErrNoRows {
+		return Settings{}, ErrNotFound
+	}
+	if err != nil {
+		return Settings{}, err
+	}
+	if ownerID != userID {
+		return Settings{}, ErrForbidden
+	}
+	s, err := u.repo.UpdateSettings(id, req)
+	if err == sql.ErrNoRows {
+		return Settings{}, ErrNotFound
+	}
+	return s, err
+}
+
+// SetCommentModeration sets how new comments on a post are moderated; nil
+// follows the global mode.
+func (u *Usecase) SetCommentModeration(id int, mode *moderation.Mode) error {
+	if mode != nil {
+		// ParseMode reads "" as open; here it is a mistake
+		if m, err := moderation.ParseMode(string(*mode)); err != nil || m != *mode {
+			return moderation.ErrUnknownMode
+		}
+	}
+	ok, err := u.repo.SetCommentModeration(id, mode)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
 var (
-	ErrForbidden = errString("forbidden")
+	ErrForbidden       = errString("forbidden")
+	ErrNotFound        = errString("post not found")
+	ErrInvalidCursor   = errString("invalid cursor")
+	ErrExpired         = errString("post is past the trash retention")
+	ErrVersionMismatch = errString("post was changed by someone else")
 )
 
 type errString string
@@ -4,6 +4,9 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
+
+	"majoo-case1-rest-api/internal/event"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 )
@@ -24,7 +27,7 @@ func TestUsecase_Update_Forbidden(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(999))
 
 	req := UpdatePostRequest{Title: stringPtr("New Title")}
-	_, err = uc.Update(1, 1, req)
+	_, err = uc.Update(1, 1, req, 0)
 	if err != ErrForbidden {
 		t.Errorf("expected ErrForbidden, got %v", err)
 	}
@@ -49,7 +52,7 @@ func TestUsecase_Update_NotFound(t *testing.T) {
 		WillReturnError(sql.ErrNoRows)
 
 	req := UpdatePostRequest{Title: stringPtr("New Title")}
-	_, err = uc.Update(1, 1, req)
+	_, err = uc.Update(1, 1, req, 0)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -58,6 +61,62 @@ func TestUsecase_Update_NotFound(t *testing.T) {
 	}
 }
 
+func TestUsecase_Update_HiddenPostNotAnnounced(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	bus := event.NewBus()
+	var got []event.Event
+	bus.Subscribe(event.PostUpdated, func(_ *sql.Tx, e event.Event) error {
+		got = append(got, e)
+		return nil
+	})
+	uc.SetEventBus(bus)
+
+	mock.ExpectQuery("SELECT user_id FROM posts").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("WITH old AS").
+		WithArgs(1, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	for i := 0; i < 11; i++ {
+		mock.ExpectExec("UPDATE").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectQuery("SELECT content, content_format FROM posts").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"content", "content_format"}).AddRow("held back", "plain"))
+	// the filter held the post when it was created
+	mock.ExpectQuery("SELECT hidden_at IS NOT NULL FROM posts").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"hidden"}).AddRow(true))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT p.id, p.user_id").
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "slug", "title", "content", "content_format", "content_html", "created_at", "updated_at", "version", "author"}).
+			AddRow(2, 1, "held", "Held", "held back", "plain", "<p>held back</p>", time.Now(), time.Now(), 2, "johndoe"))
+	mock.ExpectQuery("SELECT pt.post_id, t.slug FROM post_tags").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "slug"}))
+	mock.ExpectQuery("FROM post_mentions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "user_id", "username", "start_offset", "length"}))
+	mock.ExpectQuery("FROM post_reactions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "kind", "count", "mine"}))
+
+	if _, err := uc.Update(1, 1, UpdatePostRequest{}, 0); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no event for a hidden post, got %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
 func TestUsecase_Delete_Forbidden(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -73,7 +132,7 @@ func TestUsecase_Delete_Forbidden(t *testing.T) {
 		WithArgs(1).
 		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(999))
 
-	err = uc.Delete(1, 1)
+	err = uc.Delete(1, 1, 0)
 	if err != ErrForbidden {
 		t.Errorf("expected ErrForbidden, got %v", err)
 	}
@@ -97,7 +156,7 @@ func TestUsecase_Delete_NotFound(t *testing.T) {
 		WithArgs(1).
 		WillReturnError(sql.ErrNoRows)
 
-	err = uc.Delete(1, 1)
+	err = uc.Delete(1, 1, 0)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -106,6 +165,32 @@ func TestUsecase_Delete_NotFound(t *testing.T) {
 	}
 }
 
+func TestUsecase_Delete_VersionMismatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	mock.ExpectQuery("SELECT user_id FROM posts").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT version FROM posts WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+	mock.ExpectRollback()
+
+	if err := uc.Delete(1, 1, 2); err != ErrVersionMismatch {
+		t.Errorf("expected ErrVersionMismatch, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
 func TestUsecase_Create_TransactionError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -141,10 +226,10 @@ func TestUsecase_Get_NotFound(t *testing.T) {
 
 	// Mock: post not found
 	mock.ExpectQuery("SELECT p.id, p.user_id").
-		WithArgs(1).
+		WithArgs(1, 0).
 		WillReturnError(sql.ErrNoRows)
 
-	_, err = uc.Get(1)
+	_, err = uc.Get(0, 1)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -153,7 +238,305 @@ func TestUsecase_Get_NotFound(t *testing.T) {
 	}
 }
 
+func TestUsecase_List_LoadsTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+	uc := NewUsecase(db, repo)
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT p.id, p.user_id").
+		WithArgs(10, 0, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "slug", "title", "content", "content_format", "content_html", "created_at", "updated_at", "version", "author"}).
+			AddRow(2, 1, "second", "Second", "b", "plain", "<p>b</p>", now, now, 1, "johndoe").
+			AddRow(1, 1, "first", "First", "a", "plain", "", now, now, 1, "johndoe"))
+	mock.ExpectQuery("SELECT pt.post_id, t.slug FROM post_tags").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "slug"}).
+			AddRow(1, "go").
+			AddRow(1, "sql"))
+	mock.ExpectQuery("FROM post_mentions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "user_id", "username", "start_offset", "length"}))
+	mock.ExpectQuery("FROM post_reactions").
+		WithArgs(sqlmock.AnyArg(), 0).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "kind", "count", "mine"}))
+
+	posts, err := uc.List(0, 1, 10, ListFilter{Tags: []string{"go"}})
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(posts) != 2 {
+		t.Fatalf("expected 2 posts, got %d", len(posts))
+	}
+	if len(posts[0].Tags) != 0 || posts[0].Tags == nil {
+		t.Errorf("expected empty non-nil tags for post 2, got %#v", posts[0].Tags)
+	}
+	if len(posts[1].Tags) != 2 {
+		t.Errorf("expected 2 tags for post 1, got %v", posts[1].Tags)
+	}
+	if posts[1].ContentHTML != "<p>a</p>\n" {
+		t.Errorf("expected legacy row to be rendered on read, got %q", posts[1].ContentHTML)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Get_LoadsReactions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT p.id, p.user_id").
+		WithArgs(1, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "slug", "title", "content", "content_format", "content_html", "created_at", "updated_at", "version", "author"}).
+			AddRow(1, 1, "first", "First", "a", "plain", "<p>a</p>", now, now, 1, "johndoe"))
+	mock.ExpectQuery("SELECT pt.post_id, t.slug FROM post_tags").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "slug"}))
+	mock.ExpectQuery("FROM post_mentions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "user_id", "username", "start_offset", "length"}))
+	mock.ExpectQuery("FROM post_reactions").
+		WithArgs(sqlmock.AnyArg(), 5).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "kind", "count", "mine"}).
+			AddRow(1, "laugh", 1, false).
+			AddRow(1, "like", 3, true))
+
+	p, err := uc.Get(5, 1)
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if p.Counts["like"] != 3 || p.Counts["laugh"] != 1 {
+		t.Errorf("unexpected counts %v", p.Counts)
+	}
+	if len(p.Mine) != 1 || p.Mine[0] != "like" {
+		t.Errorf("expected my_reactions [like], got %v", p.Mine)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Create_InvalidTag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	req := CreatePostRequest{Title: "Test", Content: "Content", Tags: []string{"!!!"}}
+	if _, err := uc.Create(1, req); err == nil {
+		t.Error("expected error, got nil")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Create_DeduplicatesSlug(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO posts").
+		WithArgs(1, "Hello World", "Content", "plain", "<p>Content</p>\n").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec("pg_advisory_xact_lock").
+		WithArgs("hello-world").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT slug, post_id FROM post_slugs").
+		WithArgs("hello-world", "hello-world-%").
+		WillReturnRows(sqlmock.NewRows([]string{"slug", "post_id"}).
+			AddRow("hello-world", 3).
+			AddRow("hello-world-2", 5))
+	mock.ExpectExec("UPDATE posts SET slug").
+		WithArgs(7, "hello-world-3").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO post_slugs").
+		WithArgs("hello-world-3", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT p.id, p.user_id").
+		WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "slug", "title", "content", "content_format", "content_html", "created_at", "updated_at", "version", "author"}).
+			AddRow(7, 1, "hello-world-3", "Hello World", "Content", "plain", "<p>Content</p>\n", now, now, 1, "johndoe"))
+	mock.ExpectQuery("SELECT pt.post_id, t.slug FROM post_tags").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "slug"}))
+	mock.ExpectQuery("FROM post_mentions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "user_id", "username", "start_offset", "length"}))
+	mock.ExpectQuery("FROM post_reactions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "kind", "count", "mine"}))
+
+	p, err := uc.Create(1, CreatePostRequest{Title: "Hello World", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	if p.Slug != "hello-world-3" {
+		t.Errorf("expected slug hello-world-3, got %q", p.Slug)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_GetBySlug_OldSlug(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	mock.ExpectQuery("SELECT p.id, COALESCE\\(p.slug, ''\\) FROM post_slugs").
+		WithArgs("old-title").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).AddRow(4, "new-title"))
+
+	_, current, err := uc.GetBySlug(0, "old-title")
+	if err != nil {
+		t.Fatalf("GetBySlug error: %v", err)
+	}
+	if current != "new-title" {
+		t.Errorf("expected current slug new-title, got %q", current)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
 
+
+func TestUsecase_HomeFeed_KeysetCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	at := time.Date(2024, 1, 1, 12, 0, 0, 123456000, time.UTC)
+	cols := []string{"id", "user_id", "slug", "title", "content", "content_format", "content_html", "created_at", "updated_at", "version", "author"}
+	mock.ExpectQuery("FROM follows WHERE follower_id").
+		WithArgs(1, nil, 0, 2).
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow(9, 2, "b", "B", "b", "plain", "<p>b</p>", at.Add(time.Hour), at, 1, "jane").
+			AddRow(8, 3, "a", "A", "a", "plain", "<p>a</p>", at, at, 1, "joe"))
+	mock.ExpectQuery("SELECT pt.post_id, t.slug FROM post_tags").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "slug"}))
+	mock.ExpectQuery("FROM post_mentions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "user_id", "username", "start_offset", "length"}))
+	mock.ExpectQuery("FROM post_reactions").
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "kind", "count", "mine"}))
+
+	page, err := uc.HomeFeed(1, "", 1)
+	if err != nil {
+		t.Fatalf("HomeFeed error: %v", err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].ID != 9 || page.NextCursor == "" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+
+	mock.ExpectQuery("FROM follows WHERE follower_id").
+		WithArgs(1, at.Add(time.Hour), 9, 2).
+		WillReturnRows(sqlmock.NewRows(cols))
+
+	page, err = uc.HomeFeed(1, page.NextCursor, 1)
+	if err != nil {
+		t.Fatalf("HomeFeed error: %v", err)
+	}
+	if len(page.Posts) != 0 || page.NextCursor != "" {
+		t.Errorf("expected empty last page, got %+v", page)
+	}
+	if _, err := uc.HomeFeed(1, "%%%", 1); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_UpdateSettings_Forbidden(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+
+	mock.ExpectQuery("SELECT user_id FROM posts").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(999))
+
+	locked := true
+	_, err = uc.UpdateSettings(1, 1, UpdateSettingsRequest{CommentsLocked: &locked})
+	if err != ErrForbidden {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Restore_AnnouncesAsCreated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db))
+	uc.SetTrashRetention(time.Hour)
+	bus := event.NewBus()
+	var got []event.Event
+	bus.Subscribe(event.PostCreated, func(_ *sql.Tx, e event.Event) error {
+		got = append(got, e)
+		return nil
+	})
+	uc.SetEventBus(bus)
+
+	mock.ExpectQuery("SELECT user_id, deleted_at").
+		WithArgs(3, float64(3600)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "expired"}).AddRow(1, false))
+	mock.ExpectBegin()
+	for i := 0; i < 5; i++ {
+		mock.ExpectExec("UPDATE").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectQuery("SELECT hidden_at IS NOT NULL FROM posts").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"hidden"}).AddRow(false))
+	mock.ExpectQuery("SELECT content, content_format FROM posts").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"content", "content_format"}).AddRow("back", "plain"))
+	mock.ExpectCommit()
+
+	if err := uc.Restore(1, 3); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	want := event.Event{Type: event.PostCreated, ActorID: 1, PostID: 3, Content: "back", Restored: true}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
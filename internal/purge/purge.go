@@ -0,0 +1,114 @@
+// Package purge hard-deletes posts and comments that were soft-deleted, or
+// replaced by an edit, longer ago than the retention period. It runs on a
+// schedule inside the server and as the purge command.
+package purge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"majoo-case1-rest-api/config"
+)
+
+// Report is what a purge removed or, in a dry run, would remove, including
+// the comments, reactions and attachments that went with purged posts.
+type Report struct {
+	DryRun      bool
+	Posts       int64
+	Comments    int64
+	Reactions   int64
+	Attachments int64
+}
+
+func (r *Report) add(o Report) {
+	r.Posts += o.Posts
+	r.Comments += o.Comments
+	r.Reactions += o.Reactions
+	r.Attachments += o.Attachments
+}
+
+// String lists the counts, as in "2 posts, 5 comments, 9 reactions and 1
+// attachments".
+func (r Report) String() string {
+	return fmt.Sprintf("%d posts, %d comments, %d reactions and %d attachments", r.Posts, r.Comments, r.Reactions, r.Attachments)
+}
+
+type Purger struct {
+	repo      *Repository
+	retention time.Duration
+	batch     int
+	interval  time.Duration
+}
+
+func NewPurger(repo *Repository, cfg config.Config) *Purger {
+	batch := cfg.PurgeBatchSize
+	if batch < 1 {
+		batch = 500
+	}
+	return &Purger{repo: repo, retention: cfg.PurgeRetention, batch: batch, interval: cfg.PurgeInterval}
+}
+
+// Run purges every expired row, one batch per statement so that no lock is
+// held for long. A dry run only counts them.
+func (p *Purger) Run(ctx context.Context, dryRun bool) (Report, error) {
+	rep := Report{DryRun: dryRun}
+	for _, table := range tables {
+		n, err := p.table(ctx, table, dryRun)
+		rep.add(n)
+		if err != nil {
+			return rep, err
+		}
+	}
+	return rep, nil
+}
+
+func (p *Purger) table(ctx context.Context, table string, dryRun bool) (Report, error) {
+	if dryRun {
+		return p.repo.Count(table, p.retention)
+	}
+	var total Report
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := p.repo.DeleteBatch(table, p.retention, p.batch)
+		total.add(n)
+		// a batch's own rows are its posts or its comments, not the
+		// comments that went with its posts
+		own := n.Comments
+		if table == "posts" {
+			own = n.Posts
+		}
+		if err != nil || own < int64(p.batch) {
+			return total, err
+		}
+	}
+}
+
+// Start purges every interval until ctx is done; a zero interval leaves
+// purging to the purge command.
+func (p *Purger) Start(ctx context.Context) {
+	if p.interval <= 0 {
+		return
+	}
+	go func() {
+		t := time.NewTicker(p.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				rep, err := p.Run(ctx, false)
+				if err != nil {
+					log.Printf("purge: %v", err)
+				}
+				if rep.Posts > 0 || rep.Comments > 0 {
+					log.Printf("purge: removed %s", rep)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,67 @@
+package purge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"majoo-case1-rest-api/config"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPurger_Run_Batches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	p := NewPurger(NewRepository(db), config.Config{PurgeRetention: time.Hour, PurgeBatchSize: 2})
+
+	// a full batch means there may be more; a short one ends the table
+	mock.ExpectQuery("DELETE FROM comments").WithArgs(float64(3600), 2).WillReturnRows(tallyRow(0, 2, 1, 0))
+	mock.ExpectQuery("DELETE FROM comments").WithArgs(float64(3600), 2).WillReturnRows(tallyRow(0, 1, 0, 0))
+	// the comments going with a short batch of posts do not make it full
+	mock.ExpectQuery("DELETE FROM posts").WithArgs(float64(3600), 2).WillReturnRows(tallyRow(1, 5, 0, 0))
+
+	rep, err := p.Run(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if rep.Comments != 8 || rep.Reactions != 1 || rep.Posts != 1 || rep.DryRun {
+		t.Errorf("unexpected report %+v", rep)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestPurger_Run_DryRunOnlyCounts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	p := NewPurger(NewRepository(db), config.Config{PurgeRetention: time.Hour})
+
+	// the post's comments and attachment go with it
+	mock.ExpectQuery("SELECT id FROM comments WHERE deleted_at").WithArgs(float64(3600)).WillReturnRows(tallyRow(0, 4, 0, 0))
+	mock.ExpectQuery("SELECT id FROM posts WHERE deleted_at").WithArgs(float64(3600)).WillReturnRows(tallyRow(1, 2, 3, 1))
+
+	rep, err := p.Run(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if rep.Comments != 6 || rep.Posts != 1 || rep.Reactions != 3 || rep.Attachments != 1 || !rep.DryRun {
+		t.Errorf("unexpected report %+v", rep)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func tallyRow(posts, comments, reactions, attachments int64) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"posts", "comments", "reactions", "attachments"}).AddRow(posts, comments, reactions, attachments)
+}
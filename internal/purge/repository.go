@@ -0,0 +1,77 @@
+package purge
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+// expired matches rows soft-deleted, or superseded by an edit, longer than
+// $1 seconds ago.
+const expired = "deleted_at <= CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'"
+
+// Each statement starts from "doomed", the rows of a table being purged.
+// Rows referencing them go through their foreign keys: a post takes its
+// remaining comments, tags, reactions and so on with it, and replies to a
+// purged comment lose their parent. A post's "orphans" are the comments that
+// go with it without being past retention themselves.
+var extra = map[string]string{
+	"comments": "",
+	"posts":    `, orphans AS (SELECT id FROM comments WHERE post_id IN (SELECT id FROM doomed) AND NOT (` + expired + `))`,
+}
+
+// reports delete the reports filed against the doomed rows, which have no
+// foreign key to do it.
+var reports = map[string]string{
+	"comments": `, reports AS (
+                     DELETE FROM reports WHERE target = 'comment' AND target_id IN (SELECT id FROM doomed)
+                 )`,
+	"posts": `, reports AS (
+                  DELETE FROM reports WHERE (target = 'post' AND target_id IN (SELECT id FROM doomed))
+                     OR (target = 'comment' AND target_id IN (SELECT id FROM comments WHERE post_id IN (SELECT id FROM doomed)))
+              )`,
+}
+
+// tallies count what purging the doomed rows removes, as posts, comments,
+// reactions and attachments. Every part of a statement sees the rows as they
+// were before it, so the rows the foreign keys remove are still counted.
+var tallies = map[string]string{
+	"comments": `SELECT 0, (SELECT COUNT(*) FROM doomed),
+                        (SELECT COUNT(*) FROM comment_reactions WHERE comment_id IN (SELECT id FROM doomed)), 0`,
+	"posts": `SELECT (SELECT COUNT(*) FROM doomed), (SELECT COUNT(*) FROM orphans),
+                     (SELECT COUNT(*) FROM post_reactions WHERE post_id IN (SELECT id FROM doomed))
+                     + (SELECT COUNT(*) FROM comment_reactions WHERE comment_id IN (SELECT id FROM orphans)),
+                     (SELECT COUNT(*) FROM attachments WHERE post_id IN (SELECT id FROM doomed))`,
+}
+
+// tables lists what is purged, in order: comments go before posts so that
+// the comments of a purged post are counted as such.
+var tables = []string{"comments", "posts"}
+
+// Count returns what purging table would remove.
+func (r *Repository) Count(table string, retention time.Duration) (Report, error) {
+	q := `WITH doomed AS (SELECT id FROM ` + table + ` WHERE ` + expired + `)` + extra[table] + "\n" + tallies[table]
+	return r.tally(r.db.QueryRow(q, retention.Seconds()))
+}
+
+// DeleteBatch hard-deletes up to limit rows of table past retention and
+// returns what went with them. SKIP LOCKED lets instances purge side by side
+// without waiting on each other.
+func (r *Repository) DeleteBatch(table string, retention time.Duration, limit int) (Report, error) {
+	q := `WITH doomed AS (
+              SELECT id FROM ` + table + ` WHERE ` + expired + ` ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED
+          )` + extra[table] + reports[table] + `, gone AS (
+              DELETE FROM ` + table + ` WHERE id IN (SELECT id FROM doomed)
+          )
+          ` + tallies[table]
+	return r.tally(r.db.QueryRow(q, retention.Seconds(), limit))
+}
+
+func (r *Repository) tally(row *sql.Row) (Report, error) {
+	var rep Report
+	err := row.Scan(&rep.Posts, &rep.Comments, &rep.Reactions, &rep.Attachments)
+	return rep, err
+}
@@ -0,0 +1,21 @@
+package reaction
+
+// Target is the kind of content a reaction is attached to.
+type Target string
+
+const (
+	Post    Target = "post"
+	Comment Target = "comment"
+)
+
+// Summary aggregates the reactions on one post or comment. It is embedded in
+// the post and comment payloads.
+type Summary struct {
+	Counts map[string]int `json:"reactions"`
+	Mine   []string       `json:"my_reactions"`
+}
+
+// Empty returns a Summary with non-nil fields, so it encodes as {} and [].
+func Empty() Summary {
+	return Summary{Counts: map[string]int{}, Mine: []string{}}
+}
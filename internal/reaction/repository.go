@@ -0,0 +1,91 @@
+package reaction
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+// tables maps a target to its reaction table, the table's target column,
+// the table holding the target itself and the condition for a target anyone
+// may react to.
+var tables = map[Target][4]string{
+	Post:    {"post_reactions", "post_id", "posts", "deleted_at IS NULL AND hidden_at IS NULL"},
+	Comment: {"comment_reactions", "comment_id", "comments", "deleted_at IS NULL AND hidden_at IS NULL AND status = 'approved'"},
+}
+
+func (r *Repository) TargetExists(t Target, id int) (bool, error) {
+	tbl, ok := tables[t]
+	if !ok {
+		return false, fmt.Errorf("reaction: unknown target %q", t)
+	}
+	var exists bool
+	q := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE id=$1 AND %s)", tbl[2], tbl[3])
+	err := r.db.QueryRow(q, id).Scan(&exists)
+	return exists, err
+}
+
+// Add records the reaction; adding one the user already has is a no-op.
+func (r *Repository) Add(t Target, id, userID int, kind string) error {
+	tbl, ok := tables[t]
+	if !ok {
+		return fmt.Errorf("reaction: unknown target %q", t)
+	}
+	q := fmt.Sprintf("INSERT INTO %s (%s, user_id, kind) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING", tbl[0], tbl[1])
+	_, err := r.db.Exec(q, id, userID, kind)
+	return err
+}
+
+func (r *Repository) Remove(t Target, id, userID int, kind string) error {
+	tbl, ok := tables[t]
+	if !ok {
+		return fmt.Errorf("reaction: unknown target %q", t)
+	}
+	q := fmt.Sprintf("DELETE FROM %s WHERE %s=$1 AND user_id=$2 AND kind=$3", tbl[0], tbl[1])
+	_, err := r.db.Exec(q, id, userID, kind)
+	return err
+}
+
+// Summaries aggregates reactions on the given targets in one query. Targets
+// without reactions are absent from the result. viewerID 0 (anonymous)
+// matches no user, so Mine stays empty.
+func (r *Repository) Summaries(t Target, ids []int, viewerID int) (map[int]Summary, error) {
+	tbl, ok := tables[t]
+	if !ok {
+		return nil, fmt.Errorf("reaction: unknown target %q", t)
+	}
+	out := map[int]Summary{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	q := fmt.Sprintf(`SELECT %[2]s, kind, COUNT(*), BOOL_OR(user_id = $2) FROM %[1]s
+                      WHERE %[2]s = ANY($1) AND deleted_at IS NULL GROUP BY %[2]s, kind ORDER BY %[2]s, kind`, tbl[0], tbl[1])
+	rows, err := r.db.Query(q, pq.Array(ids), viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, n int
+		var kind string
+		var mine bool
+		if err := rows.Scan(&id, &kind, &n, &mine); err != nil {
+			return nil, err
+		}
+		s, ok := out[id]
+		if !ok {
+			s = Empty()
+		}
+		s.Counts[kind] = n
+		if mine {
+			s.Mine = append(s.Mine, kind)
+		}
+		out[id] = s
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,89 @@
+package reaction
+
+import "majoo-case1-rest-api/config"
+
+// Like is always an allowed kind, whatever else is configured.
+const Like = "like"
+
+type Usecase struct {
+	repo  *Repository
+	kinds []string
+}
+
+func NewUsecase(repo *Repository, cfg config.Config) *Usecase {
+	kinds := []string{Like}
+	for _, k := range cfg.ReactionKinds {
+		if k != Like {
+			kinds = append(kinds, k)
+		}
+	}
+	return &Usecase{repo: repo, kinds: kinds}
+}
+
+// Kinds lists the allowed reaction kinds, "like" first.
+func (u *Usecase) Kinds() []string { return u.kinds }
+
+func (u *Usecase) allowed(kind string) bool {
+	for _, k := range u.kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Add reacts to the post or comment with kind and returns its updated summary.
+func (u *Usecase) Add(t Target, id, userID int, kind string) (Summary, error) {
+	if err := u.check(t, id, kind); err != nil {
+		return Summary{}, err
+	}
+	if err := u.repo.Add(t, id, userID, kind); err != nil {
+		return Summary{}, err
+	}
+	return u.summary(t, id, userID)
+}
+
+// Remove withdraws the user's kind reaction, if any, and returns the updated summary.
+func (u *Usecase) Remove(t Target, id, userID int, kind string) (Summary, error) {
+	if err := u.check(t, id, kind); err != nil {
+		return Summary{}, err
+	}
+	if err := u.repo.Remove(t, id, userID, kind); err != nil {
+		return Summary{}, err
+	}
+	return u.summary(t, id, userID)
+}
+
+func (u *Usecase) check(t Target, id int, kind string) error {
+	if !u.allowed(kind) {
+		return ErrUnknownKind
+	}
+	exists, err := u.repo.TargetExists(t, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (u *Usecase) summary(t Target, id, userID int) (Summary, error) {
+	all, err := u.repo.Summaries(t, []int{id}, userID)
+	if err != nil {
+		return Summary{}, err
+	}
+	if s, ok := all[id]; ok {
+		return s, nil
+	}
+	return Empty(), nil
+}
+
+var (
+	ErrNotFound    = errString("not found")
+	ErrUnknownKind = errString("unknown reaction kind")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -0,0 +1,85 @@
+package reaction
+
+import (
+	"testing"
+
+	"majoo-case1-rest-api/config"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUsecase_Kinds_AlwaysIncludesLike(t *testing.T) {
+	uc := NewUsecase(nil, config.Config{ReactionKinds: []string{"🎉", "like", "sad"}})
+	got := uc.Kinds()
+	if len(got) != 3 || got[0] != "like" || got[1] != "🎉" || got[2] != "sad" {
+		t.Errorf("unexpected kinds %v", got)
+	}
+}
+
+func TestUsecase_Add_UnknownKind(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(NewRepository(db), config.Config{})
+	if _, err := uc.Add(Post, 1, 2, "love"); err != ErrUnknownKind {
+		t.Errorf("expected ErrUnknownKind, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Add_Comment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(NewRepository(db), config.Config{})
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM comments").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO comment_reactions \\(comment_id, user_id, kind\\) VALUES \\(\\$1,\\$2,\\$3\\) ON CONFLICT DO NOTHING").
+		WithArgs(4, 2, "like").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("FROM comment_reactions").
+		WithArgs(sqlmock.AnyArg(), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"comment_id", "kind", "count", "mine"}).AddRow(4, "like", 2, true))
+
+	s, err := uc.Add(Comment, 4, 2, "like")
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if s.Counts["like"] != 2 || len(s.Mine) != 1 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Remove_TargetGone(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(NewRepository(db), config.Config{})
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM posts").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	if _, err := uc.Remove(Post, 9, 2, "like"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+package report
+
+type CreateReportRequest struct {
+	Reason  string `json:"reason" binding:"required"`
+	Details string `json:"details" binding:"max=1000"`
+}
+
+type ResolveReportRequest struct {
+	Outcome string `json:"outcome" binding:"required"`
+	Note    string `json:"note" binding:"max=1000"`
+}
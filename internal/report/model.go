@@ -0,0 +1,68 @@
+package report
+
+import "time"
+
+// Target is the kind of content a report is about.
+type Target string
+
+const (
+	Post    Target = "post"
+	Comment Target = "comment"
+)
+
+// Reasons are the reason codes readers may report content for.
+var Reasons = []string{"spam", "harassment", "hate", "violence", "sexual", "misinformation", "other"}
+
+// ReasonFilter marks reports filed by a content filter rather than a reader.
+const ReasonFilter = "filter"
+
+const (
+	StatusOpen     = "open"
+	StatusResolved = "resolved"
+)
+
+// Outcomes a moderator may resolve reports with. A dismissed report makes
+// hidden content visible again; removed content stays hidden.
+const (
+	OutcomeDismissed = "dismissed"
+	OutcomeRemoved   = "removed"
+)
+
+type Reporter struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+// Report is one reader's flag on a post or comment; Reporter is nil when a
+// content filter filed it. Content is the target
+// as it stands now, so moderators can judge hidden content; it is nil once
+// the target has been deleted.
+type Report struct {
+	ID             int        `json:"id"`
+	Target         Target     `json:"target"`
+	TargetID       int        `json:"target_id"`
+	Reporter       *Reporter  `json:"reporter"`
+	Reason         string     `json:"reason"`
+	Details        string     `json:"details,omitempty"`
+	Status         string     `json:"status"`
+	Outcome        *string    `json:"outcome"`
+	ResolvedBy     *int       `json:"resolved_by"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Content        *Content   `json:"content"`
+}
+
+// Content is a snapshot of a reported post or comment.
+type Content struct {
+	AuthorID int    `json:"author_id"`
+	Text     string `json:"text"`
+	Hidden   bool   `json:"hidden"`
+}
+
+// Page is one page of reports, oldest first. NextCursor is empty on the
+// last page.
+type Page struct {
+	Reports    []Report `json:"reports"`
+	NextCursor string   `json:"next_cursor"`
+}
@@ -0,0 +1,162 @@
+package report
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+// tables maps a target to the table holding it and the condition for
+// content readers can see, and so report.
+var tables = map[Target][2]string{
+	Post:    {"posts", "deleted_at IS NULL AND hidden_at IS NULL"},
+	Comment: {"comments", "deleted_at IS NULL AND hidden_at IS NULL AND status = 'approved'"},
+}
+
+func (r *Repository) VisibleTx(tx *sql.Tx, t Target, id int) (bool, error) {
+	tbl, ok := tables[t]
+	if !ok {
+		return false, fmt.Errorf("report: unknown target %q", t)
+	}
+	var exists bool
+	q := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE id=$1 AND %s)", tbl[0], tbl[1])
+	err := tx.QueryRow(q, id).Scan(&exists)
+	return exists, err
+}
+
+// CreateTx stores a report and returns its id, or sql.ErrNoRows when the
+// reporter already has an open report on the target.
+func (r *Repository) CreateTx(tx *sql.Tx, t Target, targetID, reporterID int, reason, details string) (int, error) {
+	const q = `INSERT INTO reports (target, target_id, reporter_id, reason, details) VALUES ($1,$2,$3,$4,NULLIF($5, ''))
+               ON CONFLICT (target, target_id, reporter_id) WHERE status = 'open' DO NOTHING
+               RETURNING id`
+	var id int
+	err := tx.QueryRow(q, t, targetID, reporterID, reason, details).Scan(&id)
+	return id, err
+}
+
+// FlagTx hides the target and files a report for moderators on behalf of
+// a content filter.
+func (r *Repository) FlagTx(tx *sql.Tx, t Target, targetID int, details string) error {
+	const q = "INSERT INTO reports (target, target_id, reason, details) VALUES ($1,$2,$3,$4)"
+	if _, err := tx.Exec(q, t, targetID, ReasonFilter, details); err != nil {
+		return err
+	}
+	return r.SetHiddenTx(tx, t, targetID, true)
+}
+
+func (r *Repository) OpenCountTx(tx *sql.Tx, t Target, targetID int) (int, error) {
+	var n int
+	err := tx.QueryRow("SELECT COUNT(*) FROM reports WHERE target=$1 AND target_id=$2 AND status='open'", t, targetID).Scan(&n)
+	return n, err
+}
+
+// SetHiddenTx hides or reveals the target. Hiding keeps the time content was
+// first hidden.
+func (r *Repository) SetHiddenTx(tx *sql.Tx, t Target, id int, hidden bool) error {
+	tbl, ok := tables[t]
+	if !ok {
+		return fmt.Errorf("report: unknown target %q", t)
+	}
+	q := fmt.Sprintf("UPDATE %s SET hidden_at = NULL WHERE id=$1", tbl[0])
+	if hidden {
+		q = fmt.Sprintf("UPDATE %s SET hidden_at = COALESCE(hidden_at, CURRENT_TIMESTAMP) WHERE id=$1", tbl[0])
+	}
+	_, err := tx.Exec(q, id)
+	return err
+}
+
+// TargetTx locks a report and returns its target and status.
+func (r *Repository) TargetTx(tx *sql.Tx, id int) (Target, int, string, error) {
+	var t Target
+	var targetID int
+	var status string
+	err := tx.QueryRow("SELECT target, target_id, status FROM reports WHERE id=$1 FOR UPDATE", id).Scan(&t, &targetID, &status)
+	return t, targetID, status, err
+}
+
+// ResolveTx resolves every open report on the target with outcome.
+func (r *Repository) ResolveTx(tx *sql.Tx, t Target, targetID int, outcome string, moderatorID int, note string) error {
+	const q = `UPDATE reports SET status = 'resolved', outcome = $3, resolved_by = $4, resolved_at = CURRENT_TIMESTAMP, resolution_note = NULLIF($5, '')
+               WHERE target = $1 AND target_id = $2 AND status = 'open'`
+	_, err := tx.Exec(q, t, targetID, outcome, moderatorID, note)
+	return err
+}
+
+const selectReports = `SELECT r.id, r.target, r.target_id, r.reporter_id, u.username, r.reason, COALESCE(r.details, ''),
+                              r.status, r.outcome, r.resolved_by, r.resolved_at, COALESCE(r.resolution_note, ''), r.created_at,
+                              COALESCE(p.user_id, c.user_id), COALESCE(p.content, c.content), COALESCE(p.hidden_at, c.hidden_at) IS NOT NULL
+                       FROM reports r LEFT JOIN users u ON u.id = r.reporter_id
+                       LEFT JOIN posts p ON r.target = 'post' AND p.id = r.target_id AND p.deleted_at IS NULL
+                       LEFT JOIN comments c ON r.target = 'comment' AND c.id = r.target_id AND c.deleted_at IS NULL`
+
+// List returns up to limit reports in status with an id above afterID,
+// oldest first, restricted to target unless it is empty.
+func (r *Repository) List(status string, t Target, afterID, limit int) ([]Report, error) {
+	q := selectReports + " WHERE r.status = $1 AND r.id > $2"
+	args := []interface{}{status, afterID, limit}
+	if t != "" {
+		q += " AND r.target = $4"
+		args = append(args, t)
+	}
+	rows, err := r.db.Query(q+" ORDER BY r.id LIMIT $3", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Report
+	for rows.Next() {
+		rp, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rp)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) Get(id int) (Report, error) {
+	return scanReport(r.db.QueryRow(selectReports+" WHERE r.id = $1", id))
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReport(s scanner) (Report, error) {
+	var rp Report
+	var outcome sql.NullString
+	var resolvedBy sql.NullInt64
+	var resolvedAt sql.NullTime
+	var authorID sql.NullInt64
+	var text sql.NullString
+	var hidden bool
+	var reporterID sql.NullInt64
+	var reporterName sql.NullString
+	err := s.Scan(&rp.ID, &rp.Target, &rp.TargetID, &reporterID, &reporterName, &rp.Reason, &rp.Details,
+		&rp.Status, &outcome, &resolvedBy, &resolvedAt, &rp.ResolutionNote, &rp.CreatedAt,
+		&authorID, &text, &hidden)
+	if err != nil {
+		return Report{}, err
+	}
+	if reporterID.Valid {
+		rp.Reporter = &Reporter{ID: int(reporterID.Int64), Username: reporterName.String}
+	}
+	if outcome.Valid {
+		rp.Outcome = &outcome.String
+	}
+	if resolvedBy.Valid {
+		id := int(resolvedBy.Int64)
+		rp.ResolvedBy = &id
+	}
+	if resolvedAt.Valid {
+		rp.ResolvedAt = &resolvedAt.Time
+	}
+	if authorID.Valid {
+		rp.Content = &Content{AuthorID: int(authorID.Int64), Text: text.String, Hidden: hidden}
+	}
+	return rp, nil
+}
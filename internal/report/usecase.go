@@ -0,0 +1,185 @@
+package report
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"strconv"
+
+	"majoo-case1-rest-api/config"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type Usecase struct {
+	db        *sql.DB
+	repo      *Repository
+	threshold int
+}
+
+func NewUsecase(db *sql.DB, repo *Repository, cfg config.Config) *Usecase {
+	return &Usecase{db: db, repo: repo, threshold: cfg.ReportHideThreshold}
+}
+
+// Report flags a post or comment for moderators. Content is hidden once it
+// has as many open reports as the configured threshold.
+func (u *Usecase) Report(reporterID int, t Target, targetID int, req CreateReportRequest) (Report, error) {
+	if !validReason(req.Reason) {
+		return Report{}, ErrUnknownReason
+	}
+	tx, err := u.db.Begin()
+	if err != nil {
+		return Report{}, err
+	}
+	defer tx.Rollback()
+	ok, err := u.repo.VisibleTx(tx, t, targetID)
+	if err != nil {
+		return Report{}, err
+	}
+	if !ok {
+		return Report{}, ErrNotFound
+	}
+	id, err := u.repo.CreateTx(tx, t, targetID, reporterID, req.Reason, req.Details)
+	if err == sql.ErrNoRows {
+		return Report{}, ErrDuplicate
+	}
+	if err != nil {
+		return Report{}, err
+	}
+	if u.threshold > 0 {
+		n, err := u.repo.OpenCountTx(tx, t, targetID)
+		if err != nil {
+			return Report{}, err
+		}
+		if n >= u.threshold {
+			if err := u.repo.SetHiddenTx(tx, t, targetID, true); err != nil {
+				return Report{}, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return Report{}, err
+	}
+	return u.repo.Get(id)
+}
+
+func validReason(reason string) bool {
+	for _, r := range Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns a page of reports in status, open by default, oldest first,
+// following cursor, which is empty for the first page.
+func (u *Usecase) List(status string, t Target, cursor string, limit int) (Page, error) {
+	if status == "" {
+		status = StatusOpen
+	}
+	if status != StatusOpen && status != StatusResolved {
+		return Page{}, ErrInvalidStatus
+	}
+	if _, ok := tables[t]; t != "" && !ok {
+		return Page{}, ErrUnknownTarget
+	}
+	if limit < 1 || limit > maxLimit {
+		limit = defaultLimit
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return Page{}, err
+	}
+	reports, err := u.repo.List(status, t, after, limit+1)
+	if err != nil {
+		return Page{}, err
+	}
+	page := Page{Reports: reports}
+	if page.Reports == nil {
+		page.Reports = []Report{}
+	}
+	if len(page.Reports) > limit {
+		page.Reports = page.Reports[:limit]
+		page.NextCursor = encodeCursor(page.Reports[limit-1].ID)
+	}
+	return page, nil
+}
+
+func (u *Usecase) Get(id int) (Report, error) {
+	rp, err := u.repo.Get(id)
+	if err == sql.ErrNoRows {
+		return Report{}, ErrNotFound
+	}
+	return rp, err
+}
+
+// Resolve records a moderator's decision on the content a report is about.
+// The decision covers every open report on that content: dismissing them
+// makes hidden content visible again, removing it hides it for good.
+func (u *Usecase) Resolve(moderatorID, id int, req ResolveReportRequest) (Report, error) {
+	if req.Outcome != OutcomeDismissed && req.Outcome != OutcomeRemoved {
+		return Report{}, ErrUnknownOutcome
+	}
+	tx, err := u.db.Begin()
+	if err != nil {
+		return Report{}, err
+	}
+	defer tx.Rollback()
+	t, targetID, status, err := u.repo.TargetTx(tx, id)
+	if err == sql.ErrNoRows {
+		return Report{}, ErrNotFound
+	}
+	if err != nil {
+		return Report{}, err
+	}
+	if status != StatusOpen {
+		return Report{}, ErrResolved
+	}
+	if err := u.repo.ResolveTx(tx, t, targetID, req.Outcome, moderatorID, req.Note); err != nil {
+		return Report{}, err
+	}
+	if err := u.repo.SetHiddenTx(tx, t, targetID, req.Outcome == OutcomeRemoved); err != nil {
+		return Report{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Report{}, err
+	}
+	return u.repo.Get(id)
+}
+
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeCursor(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(string(b))
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+var (
+	ErrNotFound       = errString("not found")
+	ErrDuplicate      = errString("already reported")
+	ErrUnknownReason  = errString("unknown reason")
+	ErrUnknownTarget  = errString("unknown target")
+	ErrUnknownOutcome = errString("unknown outcome")
+	ErrInvalidStatus  = errString("invalid status")
+	ErrInvalidCursor  = errString("invalid cursor")
+	ErrResolved       = errString("report already resolved")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -0,0 +1,148 @@
+package report
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"majoo-case1-rest-api/config"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+var reportColumns = []string{"id", "target", "target_id", "reporter_id", "username", "reason", "details", "status", "outcome",
+	"resolved_by", "resolved_at", "resolution_note", "created_at", "author_id", "content", "hidden"}
+
+func TestUsecase_Report_HidesAtThreshold(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db), config.Config{ReportHideThreshold: 2})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT 1 FROM comments WHERE id=$1 AND deleted_at IS NULL AND hidden_at IS NULL AND status = 'approved')")).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("INSERT INTO reports").
+		WithArgs(Comment, 7, 3, "spam", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	mock.ExpectQuery("SELECT COUNT").
+		WithArgs(Comment, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE comments SET hidden_at = COALESCE(hidden_at, CURRENT_TIMESTAMP) WHERE id=$1")).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT r.id").
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows(reportColumns).
+			AddRow(11, "comment", 7, 3, "carol", "spam", "", "open", nil, nil, nil, "", time.Now(), 2, "buy now", true))
+
+	rp, err := uc.Report(3, Comment, 7, CreateReportRequest{Reason: "spam"})
+	if err != nil {
+		t.Fatalf("Report error: %v", err)
+	}
+	if rp.Content == nil || !rp.Content.Hidden {
+		t.Errorf("expected hidden content, got %+v", rp.Content)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Report_Duplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db), config.Config{ReportHideThreshold: 2})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("INSERT INTO reports").
+		WithArgs(Post, 7, 3, "other", "").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	if _, err := uc.Report(3, Post, 7, CreateReportRequest{Reason: "other"}); err != ErrDuplicate {
+		t.Errorf("expected ErrDuplicate, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Report_UnknownReason(t *testing.T) {
+	uc := NewUsecase(nil, nil, config.Config{})
+	if _, err := uc.Report(3, Post, 7, CreateReportRequest{Reason: "boring"}); err != ErrUnknownReason {
+		t.Errorf("expected ErrUnknownReason, got %v", err)
+	}
+}
+
+func TestUsecase_Resolve_DismissRevealsContent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db), config.Config{})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT target, target_id, status FROM reports").
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"target", "target_id", "status"}).AddRow("post", 7, "open"))
+	mock.ExpectExec("UPDATE reports SET status = 'resolved'").
+		WithArgs(Post, 7, OutcomeDismissed, 5, "fine").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE posts SET hidden_at = NULL WHERE id=$1")).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT r.id").
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows(reportColumns).
+			AddRow(11, "post", 7, 3, "carol", "spam", "", "resolved", "dismissed", 5, time.Now(), "fine", time.Now(), 2, "hello", false))
+
+	rp, err := uc.Resolve(5, 11, ResolveReportRequest{Outcome: OutcomeDismissed, Note: "fine"})
+	if err != nil {
+		t.Fatalf("Resolve error: %v", err)
+	}
+	if rp.Outcome == nil || *rp.Outcome != OutcomeDismissed {
+		t.Errorf("expected dismissed outcome, got %v", rp.Outcome)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_Resolve_AlreadyResolved(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(db, NewRepository(db), config.Config{})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT target, target_id, status FROM reports").
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"target", "target_id", "status"}).AddRow("post", 7, "resolved"))
+	mock.ExpectRollback()
+
+	if _, err := uc.Resolve(5, 11, ResolveReportRequest{Outcome: OutcomeRemoved}); err != ErrResolved {
+		t.Errorf("expected ErrResolved, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
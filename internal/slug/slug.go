@@ -0,0 +1,76 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// letters that do not decompose into an ASCII base letter under NFD.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "h", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "sch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Make converts s into a lowercase ASCII slug of at most max bytes, e.g.
+// "Héllo, Wörld!" becomes "hello-world". The result is empty when s contains
+// no letters or digits.
+func Make(s string, max int) string {
+	return build(s, max, false)
+}
+
+// Unicode is Make for slugs that may hold any script: letters Make can spell
+// in ASCII are spelled the same way, but other letters and digits, such as
+// CJK, Greek or Arabic ones, are kept lowercased instead of dropped, e.g.
+// "Café 東京" becomes "cafe-東京". max still counts bytes.
+func Unicode(s string, max int) string {
+	return build(s, max, true)
+}
+
+func build(s string, max int, keepUnicode bool) string {
+	var b strings.Builder
+	dash := false
+	// marks are accents to fold away after an ASCII or transliterated letter,
+	// and part of the letter otherwise
+	keepMarks := false
+	for _, r := range strings.ToLower(norm.NFD.String(s)) {
+		part, spelled := transliterations[r]
+		switch {
+		case unicode.Is(unicode.M, r):
+			if keepMarks {
+				b.WriteRune(r)
+			}
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			part, keepMarks = string(r), false
+		case spelled:
+			keepMarks = false
+		case keepUnicode && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			part, keepMarks = string(r), true
+		default:
+			dash, keepMarks = b.Len() > 0, false
+			continue
+		}
+		if dash && part != "" {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(part)
+	}
+	out := norm.NFC.String(b.String())
+	if max > 0 && len(out) > max {
+		for max > 0 && !utf8.RuneStart(out[max]) {
+			max--
+		}
+		out = out[:max]
+		if i := strings.LastIndexByte(out, '-'); i > 0 {
+			out = out[:i]
+		}
+	}
+	return strings.Trim(out, "-")
+}
@@ -0,0 +1,49 @@
+package slug
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	cases := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"Hello World", 0, "hello-world"},
+		{"  Go  ", 0, "go"},
+		{"Héllo, Wörld!", 0, "hello-world"},
+		{"Straße & Smørrebrød", 0, "strasse-smorrebrod"},
+		{"Привет мир", 0, "privet-mir"},
+		{"C++ / Go --- SQL", 0, "c-go-sql"},
+		{"!!!", 0, ""},
+		{"one two three four", 12, "one-two"},
+		{"abcdefghij", 5, "abcde"},
+	}
+	for _, tc := range cases {
+		if got := Make(tc.in, tc.max); got != tc.want {
+			t.Errorf("Make(%q, %d) = %q, want %q", tc.in, tc.max, got, tc.want)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	cases := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"Héllo, Wörld!", 0, "hello-world"},
+		{"Привет мир", 0, "privet-mir"},
+		{"東京 タワー", 0, "東京-タワー"},
+		{"がぎ", 0, "がぎ"},
+		{"Ελληνικά", 0, "ελληνικά"},
+		{"برمجة", 0, "برمجة"},
+		{"हिन्दी", 0, "हिन्दी"},
+		{"!!!", 0, ""},
+		{"日本語", 7, "日本"},
+	}
+	for _, tc := range cases {
+		if got := Unicode(tc.in, tc.max); got != tc.want {
+			t.Errorf("Unicode(%q, %d) = %q, want %q", tc.in, tc.max, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocal_PutGetDelete(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), "http://example.test/files", []byte("secret"))
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	ctx := context.Background()
+	if err := l.Put(ctx, "2024/01/x.png", strings.NewReader("data"), 4, "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	rc, err := l.Get(ctx, "2024/01/x.png")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "data" {
+		t.Errorf("unexpected content %q", b)
+	}
+	if err := l.Delete(ctx, "2024/01/x.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := l.Get(ctx, "2024/01/x.png"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLocal_RejectsTraversal(t *testing.T) {
+	l, _ := NewLocal(t.TempDir(), "http://example.test/files", []byte("secret"))
+	for _, key := range []string{"../etc/passwd", "a/../../b", "/abs", ""} {
+		if err := l.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain"); err != ErrInvalidKey {
+			t.Errorf("Put(%q): expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+}
+
+func TestLocal_SignedURL(t *testing.T) {
+	l, _ := NewLocal(t.TempDir(), "http://example.test/files", []byte("secret"))
+	now := time.Now()
+	raw, err := l.SignedURL("a/b.png", now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	u, _ := url.Parse(raw)
+	if u.Path != "/files/a/b.png" {
+		t.Errorf("unexpected path %q", u.Path)
+	}
+	exp, sig := u.Query().Get("expires"), u.Query().Get("signature")
+	if err := l.Verify("a/b.png", exp, sig, now); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if err := l.Verify("a/c.png", exp, sig, now); err != ErrBadSignature {
+		t.Errorf("expected ErrBadSignature for another key, got %v", err)
+	}
+	if err := l.Verify("a/b.png", exp, sig, now.Add(2*time.Minute)); err != ErrBadSignature {
+		t.Errorf("expected ErrBadSignature once expired, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"majoo-case1-rest-api/config"
+)
+
+// Storage keeps uploaded blobs under opaque, slash-separated keys.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that grants read access to key until expires.
+	SignedURL(key string, expires time.Time) (string, error)
+}
+
+// New builds the backend selected by STORAGE_BACKEND.
+func New(cfg config.Config) (Storage, error) {
+	switch cfg.StorageBackend {
+	case "", "local":
+		return NewLocal(cfg.StorageLocalDir, cfg.PublicBaseURL+"/api/v1/files", []byte(cfg.JWTSecret))
+	case "s3":
+		return NewS3(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+	}
+	return nil, fmt.Errorf("storage: unknown backend %q", cfg.StorageBackend)
+}
+
+var (
+	ErrNotFound     = errString("not_found")
+	ErrInvalidKey   = errString("invalid_key")
+	ErrBadSignature = errString("bad_signature")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -0,0 +1,38 @@
+package stream
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"majoo-case1-rest-api/internal/event"
+)
+
+// Subscribe stores the events real-time clients care about as they happen,
+// inside the producer's transaction.
+func Subscribe(bus *event.Bus, repo *Repository) {
+	for _, t := range []event.Type{event.CommentCreated, event.CommentUpdated, event.CommentDeleted} {
+		typ := string(t)
+		bus.Subscribe(t, func(tx *sql.Tx, e event.Event) error {
+			return repo.AppendCommentTx(tx, typ, e.PostID, e.CommentID, e.PreviousID)
+		})
+	}
+	// a new post has no followers yet, but one restored from the trash may
+	bus.Subscribe(event.PostCreated, func(tx *sql.Tx, e event.Event) error {
+		if !e.Restored {
+			return nil
+		}
+		payload, _ := json.Marshal(map[string]int{"id": e.PostID})
+		return repo.AppendTx(tx, PostChannel(e.PostID), string(event.PostCreated), payload)
+	})
+	bus.Subscribe(event.PostUpdated, func(tx *sql.Tx, e event.Event) error {
+		payload, _ := json.Marshal(map[string]int{"id": e.PostID, "previous_id": e.PreviousID})
+		return repo.AppendTx(tx, PostChannel(e.PreviousID), string(event.PostUpdated), payload)
+	})
+	bus.Subscribe(event.PostDeleted, func(tx *sql.Tx, e event.Event) error {
+		payload, _ := json.Marshal(map[string]int{"id": e.PostID})
+		return repo.AppendTx(tx, PostChannel(e.PostID), string(event.PostDeleted), payload)
+	})
+	bus.Subscribe(event.NotificationCreated, func(tx *sql.Tx, e event.Event) error {
+		return repo.AppendNotificationTx(tx, e.UserID, e.NotificationID)
+	})
+}
@@ -0,0 +1,81 @@
+package stream
+
+import (
+	"testing"
+
+	"majoo-case1-rest-api/internal/event"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSubscribe_StoresCommentAndNotifies(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	bus := event.NewBus()
+	Subscribe(bus, NewRepository(db))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO stream_events").
+		WithArgs("post:10", "comment.created", 11, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	mock.ExpectExec("SELECT pg_notify").
+		WithArgs("stream_events", "42").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if err := bus.PublishTx(tx, event.Event{Type: event.CommentCreated, ActorID: 1, PostID: 10, CommentID: 11}); err != nil {
+		t.Fatalf("PublishTx: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSubscribe_AnnouncesRestoredPostsOnly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	bus := event.NewBus()
+	Subscribe(bus, NewRepository(db))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO stream_events").
+		WithArgs("post:10", "post.created", `{"id":10}`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(43))
+	mock.ExpectExec("SELECT pg_notify").
+		WithArgs("stream_events", "43").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	// a brand-new post has nobody following it
+	if err := bus.PublishTx(tx, event.Event{Type: event.PostCreated, ActorID: 1, PostID: 9}); err != nil {
+		t.Fatalf("PublishTx: %v", err)
+	}
+	if err := bus.PublishTx(tx, event.Event{Type: event.PostCreated, ActorID: 1, PostID: 10, Restored: true}); err != nil {
+		t.Fatalf("PublishTx: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"majoo-case1-rest-api/config"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// subscriptionBuffer is how many messages a slow client may fall behind
+	// before it is disconnected and has to resume with Last-Event-ID.
+	subscriptionBuffer = 64
+	// catchUpBatch bounds each query when catching up after a reconnect.
+	catchUpBatch = 500
+)
+
+// Hub fans stored events out to the subscriptions of this instance. It
+// learns about new events through Postgres LISTEN/NOTIFY, so events written
+// by any instance reach clients connected to every instance.
+type Hub struct {
+	repo      *Repository
+	dsn       string
+	retention time.Duration
+
+	mu     sync.Mutex
+	byChan map[string]map[*Subscription]struct{}
+	lastID int64
+}
+
+func NewHub(repo *Repository, cfg config.Config) *Hub {
+	return &Hub{
+		repo:      repo,
+		dsn:       cfg.DatabaseURL,
+		retention: cfg.StreamRetention,
+		byChan:    map[string]map[*Subscription]struct{}{},
+	}
+}
+
+// Subscription receives the messages of its channels on C until it is closed,
+// either by Close or by the hub when the subscriber falls too far behind.
+type Subscription struct {
+	C <-chan Message
+
+	hub      *Hub
+	ch       chan Message
+	channels map[string]bool
+	closed   bool
+}
+
+func (h *Hub) Subscribe(channels []string) *Subscription {
+	ch := make(chan Message, subscriptionBuffer)
+	s := &Subscription{C: ch, hub: h, ch: ch, channels: map[string]bool{}}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range channels {
+		h.addLocked(s, c)
+	}
+	return s
+}
+
+// Add subscribes s to one more channel.
+func (s *Subscription) Add(channel string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if !s.closed {
+		s.hub.addLocked(s, channel)
+	}
+}
+
+// Remove unsubscribes s from a channel.
+func (s *Subscription) Remove(channel string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.removeLocked(s, channel)
+}
+
+// Channels returns the channels s currently receives.
+func (s *Subscription) Channels() []string {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	out := make([]string, 0, len(s.channels))
+	for c := range s.channels {
+		out = append(out, c)
+	}
+	return out
+}
+
+// Close unsubscribes s and closes C. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.closeLocked(s)
+}
+
+func (h *Hub) addLocked(s *Subscription, channel string) {
+	subs, ok := h.byChan[channel]
+	if !ok {
+		subs = map[*Subscription]struct{}{}
+		h.byChan[channel] = subs
+	}
+	subs[s] = struct{}{}
+	s.channels[channel] = true
+}
+
+func (h *Hub) removeLocked(s *Subscription, channel string) {
+	delete(s.channels, channel)
+	if subs, ok := h.byChan[channel]; ok {
+		delete(subs, s)
+		if len(subs) == 0 {
+			delete(h.byChan, channel)
+		}
+	}
+}
+
+func (h *Hub) closeLocked(s *Subscription) {
+	if s.closed {
+		return
+	}
+	for c := range s.channels {
+		h.removeLocked(s, c)
+	}
+	s.closed = true
+	close(s.ch)
+}
+
+// deliver hands messages to the subscriptions of their channels without
+// blocking. A post.updated message also moves the post's subscribers to the
+// replacement post's channel, since an edit changes the post's ID.
+func (h *Hub) deliver(msgs []Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, m := range msgs {
+		if m.ID > h.lastID {
+			h.lastID = m.ID
+		}
+		for s := range h.byChan[m.Channel] {
+			select {
+			case s.ch <- m:
+			default:
+				log.Printf("stream: dropping subscriber lagging on %s", m.Channel)
+				h.closeLocked(s)
+				continue
+			}
+			if next := successor(m); next != "" {
+				h.addLocked(s, next)
+			}
+		}
+	}
+}
+
+// successor returns the channel that replaces m's channel, if m announces one.
+func successor(m Message) string {
+	if m.Type != "post.updated" || !strings.HasPrefix(m.Channel, "post:") {
+		return ""
+	}
+	var p struct {
+		ID int `json:"id"`
+	}
+	if json.Unmarshal(m.Data, &p) != nil || p.ID == 0 {
+		return ""
+	}
+	return PostChannel(p.ID)
+}
+
+// Broadcast delivers a message that is not stored to the subscribers of
+// channel on every instance.
+func (h *Hub) Broadcast(channel, typ string, payload []byte) error {
+	return h.repo.Broadcast(channel, typ, payload)
+}
+
+// Replay returns the stored events on channels after afterID, for clients
+// resuming with Last-Event-ID.
+func (h *Hub) Replay(channels []string, afterID int64, limit int) ([]Message, error) {
+	return h.repo.After(afterID, channels, limit)
+}
+
+// Run listens for new events until ctx is done and prunes events older than
+// the retention period.
+func (h *Hub) Run(ctx context.Context) {
+	last, err := h.repo.LastID()
+	if err != nil {
+		log.Printf("stream: reading last event id: %v", err)
+	}
+	h.mu.Lock()
+	h.lastID = last
+	h.mu.Unlock()
+
+	l := pq.NewListener(h.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("stream: listener: %v", err)
+		}
+	})
+	defer l.Close()
+	for _, ch := range []string{notifyChannel, ephemeralChannel} {
+		if err := l.Listen(ch); err != nil {
+			log.Printf("stream: LISTEN %s: %v", ch, err)
+		}
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-l.Notify:
+			h.handle(l, n)
+		case <-ping.C:
+			go l.Ping()
+		case <-prune.C:
+			if err := h.repo.Prune(time.Now().Add(-h.retention)); err != nil {
+				log.Printf("stream: pruning events: %v", err)
+			}
+		}
+	}
+}
+
+// handle loads the events announced by n and any notifications already
+// queued behind it; broadcast messages arrive whole and are passed on as is.
+// Events are fetched by the IDs in the notifications rather than "everything
+// after the last ID", because IDs are assigned before commit and
+// transactions may commit out of order. A nil notification means
+// the listener reconnected and may have missed some, so it falls back to
+// catching up from the last ID seen.
+func (h *Hub) handle(l *pq.Listener, n *pq.Notification) {
+	var ids []int64
+	var live []Message
+	reconnected := false
+	for {
+		switch {
+		case n == nil:
+			reconnected = true
+		case n.Channel == ephemeralChannel:
+			var e ephemeral
+			if err := json.Unmarshal([]byte(n.Extra), &e); err == nil {
+				live = append(live, Message{Channel: e.Channel, Type: e.Type, Data: e.Data})
+			}
+		default:
+			if id, err := strconv.ParseInt(n.Extra, 10, 64); err == nil {
+				ids = append(ids, id)
+			}
+		}
+		select {
+		case n = <-l.Notify:
+			continue
+		default:
+		}
+		break
+	}
+	h.deliver(live)
+	if len(ids) > 0 {
+		msgs, err := h.repo.ByIDs(ids)
+		if err != nil {
+			log.Printf("stream: loading events: %v", err)
+		}
+		h.deliver(msgs)
+	}
+	if reconnected {
+		h.catchUp()
+	}
+}
+
+func (h *Hub) catchUp() {
+	for {
+		h.mu.Lock()
+		after := h.lastID
+		h.mu.Unlock()
+		msgs, err := h.repo.After(after, nil, catchUpBatch)
+		if err != nil {
+			log.Printf("stream: catching up: %v", err)
+			return
+		}
+		h.deliver(msgs)
+		if len(msgs) < catchUpBatch {
+			return
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"encoding/json"
+	"testing"
+
+	"majoo-case1-rest-api/config"
+)
+
+func TestHub_DeliversByChannel(t *testing.T) {
+	h := NewHub(nil, config.Config{})
+	a := h.Subscribe([]string{PostChannel(1), UserChannel(7)})
+	defer a.Close()
+	b := h.Subscribe([]string{PostChannel(2)})
+	defer b.Close()
+
+	h.deliver([]Message{
+		{ID: 1, Channel: PostChannel(1), Type: "comment.created"},
+		{ID: 2, Channel: PostChannel(2), Type: "comment.created"},
+		{ID: 3, Channel: UserChannel(7), Type: "notification.created"},
+	})
+
+	if got := drain(a); len(got) != 2 || got[0].ID != 1 || got[1].ID != 3 {
+		t.Fatalf("a got %+v", got)
+	}
+	if got := drain(b); len(got) != 1 || got[0].ID != 2 {
+		t.Fatalf("b got %+v", got)
+	}
+}
+
+func TestHub_FollowsEditedPost(t *testing.T) {
+	h := NewHub(nil, config.Config{})
+	s := h.Subscribe([]string{PostChannel(1)})
+	defer s.Close()
+
+	data, _ := json.Marshal(map[string]int{"id": 5, "previous_id": 1})
+	h.deliver([]Message{
+		{ID: 1, Channel: PostChannel(1), Type: "post.updated", Data: data},
+		{ID: 2, Channel: PostChannel(5), Type: "comment.created"},
+	})
+
+	if got := drain(s); len(got) != 2 || got[1].Channel != PostChannel(5) {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestHub_DropsLaggingSubscriber(t *testing.T) {
+	h := NewHub(nil, config.Config{})
+	s := h.Subscribe([]string{PostChannel(1)})
+
+	msgs := make([]Message, subscriptionBuffer+1)
+	for i := range msgs {
+		msgs[i] = Message{ID: int64(i + 1), Channel: PostChannel(1)}
+	}
+	h.deliver(msgs)
+
+	n := 0
+	for range s.C {
+		n++
+	}
+	if n != subscriptionBuffer {
+		t.Fatalf("received %d messages before close, want %d", n, subscriptionBuffer)
+	}
+	if len(h.byChan) != 0 {
+		t.Fatalf("dropped subscriber still registered: %v", h.byChan)
+	}
+	s.Close() // closing again is a no-op
+}
+
+func drain(s *Subscription) []Message {
+	var out []Message
+	for {
+		select {
+		case m := <-s.C:
+			out = append(out, m)
+		default:
+			return out
+		}
+	}
+}
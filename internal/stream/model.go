@@ -0,0 +1,21 @@
+package stream
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Message is a real-time event, delivered to subscribers of its channel. ID
+// is zero for broadcast messages, which are not stored.
+type Message struct {
+	ID      int64
+	Channel string
+	Type    string
+	Data    json.RawMessage
+}
+
+// PostChannel carries comment activity on a post.
+func PostChannel(postID int) string { return "post:" + strconv.Itoa(postID) }
+
+// UserChannel carries events addressed to one user, such as notifications.
+func UserChannel(userID int) string { return "user:" + strconv.Itoa(userID) }
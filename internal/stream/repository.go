@@ -0,0 +1,138 @@
+package stream
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// notifyChannel is the Postgres NOTIFY channel announcing new stream_events rows.
+	notifyChannel = "stream_events"
+	// ephemeralChannel carries messages that are not stored, such as typing
+	// indicators, in full.
+	ephemeralChannel = "stream_ephemeral"
+)
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+// insertTx runs q, an INSERT INTO stream_events ... RETURNING id, and
+// announces the new row; the NOTIFY is only delivered if tx commits.
+func (r *Repository) insertTx(tx *sql.Tx, q string, args ...interface{}) error {
+	var id int64
+	if err := tx.QueryRow(q, args...).Scan(&id); err != nil {
+		return err
+	}
+	_, err := tx.Exec("SELECT pg_notify($1, $2)", notifyChannel, strconv.FormatInt(id, 10))
+	return err
+}
+
+// AppendTx stores an event with a ready-made JSON payload.
+func (r *Repository) AppendTx(tx *sql.Tx, channel, typ string, payload []byte) error {
+	const q = "INSERT INTO stream_events (channel, type, payload) VALUES ($1,$2,$3) RETURNING id"
+	return r.insertTx(tx, q, channel, typ, string(payload))
+}
+
+// AppendCommentTx stores a comment event on the post's channel with the
+// comment as payload. previousID is the comment an update superseded, or 0.
+func (r *Repository) AppendCommentTx(tx *sql.Tx, typ string, postID, commentID, previousID int) error {
+	const q = `INSERT INTO stream_events (channel, type, payload)
+               SELECT $1, $2, json_build_object(
+                   'id', c.id, 'post_id', c.post_id, 'parent_id', c.parent_id, 'user_id', c.user_id, 'author', u.username,
+                   'content', c.content, 'content_format', c.content_format, 'content_html', c.content_html,
+                   'created_at', c.created_at, 'updated_at', c.updated_at, 'previous_id', $4::int)
+               FROM comments c JOIN users u ON u.id = c.user_id WHERE c.id = $3
+               RETURNING id`
+	var prev interface{}
+	if previousID != 0 {
+		prev = previousID
+	}
+	return r.insertTx(tx, q, PostChannel(postID), typ, commentID, prev)
+}
+
+// AppendNotificationTx stores a notification.created event on the
+// recipient's channel with the notification as payload.
+func (r *Repository) AppendNotificationTx(tx *sql.Tx, userID int, notificationID int64) error {
+	const q = `INSERT INTO stream_events (channel, type, payload)
+               SELECT $1, 'notification.created', json_build_object(
+                   'id', n.id, 'type', n.type,
+                   'actor', CASE WHEN n.actor_id IS NULL THEN NULL ELSE json_build_object('id', n.actor_id, 'username', u.username) END,
+                   'post_id', n.post_id, 'comment_id', n.comment_id, 'read', false, 'created_at', n.created_at)
+               FROM notifications n LEFT JOIN users u ON u.id = n.actor_id WHERE n.id = $2
+               RETURNING id`
+	return r.insertTx(tx, q, UserChannel(userID), notificationID)
+}
+
+// After returns up to limit events with an id above afterID, oldest first,
+// restricted to channels unless channels is nil.
+func (r *Repository) After(afterID int64, channels []string, limit int) ([]Message, error) {
+	q := "SELECT id, channel, type, payload FROM stream_events WHERE id > $1"
+	args := []interface{}{afterID, limit}
+	if channels != nil {
+		q += " AND channel = ANY($3)"
+		args = append(args, pq.Array(channels))
+	}
+	rows, err := r.db.Query(q+" ORDER BY id LIMIT $2", args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+func scanMessages(rows *sql.Rows) ([]Message, error) {
+	defer rows.Close()
+	var out []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.Channel, &m.Type, &m.Data); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// ByIDs returns the events with the given ids, oldest first.
+func (r *Repository) ByIDs(ids []int64) ([]Message, error) {
+	rows, err := r.db.Query("SELECT id, channel, type, payload FROM stream_events WHERE id = ANY($1) ORDER BY id", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	return scanMessages(rows)
+}
+
+// Broadcast sends a message to every instance without storing it. Such
+// messages have no ID and cannot be replayed. Payloads must stay well under
+// the 8000 byte NOTIFY limit.
+func (r *Repository) Broadcast(channel, typ string, payload []byte) error {
+	msg, err := json.Marshal(ephemeral{Channel: channel, Type: typ, Data: payload})
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec("SELECT pg_notify($1, $2)", ephemeralChannel, string(msg))
+	return err
+}
+
+// ephemeral is the NOTIFY payload of a broadcast message.
+type ephemeral struct {
+	Channel string          `json:"channel"`
+	Type    string          `json:"type"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (r *Repository) LastID() (int64, error) {
+	var id int64
+	err := r.db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM stream_events").Scan(&id)
+	return id, err
+}
+
+// Prune deletes events older than before.
+func (r *Repository) Prune(before time.Time) error {
+	_, err := r.db.Exec("DELETE FROM stream_events WHERE created_at < $1", before)
+	return err
+}
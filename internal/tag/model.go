@@ -0,0 +1,7 @@
+package tag
+
+type Tag struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+}
@@ -0,0 +1,72 @@
+package tag
+
+import (
+	"strings"
+	"unicode"
+
+	"majoo-case1-rest-api/internal/slug"
+)
+
+// MaxLength is the longest slug a tag may have.
+const MaxLength = 50
+
+// Normalize folds raw tag names into their slug form so that "Go", "go" and
+// " GO " all resolve to the same tag. Letters of any script are kept, and a
+// "+" or "#" ending a word is spelled out so that "C++", "C#" and "C" stay
+// apart. Duplicates are dropped and the first spelling seen is kept as the
+// display name.
+func Normalize(raw []string) ([]Tag, error) {
+	seen := make(map[string]bool, len(raw))
+	out := make([]Tag, 0, len(raw))
+	for _, r := range raw {
+		s := slug.Unicode(spellSymbols(r), MaxLength)
+		if s == "" {
+			return nil, ErrInvalid
+		}
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, Tag{Slug: s, Name: strings.TrimSpace(r)})
+	}
+	return out, nil
+}
+
+// symbols are spelled out when they follow a letter, a digit or each other,
+// as in "C++" or "F#"; elsewhere, as in "#go", they only separate words.
+var symbols = map[rune]string{'+': " plus ", '#': " sharp "}
+
+func spellSymbols(s string) string {
+	var b strings.Builder
+	spell := false
+	for _, r := range s {
+		if name, ok := symbols[r]; ok && spell {
+			b.WriteString(name)
+			continue
+		}
+		spell = unicode.IsLetter(r) || unicode.IsDigit(r)
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Slugs is Normalize for callers that only need the slugs, such as list filters.
+func Slugs(raw []string) ([]string, error) {
+	tags, err := Normalize(raw)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.Slug
+	}
+	return out, nil
+}
+
+var (
+	ErrInvalid = errString("invalid_tag")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -0,0 +1,18 @@
+package tag
+
+import "database/sql"
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+// List returns tags attached to at least one live post, most used first.
+func (r *Repository) List(limit int) (*sql.Rows, error) {
+	const q = `SELECT t.slug, t.name, COUNT(p.id) AS post_count
+               FROM tags t
+               JOIN post_tags pt ON pt.tag_id = t.id
+               JOIN posts p ON p.id = pt.post_id AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+               GROUP BY t.id, t.slug, t.name
+               ORDER BY post_count DESC, t.slug ASC LIMIT $1`
+	return r.db.Query(q, limit)
+}
@@ -0,0 +1,25 @@
+package tag
+
+type Usecase struct{ repo *Repository }
+
+func NewUsecase(repo *Repository) *Usecase { return &Usecase{repo: repo} }
+
+func (u *Usecase) List(limit int) ([]Tag, error) {
+	if limit < 1 || limit > 100 {
+		limit = 100
+	}
+	rows, err := u.repo.List(limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Tag{}
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.Slug, &t.Name, &t.PostCount); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
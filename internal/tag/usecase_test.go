@@ -0,0 +1,95 @@
+package tag
+
+import (
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNormalize_FoldsCaseAndDeduplicates(t *testing.T) {
+	tags, err := Normalize([]string{"Go", " go ", "Web Dev", "GO"})
+	if err != nil {
+		t.Fatalf("Normalize error: %v", err)
+	}
+	if len(tags) != 2 {
+		t.Fatalf("expected 2 tags, got %d: %+v", len(tags), tags)
+	}
+	if tags[0].Slug != "go" || tags[0].Name != "Go" {
+		t.Errorf("unexpected first tag: %+v", tags[0])
+	}
+	if tags[1].Slug != "web-dev" {
+		t.Errorf("expected web-dev, got %q", tags[1].Slug)
+	}
+}
+
+func TestNormalize_KeepsSymbolsAndOtherScripts(t *testing.T) {
+	tags, err := Normalize([]string{"C++", "C#", "C", "#go", "機械学習", "Ελληνικά", "برمجة"})
+	if err != nil {
+		t.Fatalf("Normalize error: %v", err)
+	}
+	want := []string{"c-plus-plus", "c-sharp", "c", "go", "機械学習", "ελληνικά", "برمجة"}
+	if len(tags) != len(want) {
+		t.Fatalf("expected %d tags, got %+v", len(want), tags)
+	}
+	for i, w := range want {
+		if tags[i].Slug != w {
+			t.Errorf("tag %d: expected slug %q, got %q", i, w, tags[i].Slug)
+		}
+	}
+	if tags[0].Name != "C++" {
+		t.Errorf("expected display name C++, got %q", tags[0].Name)
+	}
+}
+
+func TestNormalize_Invalid(t *testing.T) {
+	if _, err := Normalize([]string{"go", "###"}); err != ErrInvalid {
+		t.Errorf("expected ErrInvalid, got %v", err)
+	}
+}
+
+func TestUsecase_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(NewRepository(db))
+
+	mock.ExpectQuery("SELECT t.slug, t.name, COUNT").
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"slug", "name", "post_count"}).
+			AddRow("go", "Go", 3).
+			AddRow("sql", "SQL", 1))
+
+	tags, err := uc.List(0)
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(tags) != 2 || tags[0].PostCount != 3 {
+		t.Errorf("unexpected tags: %+v", tags)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestUsecase_List_DatabaseError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(NewRepository(db))
+
+	mock.ExpectQuery("SELECT t.slug").WillReturnError(errors.New("database error"))
+
+	if _, err := uc.List(10); err == nil {
+		t.Error("expected error, got nil")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+package trash
+
+import "time"
+
+// Item is a post or comment in its author's trash. Comments deleted along
+// with their post are not listed; they come back when the post is restored.
+type Item struct {
+	Type      string    `json:"type"` // "post" or "comment"
+	ID        int       `json:"id"`
+	PostID    int       `json:"post_id,omitempty"` // the post a comment was on
+	Title     string    `json:"title,omitempty"`
+	Content   string    `json:"content"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// ExpiresAt is when the item can no longer be restored.
+	ExpiresAt time.Time `json:"expires_at"`
+}
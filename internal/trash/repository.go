@@ -0,0 +1,27 @@
+package trash
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+// List returns the posts and comments userID deleted within retention,
+// most recently deleted first. Old versions left by edits, and whatever was
+// deleted before the trash existed, are skipped.
+func (r *Repository) List(userID int, retention time.Duration, limit, offset int) (*sql.Rows, error) {
+	const q = `SELECT type, id, post_id, title, content, deleted_at, deleted_at + $2 * INTERVAL '1 second' FROM (
+                   SELECT 'post' AS type, id, 0 AS post_id, title, content, deleted_at FROM posts
+                   WHERE user_id = $1 AND deleted_at IS NOT NULL AND superseded_by IS NULL AND NOT legacy_deleted
+                   UNION ALL
+                   SELECT 'comment', id, post_id, '', content, deleted_at FROM comments
+                   WHERE user_id = $1 AND deleted_at IS NOT NULL AND superseded_by IS NULL AND NOT legacy_deleted
+                         AND NOT deleted_with_post
+               ) t
+               WHERE deleted_at > CURRENT_TIMESTAMP - $2 * INTERVAL '1 second'
+               ORDER BY deleted_at DESC, id DESC LIMIT $3 OFFSET $4`
+	return r.db.Query(q, userID, retention.Seconds(), limit, offset)
+}
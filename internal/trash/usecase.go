@@ -0,0 +1,40 @@
+package trash
+
+import (
+	"time"
+
+	"majoo-case1-rest-api/config"
+)
+
+type Usecase struct {
+	repo      *Repository
+	retention time.Duration
+}
+
+func NewUsecase(repo *Repository, cfg config.Config) *Usecase {
+	return &Usecase{repo: repo, retention: cfg.TrashRetention}
+}
+
+// List returns a page of userID's trash.
+func (u *Usecase) List(userID, page, limit int) ([]Item, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	rows, err := u.repo.List(userID, u.retention, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Item{}
+	for rows.Next() {
+		var it Item
+		if err := rows.Scan(&it.Type, &it.ID, &it.PostID, &it.Title, &it.Content, &it.DeletedAt, &it.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
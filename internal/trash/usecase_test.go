@@ -0,0 +1,38 @@
+package trash
+
+import (
+	"testing"
+	"time"
+
+	"majoo-case1-rest-api/config"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUsecase_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(NewRepository(db), config.Config{TrashRetention: time.Hour})
+
+	now := time.Now()
+	mock.ExpectQuery("FROM posts").
+		WithArgs(3, float64(3600), 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "post_id", "title", "content", "deleted_at", "expires_at"}).
+			AddRow("comment", 9, 4, "", "nice", now, now.Add(time.Hour)).
+			AddRow("post", 4, 0, "Hello", "world", now, now.Add(time.Hour)))
+
+	items, err := uc.List(3, 0, 0)
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(items) != 2 || items[0].Type != "comment" || items[0].PostID != 4 || items[1].Title != "Hello" {
+		t.Errorf("unexpected items %+v", items)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
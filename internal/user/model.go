@@ -7,8 +7,19 @@ type User struct {
     Username     string    `json:"username"`
     Email        string    `json:"email"`
     PasswordHash string    `json:"-"`
+    Role         Role      `json:"role"`
     CreatedAt    time.Time `json:"created_at"`
     UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// Role decides access to moderation and administration endpoints.
+type Role string
 
+const (
+    RoleUser      Role = "user"
+    RoleModerator Role = "moderator"
+    RoleAdmin     Role = "admin"
+)
+
+// Roles lists every role, least privileged first.
+var Roles = []Role{RoleUser, RoleModerator, RoleAdmin}
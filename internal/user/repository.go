@@ -20,8 +20,22 @@ func (r *Repository) Create(username, email, passwordHash string) (int, error) {
 
 func (r *Repository) GetByEmail(email string) (User, error) {
     var u User
-    err := r.db.QueryRow("SELECT id, username, email, password_hash, created_at, updated_at FROM users WHERE email = $1", email).Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
+    err := r.db.QueryRow("SELECT id, username, email, password_hash, role, created_at, updated_at FROM users WHERE email = $1", email).Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt, &u.UpdatedAt)
     return u, err
 }
 
+func (r *Repository) Role(id int) (Role, error) {
+    var role Role
+    err := r.db.QueryRow("SELECT role FROM users WHERE id = $1", id).Scan(&role)
+    return role, err
+}
 
+// SetRole reports whether the user exists.
+func (r *Repository) SetRole(id int, role Role) (bool, error) {
+    res, err := r.db.Exec("UPDATE users SET role = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1", id, string(role))
+    if err != nil {
+        return false, err
+    }
+    n, err := res.RowsAffected()
+    return n > 0, err
+}
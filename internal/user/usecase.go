@@ -36,7 +36,7 @@ func (u *Usecase) Register(username, email, password string) (User, string, erro
     if err != nil {
         return User{}, "", err
     }
-    return User{ID: id, Username: username, Email: email}, token, nil
+    return User{ID: id, Username: username, Email: email, Role: RoleUser}, token, nil
 }
 
 func (u *Usecase) Login(email, password string) (User, string, error) {
@@ -54,9 +54,35 @@ func (u *Usecase) Login(email, password string) (User, string, error) {
     return user, token, nil
 }
 
+// Role returns the user's role; it is read on every privileged request so
+// changes apply without logging in again.
+func (u *Usecase) Role(id int) (Role, error) {
+    return u.repo.Role(id)
+}
+
+func (u *Usecase) SetRole(id int, role Role) error {
+    known := false
+    for _, r := range Roles {
+        known = known || r == role
+    }
+    if !known {
+        return ErrUnknownRole
+    }
+    found, err := u.repo.SetRole(id, role)
+    if err != nil {
+        return err
+    }
+    if !found {
+        return ErrNotFound
+    }
+    return nil
+}
+
 var (
     ErrUnauthorized = fmtErr("unauthorized")
     ErrConflict     = fmtErr("conflict")
+    ErrNotFound     = fmtErr("not found")
+    ErrUnknownRole  = fmtErr("unknown role")
 )
 
 type fmtErr string
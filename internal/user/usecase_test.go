@@ -110,8 +110,8 @@ func TestUsecase_Login_Success(t *testing.T) {
 	// Mock: get user by email
 	mock.ExpectQuery("SELECT id, username, email, password_hash").
 		WithArgs("test@example.com").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "created_at", "updated_at"}).
-			AddRow(1, "testuser", "test@example.com", "$2a$10$dummyhash", time.Now(), time.Now()))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "role", "created_at", "updated_at"}).
+			AddRow(1, "testuser", "test@example.com", "$2a$10$dummyhash", "user", time.Now(), time.Now()))
 
 	// Note: password check will fail with dummy hash, but we can test the flow
 	_, _, err = uc.Login("test@example.com", "wrongpassword")
@@ -162,8 +162,8 @@ func TestUsecase_Login_Unauthorized(t *testing.T) {
 	// Mock: get user by email
 	mock.ExpectQuery("SELECT id, username, email, password_hash").
 		WithArgs("test@example.com").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "created_at", "updated_at"}).
-			AddRow(1, "testuser", "test@example.com", "$2a$10$dummyhash", time.Now(), time.Now()))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password_hash", "role", "created_at", "updated_at"}).
+			AddRow(1, "testuser", "test@example.com", "$2a$10$dummyhash", "user", time.Now(), time.Now()))
 
 	_, _, err = uc.Login("test@example.com", "wrongpassword")
 	if err != ErrUnauthorized {
@@ -0,0 +1,199 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"majoo-case1-rest-api/config"
+)
+
+// maxResponseBody is how much of an endpoint's reply is kept in the log.
+const maxResponseBody = 1024
+
+// Dispatcher sends queued deliveries in the background, retrying failures
+// with exponential backoff until they succeed or run out of attempts.
+type Dispatcher struct {
+	repo        *Repository
+	client      *http.Client
+	timeout     time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	poll        time.Duration
+	workers     int
+	wake        chan struct{}
+}
+
+func NewDispatcher(repo *Repository, cfg config.Config) *Dispatcher {
+	workers := cfg.WebhookWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	attempts := cfg.WebhookMaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &Dispatcher{
+		repo: repo,
+		client: &http.Client{
+			Timeout: cfg.WebhookTimeout,
+			// a redirect is reported as the endpoint's answer, not followed
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		timeout:     cfg.WebhookTimeout,
+		maxAttempts: attempts,
+		backoff:     cfg.WebhookBackoff,
+		maxBackoff:  cfg.WebhookMaxBackoff,
+		poll:        cfg.WebhookPollInterval,
+		workers:     workers,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Start polls for due deliveries until ctx is cancelled.
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		t := time.NewTicker(d.poll)
+		defer t.Stop()
+		for {
+			d.drain(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			case <-d.wake:
+			}
+		}
+	}()
+}
+
+// Wake makes the dispatcher look for due deliveries now rather than at the
+// next poll.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// drain sends due deliveries until none are left, d.workers at a time.
+func (d *Dispatcher) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		// the lease outlasts every attempt in the batch, even if they queue
+		// behind each other
+		jobs, err := d.repo.Claim(d.workers, 2*d.timeout+time.Minute)
+		if err != nil {
+			log.Printf("webhook: claiming deliveries: %v", err)
+			return
+		}
+		if len(jobs) == 0 {
+			return
+		}
+		var wg sync.WaitGroup
+		for _, j := range jobs {
+			wg.Add(1)
+			go func(j job) {
+				defer wg.Done()
+				res := d.attempt(ctx, j)
+				if err := d.repo.Record(j.ID, res); err != nil {
+					log.Printf("webhook: recording delivery %d: %v", j.ID, err)
+					// count the attempt anyway, or the delivery is retried
+					// forever once its lease runs out
+					if err := d.repo.RecordAttempt(j.ID, res); err != nil {
+						log.Printf("webhook: recording attempt of delivery %d: %v", j.ID, err)
+					}
+				}
+			}(j)
+		}
+		wg.Wait()
+	}
+}
+
+// attempt POSTs the delivery once and decides what happens next.
+func (d *Dispatcher) attempt(ctx context.Context, j job) attemptResult {
+	res := attemptResult{Attempts: j.Attempts + 1}
+	body, err := json.Marshal(envelope{ID: j.EventID, Type: j.EventType, CreatedAt: j.CreatedAt.UTC(), Data: j.Payload})
+	if err != nil {
+		res.Status, res.Error = StatusFailed, err.Error()
+		return res
+	}
+	start := time.Now()
+	status, reply, err := d.send(ctx, j, body, start)
+	res.Duration = time.Since(start)
+	res.ResponseBody = reply
+	if status != 0 {
+		res.ResponseStatus = &status
+	}
+	switch {
+	case err != nil:
+		res.Error = err.Error()
+	case status < 200 || status > 299:
+		res.Error = fmt.Sprintf("endpoint answered %d", status)
+	default:
+		res.Status = StatusSucceeded
+		return res
+	}
+	if res.Attempts >= d.maxAttempts {
+		res.Status = StatusFailed
+		return res
+	}
+	res.Status = StatusPending
+	next := time.Now().Add(jitter(backoff(d.backoff, d.maxBackoff, res.Attempts)))
+	res.NextAttemptAt = &next
+	return res
+}
+
+func (d *Dispatcher) send(ctx context.Context, j job, body []byte, now time.Time) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "blog-webhooks/1")
+	req.Header.Set("X-Webhook-Event", j.EventType)
+	req.Header.Set("X-Webhook-ID", j.EventID)
+	req.Header.Set("X-Webhook-Delivery", fmt.Sprint(j.ID))
+	req.Header.Set(SignatureHeader, Sign(j.Secret, now, body))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20)) // let the connection be reused
+	return resp.StatusCode, storableText(reply), nil
+}
+
+// storableText turns a reply into text Postgres accepts: the limit may cut a
+// rune in half and endpoints may answer with binary, but TEXT columns take
+// neither invalid UTF-8 nor NUL bytes.
+func storableText(b []byte) string {
+	return strings.ToValidUTF8(strings.ReplaceAll(string(b), "\x00", ""), "\uFFFD")
+}
+
+// backoff is the wait after the given number of failed attempts: base,
+// doubling each time, capped at max.
+func backoff(base, max time.Duration, failed int) time.Duration {
+	wait := base
+	for i := 1; i < failed && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// jitter spreads retries of deliveries that failed together by up to 10%.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Int63n(int64(d)/10+1))
+}
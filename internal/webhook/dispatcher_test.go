@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"majoo-case1-rest-api/config"
+)
+
+func testDispatcher() *Dispatcher {
+	return NewDispatcher(nil, config.Config{
+		WebhookTimeout:     time.Second,
+		WebhookMaxAttempts: 3,
+		WebhookBackoff:     time.Minute,
+		WebhookMaxBackoff:  time.Hour,
+	})
+}
+
+func TestDispatcher_Attempt_SignsAndSucceeds(t *testing.T) {
+	var got envelope
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify("whsec_test", r.Header.Get(SignatureHeader), body, time.Minute, time.Now()) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-Webhook-Event") != "post.created" || r.Header.Get("X-Webhook-ID") != "evt1" {
+			http.Error(w, "bad headers", http.StatusBadRequest)
+			return
+		}
+		json.Unmarshal(body, &got)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	res := testDispatcher().attempt(context.Background(), job{
+		ID: 1, URL: srv.URL, Secret: "whsec_test", EventID: "evt1", EventType: "post.created",
+		Payload: []byte(`{"id":5}`), CreatedAt: time.Now(),
+	})
+	if res.Status != StatusSucceeded || res.Attempts != 1 || *res.ResponseStatus != 200 || res.ResponseBody != "ok" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if got.ID != "evt1" || got.Type != "post.created" || string(got.Data) != `{"id":5}` {
+		t.Errorf("unexpected envelope %+v", got)
+	}
+}
+
+func TestDispatcher_Attempt_RetriesThenFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer srv.Close()
+	d := testDispatcher()
+	j := job{ID: 1, URL: srv.URL, Secret: "s", EventID: "evt1", EventType: "post.created", Payload: []byte(`{}`)}
+
+	res := d.attempt(context.Background(), j)
+	if res.Status != StatusPending || res.NextAttemptAt == nil || *res.ResponseStatus != http.StatusFound {
+		t.Fatalf("first failure should be retried, got %+v", res)
+	}
+	if wait := time.Until(*res.NextAttemptAt); wait < 50*time.Second || wait > 70*time.Second {
+		t.Errorf("first retry in %v, want about a minute", wait)
+	}
+
+	j.Attempts = 2
+	if res = d.attempt(context.Background(), j); res.Status != StatusFailed || res.NextAttemptAt != nil {
+		t.Fatalf("last attempt should fail the delivery, got %+v", res)
+	}
+}
+
+func TestDispatcher_Attempt_KeepsBinaryReplyStorable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a NUL, and a two-byte rune cut in half by the limit
+		reply := append([]byte("ok\x00"), bytes.Repeat([]byte("é"), maxResponseBody)...)
+		w.Write(reply)
+	}))
+	defer srv.Close()
+
+	res := testDispatcher().attempt(context.Background(), job{ID: 1, URL: srv.URL, Secret: "s", EventID: "evt1", EventType: "post.created", Payload: []byte(`{}`)})
+	if !utf8.ValidString(res.ResponseBody) || strings.ContainsRune(res.ResponseBody, 0) || !strings.HasPrefix(res.ResponseBody, "okéé") {
+		t.Errorf("reply not cleaned up: %q", res.ResponseBody[:8])
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 4: 4 * time.Minute, 20: time.Hour}
+	for failed, want := range cases {
+		if got := backoff(30*time.Second, time.Hour, failed); got != want {
+			t.Errorf("backoff after %d failures = %v, want %v", failed, got, want)
+		}
+	}
+}
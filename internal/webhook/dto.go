@@ -0,0 +1,15 @@
+package webhook
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=2048"`
+	Description string   `json:"description" binding:"max=255"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Active      *bool    `json:"active"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         *string   `json:"url" binding:"omitempty,url,max=2048"`
+	Description *string   `json:"description" binding:"omitempty,max=255"`
+	Events      *[]string `json:"events" binding:"omitempty,min=1"`
+	Active      *bool     `json:"active"`
+}
@@ -0,0 +1,273 @@
+package webhook
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Repository struct{ db *sql.DB }
+
+func NewRepository(db *sql.DB) *Repository { return &Repository{db: db} }
+
+const webhookColumns = "id, url, description, events, active, created_at, updated_at"
+
+func scanWebhook(s interface{ Scan(...interface{}) error }) (Webhook, error) {
+	var w Webhook
+	err := s.Scan(&w.ID, &w.URL, &w.Description, pq.Array(&w.Events), &w.Active, &w.CreatedAt, &w.UpdatedAt)
+	return w, err
+}
+
+func (r *Repository) Create(url, description, secret string, events []string, active bool, createdBy int) (int, error) {
+	var id int
+	err := r.db.QueryRow(`INSERT INTO webhooks (url, description, secret, events, active, created_by)
+                          VALUES ($1,$2,$3,$4,$5,$6) RETURNING id`,
+		url, description, secret, pq.Array(events), active, createdBy).Scan(&id)
+	return id, err
+}
+
+func (r *Repository) Get(id int) (Webhook, error) {
+	return scanWebhook(r.db.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id=$1", id))
+}
+
+func (r *Repository) List() ([]Webhook, error) {
+	rows, err := r.db.Query("SELECT " + webhookColumns + " FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+// Update changes the given fields; nil leaves a field as is. It reports
+// whether the webhook exists.
+func (r *Repository) Update(id int, url, description *string, events *[]string, active *bool) (bool, error) {
+	var evs interface{}
+	if events != nil {
+		evs = pq.Array(*events)
+	}
+	res, err := r.db.Exec(`UPDATE webhooks SET url = COALESCE($2, url), description = COALESCE($3, description),
+                               events = COALESCE($4, events), active = COALESCE($5, active), updated_at = CURRENT_TIMESTAMP
+                           WHERE id=$1`, id, url, description, evs, active)
+	return affected(res, err)
+}
+
+func (r *Repository) SetSecret(id int, secret string) (bool, error) {
+	res, err := r.db.Exec("UPDATE webhooks SET secret=$2, updated_at = CURRENT_TIMESTAMP WHERE id=$1", id, secret)
+	return affected(res, err)
+}
+
+// Delete removes the webhook together with its delivery log.
+func (r *Repository) Delete(id int) (bool, error) {
+	res, err := r.db.Exec("DELETE FROM webhooks WHERE id=$1", id)
+	return affected(res, err)
+}
+
+func affected(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// SubscribedTx reports whether any active webhook wants events of typ, so
+// producers can skip building payloads nobody receives.
+func (r *Repository) SubscribedTx(tx *sql.Tx, typ string) (bool, error) {
+	var ok bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM webhooks WHERE active AND $1 = ANY(events))", typ).Scan(&ok)
+	return ok, err
+}
+
+// EnqueueTx queues the event for every active webhook subscribed to it.
+func (r *Repository) EnqueueTx(tx *sql.Tx, eventID, typ string, payload []byte) error {
+	_, err := tx.Exec(`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+                       SELECT id, $1, $2, $3 FROM webhooks WHERE active AND $2 = ANY(events)`,
+		eventID, typ, string(payload))
+	return err
+}
+
+// PostPayloadTx renders a post as event data. previousID is the post an
+// update superseded, or 0.
+func (r *Repository) PostPayloadTx(tx *sql.Tx, postID, previousID int) ([]byte, error) {
+	const q = `SELECT json_build_object(
+                   'id', p.id, 'user_id', p.user_id, 'author', u.username, 'slug', p.slug, 'title', p.title,
+                   'content', p.content, 'content_format', p.content_format, 'content_html', p.content_html,
+                   'created_at', p.created_at, 'updated_at', p.updated_at, 'previous_id', $2::int)
+               FROM posts p JOIN users u ON u.id = p.user_id WHERE p.id = $1`
+	var b []byte
+	err := tx.QueryRow(q, postID, nullID(previousID)).Scan(&b)
+	return b, err
+}
+
+// CommentPayloadTx renders a comment as event data, like PostPayloadTx.
+func (r *Repository) CommentPayloadTx(tx *sql.Tx, commentID, previousID int) ([]byte, error) {
+	const q = `SELECT json_build_object(
+                   'id', c.id, 'post_id', c.post_id, 'parent_id', c.parent_id, 'user_id', c.user_id, 'author', u.username,
+                   'content', c.content, 'content_format', c.content_format, 'content_html', c.content_html,
+                   'created_at', c.created_at, 'updated_at', c.updated_at, 'previous_id', $2::int)
+               FROM comments c JOIN users u ON u.id = c.user_id WHERE c.id = $1`
+	var b []byte
+	err := tx.QueryRow(q, commentID, nullID(previousID)).Scan(&b)
+	return b, err
+}
+
+func nullID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// job is a claimed delivery with what is needed to send it.
+type job struct {
+	ID        int64
+	WebhookID int
+	URL       string
+	Secret    string
+	EventID   string
+	EventType string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Claim takes up to limit due deliveries of active webhooks and hides them
+// from other claimers for lease, so several instances can dispatch without
+// sending anything twice. A delivery whose sender dies mid-attempt becomes
+// due again when the lease runs out.
+func (r *Repository) Claim(limit int, lease time.Duration) ([]job, error) {
+	const q = `UPDATE webhook_deliveries d SET next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond'
+               FROM webhooks w
+               WHERE w.id = d.webhook_id AND d.id IN (
+                   SELECT dd.id FROM webhook_deliveries dd JOIN webhooks ww ON ww.id = dd.webhook_id
+                   WHERE dd.status = 'pending' AND dd.next_attempt_at <= CURRENT_TIMESTAMP AND ww.active
+                   ORDER BY dd.next_attempt_at LIMIT $1
+                   FOR UPDATE OF dd SKIP LOCKED)
+               RETURNING d.id, d.webhook_id, w.url, w.secret, d.event_id, d.event_type, d.payload, d.attempts, d.created_at`
+	rows, err := r.db.Query(q, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []job
+	for rows.Next() {
+		var j job
+		if err := rows.Scan(&j.ID, &j.WebhookID, &j.URL, &j.Secret, &j.EventID, &j.EventType, &j.Payload, &j.Attempts, &j.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// attemptResult is the outcome of one delivery attempt.
+type attemptResult struct {
+	Status         string
+	Attempts       int
+	NextAttemptAt  *time.Time
+	ResponseStatus *int
+	ResponseBody   string
+	Error          string
+	Duration       time.Duration
+}
+
+func (r *Repository) Record(id int64, res attemptResult) error {
+	_, err := r.db.Exec(`UPDATE webhook_deliveries SET status=$2, attempts=$3, next_attempt_at=$4, last_attempt_at=CURRENT_TIMESTAMP,
+                             response_status=$5, response_body=$6, error=$7, duration_ms=$8
+                         WHERE id=$1`,
+		id, res.Status, res.Attempts, res.NextAttemptAt, res.ResponseStatus, res.ResponseBody, res.Error, res.Duration.Milliseconds())
+	return err
+}
+
+// RecordAttempt is Record without the reply, for when the reply itself
+// could not be stored.
+func (r *Repository) RecordAttempt(id int64, res attemptResult) error {
+	_, err := r.db.Exec(`UPDATE webhook_deliveries SET status=$2, attempts=$3, next_attempt_at=$4, last_attempt_at=CURRENT_TIMESTAMP,
+                             response_status=$5, response_body=NULL, error=$6, duration_ms=$7
+                         WHERE id=$1`,
+		id, res.Status, res.Attempts, res.NextAttemptAt, res.ResponseStatus, storableText([]byte(res.Error)), res.Duration.Milliseconds())
+	return err
+}
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, status, attempts, next_attempt_at, last_attempt_at,
+                         response_status, COALESCE(response_body, ''), COALESCE(error, ''), duration_ms, redelivery_of, created_at`
+
+func scanDelivery(s interface{ Scan(...interface{}) error }, extra ...interface{}) (Delivery, error) {
+	var d Delivery
+	var next, last sql.NullTime
+	var status, duration sql.NullInt64
+	var redelivery sql.NullInt64
+	dest := []interface{}{&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &next, &last,
+		&status, &d.ResponseBody, &d.Error, &duration, &redelivery, &d.CreatedAt}
+	if err := s.Scan(append(dest, extra...)...); err != nil {
+		return Delivery{}, err
+	}
+	if next.Valid && d.Status == StatusPending {
+		d.NextAttemptAt = &next.Time
+	}
+	if last.Valid {
+		d.LastAttemptAt = &last.Time
+	}
+	if status.Valid {
+		v := int(status.Int64)
+		d.ResponseStatus = &v
+	}
+	if duration.Valid {
+		v := int(duration.Int64)
+		d.DurationMS = &v
+	}
+	if redelivery.Valid {
+		d.RedeliveryOf = &redelivery.Int64
+	}
+	return d, nil
+}
+
+// Deliveries returns the webhook's deliveries with an id below before (0 for
+// the first page), newest first, optionally only those with status.
+func (r *Repository) Deliveries(webhookID int, before int64, limit int, status string) ([]Delivery, error) {
+	rows, err := r.db.Query(`SELECT `+deliveryColumns+` FROM webhook_deliveries
+                             WHERE webhook_id=$1 AND ($2 = 0 OR id < $2) AND ($3 = '' OR status = $3)
+                             ORDER BY id DESC LIMIT $4`, webhookID, before, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// Delivery returns one delivery including its payload.
+func (r *Repository) Delivery(webhookID int, id int64) (Delivery, error) {
+	var payload []byte
+	row := r.db.QueryRow("SELECT "+deliveryColumns+", payload FROM webhook_deliveries WHERE webhook_id=$1 AND id=$2", webhookID, id)
+	d, err := scanDelivery(row, &payload)
+	d.Payload = payload
+	return d, err
+}
+
+// Redeliver queues a copy of a delivery, keeping its event ID so receivers
+// can recognise the event. It returns the new delivery's ID.
+func (r *Repository) Redeliver(webhookID int, id int64) (int64, error) {
+	var newID int64
+	err := r.db.QueryRow(`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, redelivery_of)
+                          SELECT webhook_id, event_id, event_type, payload, id FROM webhook_deliveries
+                          WHERE webhook_id=$1 AND id=$2 RETURNING id`, webhookID, id).Scan(&newID)
+	return newID, err
+}
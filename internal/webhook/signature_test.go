@@ -0,0 +1,25 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"abc"}`)
+	header := Sign("whsec_test", now, body)
+
+	if !Verify("whsec_test", header, body, 5*time.Minute, now.Add(time.Minute)) {
+		t.Fatalf("valid signature %q rejected", header)
+	}
+	if Verify("whsec_other", header, body, 5*time.Minute, now) {
+		t.Error("signature accepted with the wrong secret")
+	}
+	if Verify("whsec_test", header, []byte(`{"id":"abd"}`), 5*time.Minute, now) {
+		t.Error("signature accepted for a tampered body")
+	}
+	if Verify("whsec_test", header, body, 5*time.Minute, now.Add(10*time.Minute)) {
+		t.Error("stale signature accepted")
+	}
+}
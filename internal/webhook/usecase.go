@@ -0,0 +1,271 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+
+	"majoo-case1-rest-api/internal/event"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type Usecase struct {
+	repo       *Repository
+	dispatcher *Dispatcher
+}
+
+func NewUsecase(repo *Repository, dispatcher *Dispatcher) *Usecase {
+	return &Usecase{repo: repo, dispatcher: dispatcher}
+}
+
+// Subscribe queues deliveries for post and comment events inside the
+// producer's transaction.
+func (u *Usecase) Subscribe(bus *event.Bus) {
+	for _, t := range EventTypes {
+		bus.Subscribe(event.Type(t), u.enqueueTx)
+	}
+}
+
+func (u *Usecase) enqueueTx(tx *sql.Tx, e event.Event) error {
+	typ := string(e.Type)
+	ok, err := u.repo.SubscribedTx(tx, typ)
+	if err != nil || !ok {
+		return err
+	}
+	var payload []byte
+	switch e.Type {
+	case event.PostCreated, event.PostUpdated, event.PostDeleted:
+		payload, err = u.repo.PostPayloadTx(tx, e.PostID, e.PreviousID)
+	default:
+		payload, err = u.repo.CommentPayloadTx(tx, e.CommentID, e.PreviousID)
+	}
+	if err != nil {
+		return err
+	}
+	id, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+	return u.repo.EnqueueTx(tx, id, typ, payload)
+}
+
+func (u *Usecase) List() ([]Webhook, error) { return u.repo.List() }
+
+func (u *Usecase) Get(id int) (Webhook, error) {
+	w, err := u.repo.Get(id)
+	if err == sql.ErrNoRows {
+		return Webhook{}, ErrNotFound
+	}
+	return w, err
+}
+
+// Create registers an endpoint and returns it with its signing secret, which
+// is not shown again.
+func (u *Usecase) Create(userID int, req CreateWebhookRequest) (Webhook, error) {
+	if err := validate(req.URL, req.Events); err != nil {
+		return Webhook{}, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return Webhook{}, err
+	}
+	active := req.Active == nil || *req.Active
+	id, err := u.repo.Create(req.URL, req.Description, secret, dedupe(req.Events), active, userID)
+	if err != nil {
+		return Webhook{}, err
+	}
+	w, err := u.Get(id)
+	w.Secret = secret
+	return w, err
+}
+
+func (u *Usecase) Update(id int, req UpdateWebhookRequest) (Webhook, error) {
+	if req.URL != nil {
+		if err := validate(*req.URL, nil); err != nil {
+			return Webhook{}, err
+		}
+	}
+	var events *[]string
+	if req.Events != nil {
+		if err := validate("", *req.Events); err != nil {
+			return Webhook{}, err
+		}
+		evs := dedupe(*req.Events)
+		events = &evs
+	}
+	found, err := u.repo.Update(id, req.URL, req.Description, events, req.Active)
+	if err != nil {
+		return Webhook{}, err
+	}
+	if !found {
+		return Webhook{}, ErrNotFound
+	}
+	if req.Active != nil && *req.Active {
+		u.dispatcher.Wake()
+	}
+	return u.Get(id)
+}
+
+// RotateSecret replaces the signing secret and returns the new one.
+// Deliveries are signed with the new secret from the next attempt on.
+func (u *Usecase) RotateSecret(id int) (Webhook, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return Webhook{}, err
+	}
+	found, err := u.repo.SetSecret(id, secret)
+	if err != nil {
+		return Webhook{}, err
+	}
+	if !found {
+		return Webhook{}, ErrNotFound
+	}
+	w, err := u.Get(id)
+	w.Secret = secret
+	return w, err
+}
+
+func (u *Usecase) Delete(id int) error {
+	found, err := u.repo.Delete(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Deliveries returns a page of the webhook's delivery log, newest first.
+// status filters by pending, succeeded or failed when not empty.
+func (u *Usecase) Deliveries(webhookID int, status, cursor string, limit int) (DeliveryPage, error) {
+	if status != "" && status != StatusPending && status != StatusSucceeded && status != StatusFailed {
+		return DeliveryPage{}, ErrInvalidStatus
+	}
+	if limit < 1 || limit > maxLimit {
+		limit = defaultLimit
+	}
+	before, err := decodeCursor(cursor)
+	if err != nil {
+		return DeliveryPage{}, err
+	}
+	if _, err := u.Get(webhookID); err != nil {
+		return DeliveryPage{}, err
+	}
+	ds, err := u.repo.Deliveries(webhookID, before, limit+1, status)
+	if err != nil {
+		return DeliveryPage{}, err
+	}
+	page := DeliveryPage{Deliveries: ds}
+	if len(ds) > limit {
+		page.Deliveries = ds[:limit]
+		page.NextCursor = encodeCursor(ds[limit-1].ID)
+	}
+	return page, nil
+}
+
+func (u *Usecase) Delivery(webhookID int, id int64) (Delivery, error) {
+	d, err := u.repo.Delivery(webhookID, id)
+	if err == sql.ErrNoRows {
+		return Delivery{}, ErrNotFound
+	}
+	return d, err
+}
+
+// Redeliver queues the delivery's event again for immediate sending, whatever
+// the outcome of the original.
+func (u *Usecase) Redeliver(webhookID int, id int64) (Delivery, error) {
+	newID, err := u.repo.Redeliver(webhookID, id)
+	if err == sql.ErrNoRows {
+		return Delivery{}, ErrNotFound
+	}
+	if err != nil {
+		return Delivery{}, err
+	}
+	u.dispatcher.Wake()
+	return u.Delivery(webhookID, newID)
+}
+
+// validate checks rawURL and events; empty values are skipped so updates can
+// check one of them.
+func validate(rawURL string, events []string) error {
+	if rawURL != "" {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return ErrInvalidURL
+		}
+	}
+	for _, e := range events {
+		known := false
+		for _, t := range EventTypes {
+			known = known || t == e
+		}
+		if !known {
+			return ErrUnknownEvent
+		}
+	}
+	return nil
+}
+
+func dedupe(events []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, e := range events {
+		if !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func newSecret() (string, error) {
+	s, err := randomHex(32)
+	return "whsec_" + s, err
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+var (
+	ErrNotFound      = errString("webhook not found")
+	ErrInvalidURL    = errString("url must be an absolute http or https URL")
+	ErrUnknownEvent  = errString("unknown event type")
+	ErrInvalidStatus = errString("status must be pending, succeeded or failed")
+	ErrInvalidCursor = errString("invalid cursor")
+)
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
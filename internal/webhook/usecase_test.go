@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"testing"
+
+	"majoo-case1-rest-api/internal/event"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUsecase_Create_Validates(t *testing.T) {
+	uc := NewUsecase(nil, nil)
+	if _, err := uc.Create(1, CreateWebhookRequest{URL: "ftp://example.com", Events: []string{"post.created"}}); err != ErrInvalidURL {
+		t.Errorf("expected ErrInvalidURL, got %v", err)
+	}
+	if _, err := uc.Create(1, CreateWebhookRequest{URL: "https://example.com/hook", Events: []string{"post.liked"}}); err != ErrUnknownEvent {
+		t.Errorf("expected ErrUnknownEvent, got %v", err)
+	}
+}
+
+func TestUsecase_Enqueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	uc := NewUsecase(NewRepository(db), nil)
+	bus := event.NewBus()
+	uc.Subscribe(bus)
+
+	mock.ExpectBegin()
+	// nobody listens for post.created: no payload is built
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs("post.created").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs("comment.updated").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("FROM comments c JOIN users u").
+		WithArgs(12, 11).
+		WillReturnRows(sqlmock.NewRows([]string{"json_build_object"}).AddRow(`{"id":12}`))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(sqlmock.AnyArg(), "comment.updated", `{"id":12}`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	tx, _ := db.Begin()
+	if err := bus.PublishTx(tx, event.Event{Type: event.PostCreated, PostID: 3}); err != nil {
+		t.Fatalf("PublishTx: %v", err)
+	}
+	if err := bus.PublishTx(tx, event.Event{Type: event.CommentUpdated, PostID: 3, CommentID: 12, PreviousID: 11}); err != nil {
+		t.Fatalf("PublishTx: %v", err)
+	}
+	tx.Commit()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
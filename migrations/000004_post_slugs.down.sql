@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_posts_slug;
+DROP INDEX IF EXISTS idx_post_slugs_post_id;
+DROP TABLE IF EXISTS post_slugs;
+ALTER TABLE posts DROP COLUMN IF EXISTS slug;
@@ -0,0 +1,17 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS slug VARCHAR(100);
+
+-- Every slug a post has ever had; old entries keep resolving after a rename.
+CREATE TABLE IF NOT EXISTS post_slugs (
+    slug VARCHAR(100) PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_slugs_post_id ON post_slugs(post_id);
+CREATE INDEX IF NOT EXISTS idx_posts_slug ON posts(slug) WHERE deleted_at IS NULL;
+
+-- Backfill existing posts with a stable placeholder slug
+UPDATE posts SET slug = 'post-' || id WHERE slug IS NULL AND deleted_at IS NULL;
+INSERT INTO post_slugs (slug, post_id)
+SELECT slug, id FROM posts WHERE slug IS NOT NULL
+ON CONFLICT (slug) DO NOTHING;
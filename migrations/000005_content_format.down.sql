@@ -0,0 +1,6 @@
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS comments_content_format_check;
+ALTER TABLE comments DROP COLUMN IF EXISTS content_html;
+ALTER TABLE comments DROP COLUMN IF EXISTS content_format;
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_content_format_check;
+ALTER TABLE posts DROP COLUMN IF EXISTS content_html;
+ALTER TABLE posts DROP COLUMN IF EXISTS content_format;
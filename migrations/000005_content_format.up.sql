@@ -0,0 +1,9 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_format VARCHAR(16) NOT NULL DEFAULT 'plain';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE posts ADD CONSTRAINT posts_content_format_check CHECK (content_format IN ('plain', 'markdown'));
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS content_format VARCHAR(16) NOT NULL DEFAULT 'plain';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD CONSTRAINT comments_content_format_check CHECK (content_format IN ('plain', 'markdown'));
+
+-- Existing rows keep content_html = '' and are rendered on read.
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_attachments_upload_id;
+DROP INDEX IF EXISTS idx_uploads_user_id;
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS uploads;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS upload_variants;
+DROP INDEX IF EXISTS idx_uploads_status;
+ALTER TABLE uploads DROP COLUMN IF EXISTS processing_started_at;
+ALTER TABLE uploads DROP COLUMN IF EXISTS processing_error;
+ALTER TABLE uploads DROP COLUMN IF EXISTS status;
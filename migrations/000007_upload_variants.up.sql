@@ -0,0 +1,17 @@
+-- pending -> processing -> ready | failed; uploads that need no processing start ready
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'ready';
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS processing_error TEXT NOT NULL DEFAULT '';
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS processing_started_at TIMESTAMP NULL;
+CREATE INDEX IF NOT EXISTS idx_uploads_status ON uploads(status) WHERE status IN ('pending', 'processing');
+
+CREATE TABLE IF NOT EXISTS upload_variants (
+    upload_id INTEGER NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+    name VARCHAR(32) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (upload_id, name)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followee_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+
+-- The primary key serves "who do I follow"; this one serves "who follows me"
+CREATE INDEX IF NOT EXISTS idx_follows_followee_id ON follows(followee_id, created_at DESC);
+
+-- Home feed: newest live posts per followed author, walked in (created_at, id) order
+CREATE INDEX IF NOT EXISTS idx_posts_user_created_live ON posts(user_id, created_at DESC, id DESC) WHERE deleted_at IS NULL;
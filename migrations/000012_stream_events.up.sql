@@ -0,0 +1,13 @@
+-- Short-lived log of real-time events. Rows are announced with NOTIFY so every
+-- API instance can fan them out, and kept for a while so clients can resume
+-- with Last-Event-ID after a reconnect.
+CREATE TABLE IF NOT EXISTS stream_events (
+    id BIGSERIAL PRIMARY KEY,
+    channel VARCHAR(64) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stream_events_channel_id ON stream_events(channel, id);
+CREATE INDEX IF NOT EXISTS idx_stream_events_created_at ON stream_events(created_at);
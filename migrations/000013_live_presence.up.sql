@@ -0,0 +1,12 @@
+-- Who is currently in a post's live room, one row per WebSocket connection
+-- and post. Rows are refreshed while the connection is alive and ignored once
+-- stale, so a crashed instance's connections fade out on their own.
+CREATE TABLE IF NOT EXISTS live_presence (
+    conn_id VARCHAR(32) NOT NULL,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (conn_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_live_presence_post_id_seen_at ON live_presence(post_id, seen_at);
@@ -0,0 +1,20 @@
+-- Resolved @mentions, one row per occurrence. Rows belong to one version of
+-- a post or comment; an edit stores the new version's mentions afresh.
+CREATE TABLE IF NOT EXISTS post_mentions (
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_offset INTEGER NOT NULL,
+    length INTEGER NOT NULL,
+    PRIMARY KEY (post_id, start_offset)
+);
+
+CREATE TABLE IF NOT EXISTS comment_mentions (
+    comment_id INTEGER NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_offset INTEGER NOT NULL,
+    length INTEGER NOT NULL,
+    PRIMARY KEY (comment_id, start_offset)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_mentions_user_id ON post_mentions(user_id);
+CREATE INDEX IF NOT EXISTS idx_comment_mentions_user_id ON comment_mentions(user_id);
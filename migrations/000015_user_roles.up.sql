@@ -0,0 +1,4 @@
+-- Roles gate moderation and administration. Promote the first admin by hand:
+--   UPDATE users SET role = 'admin' WHERE username = '...';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
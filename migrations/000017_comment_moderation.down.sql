@@ -0,0 +1,6 @@
+ALTER TABLE posts DROP COLUMN IF EXISTS comment_moderation;
+DROP INDEX IF EXISTS idx_comments_moderation_queue;
+ALTER TABLE comments DROP COLUMN IF EXISTS moderation_note;
+ALTER TABLE comments DROP COLUMN IF EXISTS moderated_at;
+ALTER TABLE comments DROP COLUMN IF EXISTS moderated_by;
+ALTER TABLE comments DROP COLUMN IF EXISTS status;
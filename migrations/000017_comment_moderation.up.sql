@@ -0,0 +1,13 @@
+-- Existing comments were published immediately, so they start approved.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'approved'
+    CHECK (status IN ('pending', 'approved', 'rejected'));
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS moderated_by INT REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS moderated_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS moderation_note TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_comments_moderation_queue ON comments (id)
+    WHERE status <> 'approved' AND deleted_at IS NULL;
+
+-- NULL follows the COMMENT_MODERATION setting.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comment_moderation VARCHAR(16)
+    CHECK (comment_moderation IN ('open', 'first_time', 'all'));
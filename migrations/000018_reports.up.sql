@@ -0,0 +1,24 @@
+-- Hidden content is left out of every read, as if deleted, until a moderator
+-- resolves the reports against it.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS reports (
+    id SERIAL PRIMARY KEY,
+    target VARCHAR(16) NOT NULL CHECK (target IN ('post', 'comment')),
+    target_id INT NOT NULL,
+    reporter_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(32) NOT NULL,
+    details TEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    outcome VARCHAR(16) CHECK (outcome IN ('dismissed', 'removed')),
+    resolved_by INT REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP,
+    resolution_note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One open report per reporter and target; reporting again is possible once
+-- a moderator has resolved the earlier one.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_reporter ON reports (target, target_id, reporter_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports (status, id);
@@ -0,0 +1,2 @@
+-- Content filters file reports without a reporter.
+ALTER TABLE reports ALTER COLUMN reporter_id DROP NOT NULL;
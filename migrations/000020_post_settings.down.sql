@@ -0,0 +1,4 @@
+ALTER TABLE posts DROP COLUMN IF EXISTS comments_premoderated;
+ALTER TABLE posts DROP COLUMN IF EXISTS comments_members_only;
+ALTER TABLE posts DROP COLUMN IF EXISTS comments_locked;
+ALTER TABLE posts DROP COLUMN IF EXISTS comments_enabled;
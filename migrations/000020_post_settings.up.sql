@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_enabled BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_locked BOOLEAN NOT NULL DEFAULT FALSE;
+-- Members are the author's followers.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_members_only BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS comments_premoderated BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_comments_user_trash;
+DROP INDEX IF EXISTS idx_posts_user_trash;
+ALTER TABLE comments DROP COLUMN IF EXISTS deleted_with_post;
+ALTER TABLE comments DROP COLUMN IF EXISTS superseded_by;
+ALTER TABLE posts DROP COLUMN IF EXISTS superseded_by;
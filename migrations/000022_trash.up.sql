@@ -0,0 +1,38 @@
+-- An edit soft-deletes the old row and inserts a new one; superseded_by
+-- points old versions at their replacement so they are not mistaken for
+-- deleted content.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS superseded_by INT;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS superseded_by INT;
+
+-- Comments deleted along with their post come back when it is restored.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_with_post BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Backfill: the replacement row is written in the same transaction, so its
+-- CURRENT_TIMESTAMP matches the old row's deleted_at (posts keep created_at
+-- across edits, so their updated_at is compared instead).
+UPDATE posts o SET superseded_by = (
+    SELECT MIN(n.id) FROM posts n
+    WHERE n.id > o.id AND n.user_id = o.user_id AND n.updated_at = o.deleted_at
+)
+WHERE o.deleted_at IS NOT NULL AND o.superseded_by IS NULL;
+
+UPDATE comments o SET superseded_by = (
+    SELECT MIN(n.id) FROM comments n
+    WHERE n.id > o.id AND n.user_id = o.user_id AND n.post_id = o.post_id AND n.created_at = o.deleted_at
+)
+WHERE o.deleted_at IS NOT NULL AND o.superseded_by IS NULL;
+
+-- Comments used to stay on the version of a post they were written on; move
+-- them to the current version, which is where edits now carry them.
+WITH RECURSIVE chain(id, head) AS (
+    SELECT id, id FROM posts WHERE superseded_by IS NULL
+    UNION ALL
+    SELECT p.id, c.head FROM posts p JOIN chain c ON p.superseded_by = c.id
+)
+UPDATE comments cm SET post_id = chain.head FROM chain WHERE cm.post_id = chain.id AND chain.id <> chain.head;
+
+-- The trash: a user's deleted content, most recent first
+CREATE INDEX IF NOT EXISTS idx_posts_user_trash ON posts(user_id, deleted_at DESC)
+    WHERE deleted_at IS NOT NULL AND superseded_by IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_user_trash ON comments(user_id, deleted_at DESC)
+    WHERE deleted_at IS NOT NULL AND superseded_by IS NULL AND NOT deleted_with_post;
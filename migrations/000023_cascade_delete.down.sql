@@ -0,0 +1,3 @@
+ALTER TABLE attachments DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE comment_reactions DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE post_reactions DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,20 @@
+-- Deleting a post soft-deletes what hangs off it, so restoring it can bring
+-- everything back.
+ALTER TABLE post_reactions ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE comment_reactions ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Backfill posts deleted before deletion cascaded
+UPDATE comment_reactions r SET deleted_at = p.deleted_at
+FROM comments c JOIN posts p ON p.id = c.post_id
+WHERE r.comment_id = c.id AND c.deleted_at IS NULL AND p.deleted_at IS NOT NULL AND r.deleted_at IS NULL;
+
+UPDATE comments c SET deleted_at = p.deleted_at, deleted_with_post = TRUE
+FROM posts p
+WHERE p.id = c.post_id AND c.deleted_at IS NULL AND p.deleted_at IS NOT NULL;
+
+UPDATE post_reactions r SET deleted_at = p.deleted_at
+FROM posts p WHERE p.id = r.post_id AND p.deleted_at IS NOT NULL AND r.deleted_at IS NULL;
+
+UPDATE attachments a SET deleted_at = p.deleted_at
+FROM posts p WHERE p.id = a.post_id AND p.deleted_at IS NOT NULL AND a.deleted_at IS NULL;
@@ -0,0 +1,19 @@
+-- Edits write a new row with the next version; clients send it back in
+-- If-Match so that an edit based on an outdated copy is refused.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+-- Number existing edit chains, oldest first
+WITH RECURSIVE chain(id, version) AS (
+    SELECT id, 1 FROM posts p WHERE NOT EXISTS (SELECT 1 FROM posts o WHERE o.superseded_by = p.id)
+    UNION ALL
+    SELECT p.superseded_by, c.version + 1 FROM posts p JOIN chain c ON p.id = c.id WHERE p.superseded_by IS NOT NULL
+)
+UPDATE posts p SET version = chain.version FROM chain WHERE p.id = chain.id AND chain.version > 1;
+
+WITH RECURSIVE chain(id, version) AS (
+    SELECT id, 1 FROM comments c WHERE NOT EXISTS (SELECT 1 FROM comments o WHERE o.superseded_by = c.id)
+    UNION ALL
+    SELECT c.superseded_by, ch.version + 1 FROM comments c JOIN chain ch ON c.id = ch.id WHERE c.superseded_by IS NOT NULL
+)
+UPDATE comments c SET version = chain.version FROM chain WHERE c.id = chain.id AND chain.version > 1;
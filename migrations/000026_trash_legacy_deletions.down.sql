@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_comments_user_trash;
+CREATE INDEX IF NOT EXISTS idx_comments_user_trash ON comments(user_id, deleted_at DESC)
+    WHERE deleted_at IS NOT NULL AND superseded_by IS NULL AND NOT deleted_with_post;
+DROP INDEX IF EXISTS idx_posts_user_trash;
+CREATE INDEX IF NOT EXISTS idx_posts_user_trash ON posts(user_id, deleted_at DESC)
+    WHERE deleted_at IS NOT NULL AND superseded_by IS NULL;
+ALTER TABLE comments DROP COLUMN IF EXISTS legacy_deleted;
+ALTER TABLE posts DROP COLUMN IF EXISTS legacy_deleted;
@@ -0,0 +1,18 @@
+-- The trash migration matched old versions to their replacements by
+-- timestamp, which is only a guess: whatever it left unmatched may be an old
+-- version as well as a deletion, and restoring an old version would
+-- duplicate the current one. Nothing deleted before the trash existed could
+-- be restored anyway, so all of it is kept out of the trash. On a database
+-- that already ran the trash, this also covers what was deleted since, which
+-- cannot be told apart.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS legacy_deleted BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS legacy_deleted BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE posts SET legacy_deleted = TRUE WHERE deleted_at IS NOT NULL AND superseded_by IS NULL;
+UPDATE comments SET legacy_deleted = TRUE WHERE deleted_at IS NOT NULL AND superseded_by IS NULL;
+
+DROP INDEX IF EXISTS idx_posts_user_trash;
+CREATE INDEX IF NOT EXISTS idx_posts_user_trash ON posts(user_id, deleted_at DESC)
+    WHERE deleted_at IS NOT NULL AND superseded_by IS NULL AND NOT legacy_deleted;
+DROP INDEX IF EXISTS idx_comments_user_trash;
+CREATE INDEX IF NOT EXISTS idx_comments_user_trash ON comments(user_id, deleted_at DESC)
+    WHERE deleted_at IS NOT NULL AND superseded_by IS NULL AND NOT legacy_deleted AND NOT deleted_with_post;
@@ -0,0 +1,4 @@
+-- The request holding the key; a retry taking it over replaces it, so a
+-- request that lost its key can no longer complete or release it.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS owner CHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys ALTER COLUMN owner DROP DEFAULT;